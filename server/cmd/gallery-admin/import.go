@@ -0,0 +1,56 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"os"
+
+	"gogallery/internal/media"
+)
+
+// runImport 匯入伺服器上的目錄；可重複執行 (已匯入的檔案會略過)
+func runImport(ctx context.Context, env *environment, args []string) error {
+	fs := flag.NewFlagSet("import", flag.ExitOnError)
+	userRef := fs.String("user", "", "目標使用者的 email 或 ID")
+	move := fs.Bool("move", false, "匯入後刪除來源檔案 (預設複製)")
+	concurrency := fs.Int("concurrency", media.DefaultImportConcurrency, "同時處理的檔案數")
+	dryRun := fs.Bool("dry-run", false, "只檢查哪些檔案會被匯入，不寫入任何資料")
+	fs.Parse(args)
+	if *userRef == "" || fs.NArg() != 1 {
+		return fmt.Errorf("usage: gallery-admin import -user <email|id> [-move] [-concurrency n] [-dry-run] <dir>")
+	}
+
+	userID, err := env.Users.LookupID(ctx, *userRef)
+	if err != nil {
+		return err
+	}
+
+	opts := media.DirImportOptions{Move: *move, Concurrency: *concurrency, DryRun: *dryRun}
+	sum, err := env.Media.ImportDirectory(ctx, userID, fs.Arg(0), opts, printProgress)
+	if sum != nil {
+		printSummary(sum, *dryRun)
+	}
+	return err
+}
+
+// printProgress 每處理完一個檔案輸出一行進度
+func printProgress(p media.ImportProgress) {
+	fmt.Printf("[%d/%d] imported=%d skipped=%d failed=%d  %s\n", p.Done, p.Total, p.Imported, p.Skipped, p.Failed, p.Current)
+}
+
+// printSummary 輸出匯入結果 (失敗項目輸出至 stderr)
+func printSummary(sum *media.ImportSummary, dryRun bool) {
+	for _, f := range sum.Failures {
+		fmt.Fprintf(os.Stderr, "failed: %s: %s\n", f.Path, f.Error)
+	}
+	verb := "imported"
+	if dryRun {
+		verb = "would be imported"
+	}
+	fmt.Printf("done: %d files, %d %s, %d skipped, %d failed", sum.Total, sum.Imported, verb, sum.Skipped, sum.Failed)
+	if sum.Albums > 0 {
+		fmt.Printf(", %d albums created", sum.Albums)
+	}
+	fmt.Println()
+}
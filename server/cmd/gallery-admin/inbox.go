@@ -0,0 +1,31 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+)
+
+// runInbox 設定或移除使用者的收件資料夾；API 的 InboxWatcher 會在下次掃描時套用
+func runInbox(ctx context.Context, env *environment, args []string) error {
+	fs := flag.NewFlagSet("inbox", flag.ExitOnError)
+	userRef := fs.String("user", "", "目標使用者的 email 或 ID")
+	remove := fs.Bool("remove", false, "停止監看 (資料夾內容保留)")
+	fs.Parse(args)
+	if *userRef == "" || (*remove && fs.NArg() != 0) || (!*remove && fs.NArg() != 1) {
+		return fmt.Errorf("usage: gallery-admin inbox -user <email|id> <dir> | -remove")
+	}
+
+	userID, err := env.Users.LookupID(ctx, *userRef)
+	if err != nil {
+		return err
+	}
+	if *remove {
+		return env.Media.RemoveInboxFolder(ctx, userID)
+	}
+	if err := env.Media.SetInboxFolder(ctx, userID, fs.Arg(0)); err != nil {
+		return err
+	}
+	fmt.Printf("watching %s for %s\n", fs.Arg(0), *userRef)
+	return nil
+}
@@ -0,0 +1,103 @@
+// gallery-admin 伺服器端管理指令 (直接存取資料庫與上傳目錄，不經 HTTP)
+//
+// 使用與 API 相同的環境變數：DB_DSN、UPLOAD_DIR
+//
+//	gallery-admin import -user alice@example.com -move /imports/alice
+//	gallery-admin takeout -user alice@example.com /imports/Takeout
+//
+// 匯入的影片 HLS 轉檔狀態為 pending，由 API 啟動時的 ResumePending 補做
+package main
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"os"
+	"os/signal"
+	"syscall"
+
+	_ "github.com/jackc/pgx/v5/stdlib"
+
+	"gogallery/internal/media"
+	"gogallery/internal/user"
+)
+
+// command 子指令
+type command struct {
+	name  string
+	usage string
+	run   func(ctx context.Context, env *environment, args []string) error
+}
+
+var commands = []command{
+	{"inbox", "inbox -user <email|id> <dir> | -remove    設定監看的收件資料夾", runInbox},
+	{"import", "import -user <email|id> [-move] [-concurrency n] [-dry-run] <dir>    匯入伺服器上的目錄", runImport},
+	{"takeout", "takeout -user <email|id> <dir>    匯入解壓縮後的 Google Takeout", runTakeout},
+}
+
+// environment 子指令共用的服務
+type environment struct {
+	DB    *sql.DB
+	Media *media.Service
+	Users *user.Service
+}
+
+func main() {
+	if len(os.Args) < 2 {
+		usage()
+		os.Exit(2)
+	}
+	var cmd *command
+	for i := range commands {
+		if commands[i].name == os.Args[1] {
+			cmd = &commands[i]
+		}
+	}
+	if cmd == nil {
+		usage()
+		os.Exit(2)
+	}
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	env, err := openEnvironment()
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(1)
+	}
+	defer env.DB.Close()
+
+	if err := cmd.run(ctx, env, os.Args[2:]); err != nil {
+		fmt.Fprintln(os.Stderr, "error:", err)
+		os.Exit(1)
+	}
+}
+
+func usage() {
+	fmt.Fprintln(os.Stderr, "usage: gallery-admin <command> [options]")
+	for _, cmd := range commands {
+		fmt.Fprintln(os.Stderr, "  "+cmd.usage)
+	}
+}
+
+func openEnvironment() (*environment, error) {
+	dsn := os.Getenv("DB_DSN")
+	uploadDir := os.Getenv("UPLOAD_DIR")
+	if dsn == "" || uploadDir == "" {
+		return nil, fmt.Errorf("DB_DSN and UPLOAD_DIR must be set")
+	}
+	db, err := sql.Open("pgx", dsn)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open database: %w", err)
+	}
+	if err := db.Ping(); err != nil {
+		db.Close()
+		return nil, fmt.Errorf("failed to connect to database: %w", err)
+	}
+	return &environment{
+		DB:    db,
+		Media: media.NewService(db, uploadDir),
+		Users: &user.Service{DB: db},
+	}, nil
+}
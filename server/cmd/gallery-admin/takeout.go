@@ -0,0 +1,28 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+)
+
+// runTakeout 匯入 Google Takeout；可重複執行 (已匯入的檔案會略過)
+func runTakeout(ctx context.Context, env *environment, args []string) error {
+	fs := flag.NewFlagSet("takeout", flag.ExitOnError)
+	userRef := fs.String("user", "", "目標使用者的 email 或 ID")
+	fs.Parse(args)
+	if *userRef == "" || fs.NArg() != 1 {
+		return fmt.Errorf("usage: gallery-admin takeout -user <email|id> <dir>")
+	}
+
+	userID, err := env.Users.LookupID(ctx, *userRef)
+	if err != nil {
+		return err
+	}
+
+	sum, err := env.Media.ImportTakeout(ctx, userID, fs.Arg(0), printProgress)
+	if sum != nil {
+		printSummary(sum, false)
+	}
+	return err
+}
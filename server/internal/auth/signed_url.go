@@ -0,0 +1,155 @@
+package auth
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"fmt"
+	"net/http"
+	"net/url"
+	"os"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// 簽章網址涵蓋的衍生類型
+const (
+	VariantOriginal = "original" // 不帶任何衍生參數 (原始檔需另帶 format=original)
+	VariantHLS      = "hls"      // 整個 HLS 串流 (playlist 與片段使用相對路徑，共用同一簽章)
+)
+
+// variantParams 影響檔案內容的查詢參數，簽章時依名稱排序
+var variantParams = []string{"w", "h", "fit", "format"}
+
+// minSecretLength HMAC 金鑰的最短長度 (位元組)
+const minSecretLength = 32
+
+// DefaultSignedURLTTL 未設定 SIGNED_URL_TTL 時的有效期間
+const DefaultSignedURLTTL = time.Hour
+
+// URLSigner 產生與驗證媒體簽章網址 (HMAC-SHA256)
+//
+// 網址格式為 {Prefix}/signed/{user}/{exp}/{sig}/media/{id}/file?w=...，
+// 驗證資訊放在路徑中，HLS playlist 內的相對路徑可沿用同一前綴
+type URLSigner struct {
+	secret []byte
+	ttl    time.Duration
+	Prefix string // API 掛載路徑，例如 "/api"
+}
+
+// NewURLSigner 建立簽章器；ttl 為最短有效期間
+func NewURLSigner(secret []byte, ttl time.Duration) (*URLSigner, error) {
+	if len(secret) < minSecretLength {
+		return nil, fmt.Errorf("signing secret must be at least %d bytes", minSecretLength)
+	}
+	if ttl <= 0 {
+		return nil, fmt.Errorf("ttl must be positive")
+	}
+	return &URLSigner{secret: secret, ttl: ttl}, nil
+}
+
+// NewURLSignerFromEnv 以環境變數建立簽章器：SIGNED_URL_SECRET (必填，至少 32 位元組)、
+// SIGNED_URL_TTL (選填，例如 "30m")、SIGNED_URL_PREFIX (API 掛載路徑，例如 "/api")
+// 未設定金鑰時回傳錯誤 (API 應拒絕啟動)，不以預設金鑰產生可被偽造的網址
+func NewURLSignerFromEnv() (*URLSigner, error) {
+	secret := os.Getenv("SIGNED_URL_SECRET")
+	if secret == "" {
+		return nil, fmt.Errorf("SIGNED_URL_SECRET must be set")
+	}
+	ttl := DefaultSignedURLTTL
+	if raw := os.Getenv("SIGNED_URL_TTL"); raw != "" {
+		d, err := time.ParseDuration(raw)
+		if err != nil {
+			return nil, fmt.Errorf("invalid SIGNED_URL_TTL: %w", err)
+		}
+		ttl = d
+	}
+	s, err := NewURLSigner([]byte(secret), ttl)
+	if err != nil {
+		return nil, fmt.Errorf("invalid SIGNED_URL_SECRET or SIGNED_URL_TTL: %w", err)
+	}
+	s.Prefix = os.Getenv("SIGNED_URL_PREFIX")
+	return s, nil
+}
+
+// Expiry 目前時間對應的到期時間：對齊 TTL 區間 (實際有效 TTL 至 2*TTL)，
+// 同一區間內產生的網址完全相同，列表 ETag 與客戶端圖片快取不會因此失效
+func (s *URLSigner) Expiry(now time.Time) time.Time {
+	return now.Truncate(s.ttl).Add(2 * s.ttl)
+}
+
+// Signature 計算 (使用者, 媒體, 衍生類型, 到期時間) 的簽章
+func (s *URLSigner) Signature(userID, mediaID, variant string, exp int64) string {
+	mac := hmac.New(sha256.New, s.secret)
+	fmt.Fprintf(mac, "v1\n%s\n%s\n%s\n%d", userID, mediaID, variant, exp)
+	return base64.RawURLEncoding.EncodeToString(mac.Sum(nil))
+}
+
+// FileURL 產生檔案網址；query 為 w、h、fit、format (空白代表原始檔)
+func (s *URLSigner) FileURL(userID, mediaID string, query url.Values, exp time.Time) string {
+	variant := CanonicalVariant(query)
+	u := s.base(userID, mediaID, variant, exp) + "/file"
+	if variant != VariantOriginal {
+		u += "?" + variant
+	}
+	return u
+}
+
+// StreamURL 產生 HLS master playlist 網址
+func (s *URLSigner) StreamURL(userID, mediaID string, exp time.Time) string {
+	return s.base(userID, mediaID, VariantHLS, exp) + "/hls/master.m3u8"
+}
+
+func (s *URLSigner) base(userID, mediaID, variant string, exp time.Time) string {
+	unix := exp.Unix()
+	return fmt.Sprintf("%s/signed/%s/%d/%s/media/%s",
+		s.Prefix, url.PathEscape(userID), unix, s.Signature(userID, mediaID, variant, unix), url.PathEscape(mediaID))
+}
+
+// CanonicalVariant 將影響檔案內容的參數轉為固定格式；皆未提供時為 VariantOriginal
+func CanonicalVariant(query url.Values) string {
+	v := url.Values{}
+	for _, key := range variantParams {
+		if val := query.Get(key); val != "" {
+			v.Set(key, val)
+		}
+	}
+	if len(v) == 0 {
+		return VariantOriginal
+	}
+	return v.Encode()
+}
+
+// SignedURLMiddleware 驗證簽章網址並將 User ID 注入 Context，不呼叫 Google JWKs 也不查詢 users 資料表
+//
+// 路由：/signed/:uid/:exp/:sig/media/:id/file 與 /signed/:uid/:exp/:sig/media/:id/hls/*file，
+// 之後掛載與一般路由相同的 GetFileHandler、StreamHandler (媒體存取權仍由 handler 檢查)
+func SignedURLMiddleware(signer *URLSigner) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		userID, mediaID := c.Param("uid"), c.Param("id")
+		exp, err := strconv.ParseInt(c.Param("exp"), 10, 64)
+		if err != nil || userID == "" || mediaID == "" {
+			c.AbortWithStatusJSON(http.StatusForbidden, gin.H{"error": "invalid signed url"})
+			return
+		}
+		if time.Now().Unix() > exp {
+			c.AbortWithStatusJSON(http.StatusForbidden, gin.H{"error": "signed url expired"})
+			return
+		}
+
+		variant := VariantHLS
+		if c.Param("file") == "" {
+			variant = CanonicalVariant(c.Request.URL.Query())
+		}
+		expected := signer.Signature(userID, mediaID, variant, exp)
+		if !hmac.Equal([]byte(expected), []byte(c.Param("sig"))) {
+			c.AbortWithStatusJSON(http.StatusForbidden, gin.H{"error": "invalid signature"})
+			return
+		}
+
+		c.Set("userID", userID)
+		c.Next()
+	}
+}
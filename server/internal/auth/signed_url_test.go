@@ -0,0 +1,107 @@
+package auth
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+func newTestSigner(t *testing.T) *URLSigner {
+	t.Helper()
+	s, err := NewURLSigner([]byte(strings.Repeat("k", 32)), time.Hour)
+	if err != nil {
+		t.Fatalf("NewURLSigner failed: %v", err)
+	}
+	return s
+}
+
+func TestURLSignerExpiryIsStableWithinWindow(t *testing.T) {
+	s := newTestSigner(t)
+	base := time.Date(2025, 3, 1, 10, 5, 0, 0, time.UTC)
+	a, b := s.Expiry(base), s.Expiry(base.Add(50*time.Minute))
+	if !a.Equal(b) {
+		t.Errorf("expected same expiry, got %v and %v", a, b)
+	}
+	if d := a.Sub(base.Add(50 * time.Minute)); d < time.Hour {
+		t.Errorf("expected at least one ttl of validity, got %v", d)
+	}
+	if _, err := NewURLSigner([]byte("short"), time.Hour); err == nil {
+		t.Error("expected error for short secret")
+	}
+}
+
+func TestSignedURLMiddleware(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	s := newTestSigner(t)
+
+	r := gin.New()
+	signed := r.Group("/signed/:uid/:exp/:sig", SignedURLMiddleware(s))
+	ok := func(c *gin.Context) { c.String(http.StatusOK, c.MustGet("userID").(string)) }
+	signed.GET("/media/:id/file", ok)
+	signed.GET("/media/:id/hls/*file", ok)
+
+	do := func(target string) *httptest.ResponseRecorder {
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, httptest.NewRequest(http.MethodGet, target, nil))
+		return w
+	}
+
+	exp := s.Expiry(time.Now())
+	thumb := s.FileURL("user-1", "media-1", url.Values{"w": {"512"}, "fit": {"cover"}}, exp)
+	if w := do(thumb); w.Code != http.StatusOK || w.Body.String() != "user-1" {
+		t.Errorf("expected valid thumbnail url, got %d %s", w.Code, w.Body.String())
+	}
+
+	// 竄改縮放參數 (例如移除以取得原始檔) 應失效
+	if w := do(strings.Split(thumb, "?")[0]); w.Code != http.StatusForbidden {
+		t.Errorf("expected tampered url to be rejected, got %d", w.Code)
+	}
+	if w := do(strings.Replace(thumb, "/media-1/", "/media-2/", 1)); w.Code != http.StatusForbidden {
+		t.Errorf("expected other media to be rejected, got %d", w.Code)
+	}
+
+	// 串流片段以相對路徑沿用 playlist 的簽章前綴
+	stream := s.StreamURL("user-1", "media-1", exp)
+	segment := strings.TrimSuffix(stream, "master.m3u8") + "720p/seg_000.ts"
+	if w := do(segment); w.Code != http.StatusOK {
+		t.Errorf("expected segment to be accepted, got %d", w.Code)
+	}
+
+	expired := s.FileURL("user-1", "media-1", nil, time.Now().Add(-time.Minute))
+	if w := do(expired); w.Code != http.StatusForbidden {
+		t.Errorf("expected expired url to be rejected, got %d", w.Code)
+	}
+}
+
+func TestNewURLSignerFromEnv(t *testing.T) {
+	t.Setenv("SIGNED_URL_SECRET", "")
+	if _, err := NewURLSignerFromEnv(); err == nil {
+		t.Error("expected error when secret is missing")
+	}
+
+	t.Setenv("SIGNED_URL_SECRET", "short")
+	if _, err := NewURLSignerFromEnv(); err == nil {
+		t.Error("expected error for short secret")
+	}
+
+	t.Setenv("SIGNED_URL_SECRET", strings.Repeat("k", 32))
+	t.Setenv("SIGNED_URL_TTL", "soon")
+	if _, err := NewURLSignerFromEnv(); err == nil {
+		t.Error("expected error for invalid ttl")
+	}
+
+	t.Setenv("SIGNED_URL_TTL", "30m")
+	t.Setenv("SIGNED_URL_PREFIX", "/api")
+	s, err := NewURLSignerFromEnv()
+	if err != nil {
+		t.Fatalf("NewURLSignerFromEnv failed: %v", err)
+	}
+	if s.ttl != 30*time.Minute || !strings.HasPrefix(s.StreamURL("u", "m", time.Now()), "/api/signed/") {
+		t.Errorf("unexpected signer config: ttl %v, prefix %q", s.ttl, s.Prefix)
+	}
+}
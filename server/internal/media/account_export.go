@@ -0,0 +1,554 @@
+package media
+
+import (
+	"archive/zip"
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"time"
+)
+
+// 帳號匯出狀態 (account_exports.status)
+const (
+	ExportPending    = "pending"
+	ExportProcessing = "processing"
+	ExportReady      = "ready"
+	ExportFailed     = "failed"
+	ExportExpired    = "expired" // 已過期，檔案已刪除
+)
+
+// AccountExportTTL 完成後可下載的期間
+const AccountExportTTL = 7 * 24 * time.Hour
+
+const (
+	accountDumpName               = "gogallery-export.json"
+	accountDumpFormat             = "gogallery-account-export/v1"
+	accountExportProgressInterval = 2 * time.Second // 寫回進度的最短間隔
+	accountExportPurgeInterval    = time.Hour
+)
+
+var (
+	ErrExportNotReady = errors.New("export is not ready")
+	ErrExportExpired  = errors.New("export has expired")
+)
+
+// AccountExport 帳號匯出工作
+type AccountExport struct {
+	ID          string     `json:"id"`
+	Status      string     `json:"status"`
+	MediaCount  int        `json:"media_count"`
+	TotalBytes  int64      `json:"total_bytes"`
+	DoneBytes   int64      `json:"done_bytes"`
+	Progress    float64    `json:"progress"`   // 0 ~ 1
+	SizeBytes   int64      `json:"size_bytes"` // 完成後的 ZIP 大小
+	Error       string     `json:"error,omitempty"`
+	CreatedAt   time.Time  `json:"created_at"`
+	CompletedAt *time.Time `json:"completed_at"`
+	ExpiresAt   *time.Time `json:"expires_at"`
+}
+
+const accountExportColumns = `id, status, media_count, total_bytes, done_bytes, size_bytes, error, created_at, completed_at, expires_at`
+
+func scanAccountExport(row rowScanner, e *AccountExport) error {
+	err := row.Scan(&e.ID, &e.Status, &e.MediaCount, &e.TotalBytes, &e.DoneBytes, &e.SizeBytes, &e.Error, &e.CreatedAt, &e.CompletedAt, &e.ExpiresAt)
+	if err != nil {
+		return err
+	}
+	switch {
+	case e.Status == ExportReady || e.Status == ExportExpired:
+		e.Progress = 1
+	case e.TotalBytes > 0:
+		e.Progress = min(float64(e.DoneBytes)/float64(e.TotalBytes), 1)
+	}
+	return nil
+}
+
+// accountExportPath 完成的匯出檔案位置
+func (s *Service) accountExportPath(exportID string) string {
+	return filepath.Join(s.UploadDir, "exports", exportID+".zip")
+}
+
+// RequestAccountExport 建立帳號匯出並排入背景處理；已有進行中的匯出時直接回傳該筆
+func (s *Service) RequestAccountExport(ctx context.Context, userID string) (*AccountExport, error) {
+	var id string
+	err := s.DB.QueryRowContext(ctx, `
+		INSERT INTO account_exports (user_id) VALUES ($1)
+		ON CONFLICT (user_id) WHERE status IN ('pending', 'processing') DO NOTHING
+		RETURNING id
+	`, userID).Scan(&id)
+	switch {
+	case err == sql.ErrNoRows:
+		err = s.DB.QueryRowContext(ctx, `
+			SELECT id FROM account_exports WHERE user_id = $1 AND status IN ('pending', 'processing')
+		`, userID).Scan(&id)
+		if err != nil {
+			return nil, fmt.Errorf("failed to query active export: %w", err)
+		}
+	case err != nil:
+		return nil, fmt.Errorf("failed to create export: %w", err)
+	case s.AccountExports != nil:
+		s.AccountExports.Enqueue(id)
+	}
+	return s.GetAccountExport(ctx, userID, id)
+}
+
+// ListAccountExports 列出使用者的帳號匯出 (新到舊)
+func (s *Service) ListAccountExports(ctx context.Context, userID string) ([]*AccountExport, error) {
+	query := `SELECT ` + accountExportColumns + ` FROM account_exports WHERE user_id = $1 ORDER BY created_at DESC, id`
+	rows, err := s.DB.QueryContext(ctx, query, userID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query exports: %w", err)
+	}
+	defer rows.Close()
+
+	list := []*AccountExport{}
+	for rows.Next() {
+		e := &AccountExport{}
+		if err := scanAccountExport(rows, e); err != nil {
+			return nil, fmt.Errorf("failed to scan export: %w", err)
+		}
+		list = append(list, e)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to iterate exports: %w", err)
+	}
+	return list, nil
+}
+
+// GetAccountExport 取得單一帳號匯出 (含進度)
+func (s *Service) GetAccountExport(ctx context.Context, userID, exportID string) (*AccountExport, error) {
+	query := `SELECT ` + accountExportColumns + ` FROM account_exports WHERE id = $1 AND user_id = $2`
+	e := &AccountExport{}
+	err := scanAccountExport(s.DB.QueryRowContext(ctx, query, exportID, userID), e)
+	if err == sql.ErrNoRows {
+		return nil, ErrExportNotFound
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to query export: %w", err)
+	}
+	return e, nil
+}
+
+// AccountExportFile 回傳可下載的匯出檔案路徑
+func (s *Service) AccountExportFile(ctx context.Context, userID, exportID string) (string, *AccountExport, error) {
+	e, err := s.GetAccountExport(ctx, userID, exportID)
+	if err != nil {
+		return "", nil, err
+	}
+	if e.Status == ExportExpired || (e.ExpiresAt != nil && time.Now().After(*e.ExpiresAt)) {
+		return "", nil, ErrExportExpired
+	}
+	if e.Status != ExportReady {
+		return "", nil, ErrExportNotReady
+	}
+	return s.accountExportPath(e.ID), e, nil
+}
+
+// accountDump 匯出檔中的 gogallery-export.json：帳號內所有可攜的資料
+type accountDump struct {
+	Format      string              `json:"format"`
+	ExportedAt  time.Time           `json:"exported_at"`
+	User        accountDumpUser     `json:"user"`
+	Media       []*accountDumpMedia `json:"media"` // 包含垃圾桶中的項目 (deleted_at 不為 null)
+	Albums      []*accountDumpAlbum `json:"albums"`
+	SmartAlbums []*SmartAlbum       `json:"smart_albums"`
+	Tags        []string            `json:"tags"` // 所有標籤 (包含目前沒有使用的)
+}
+
+type accountDumpUser struct {
+	ID    string `json:"id"`
+	Email string `json:"email"`
+}
+
+// accountDumpMedia 媒體的所有欄位 (含我的最愛、封存、標籤與覆寫) 加上壓縮檔內的路徑
+type accountDumpMedia struct {
+	*Media
+	File      *string `json:"file"` // 原始檔在壓縮檔中的路徑；磁碟上找不到時為 null
+	Extracted struct {
+		TakenAt   *time.Time `json:"taken_at"`
+		Latitude  *float64   `json:"latitude"`
+		Longitude *float64   `json:"longitude"`
+	} `json:"extracted"` // 上傳時解析出的原始值 (使用者覆寫前)
+
+	path    string
+	size    int64
+	modTime time.Time
+}
+
+// accountDumpAlbum 擁有的相簿 (受邀加入的共享相簿不包含在內)
+type accountDumpAlbum struct {
+	ID           string          `json:"id"`
+	Title        string          `json:"title"`
+	Description  string          `json:"description"`
+	CoverMediaID *string         `json:"cover_media_id"`
+	CreatedAt    time.Time       `json:"created_at"`
+	UpdatedAt    time.Time       `json:"updated_at"`
+	MediaIDs     json.RawMessage `json:"media_ids"` // 依手動排序
+}
+
+// accountDump 讀取使用者的所有資料，並決定每個原始檔在壓縮檔中的路徑 (media/年份/檔名)
+func (s *Service) accountDump(ctx context.Context, userID string) (*accountDump, error) {
+	d := &accountDump{Format: accountDumpFormat, ExportedAt: time.Now().UTC(), User: accountDumpUser{ID: userID}}
+	if err := s.DB.QueryRowContext(ctx, `SELECT email FROM users WHERE id = $1`, userID).Scan(&d.User.Email); err != nil {
+		return nil, fmt.Errorf("failed to query user: %w", err)
+	}
+
+	rows, err := s.DB.QueryContext(ctx, `
+		SELECT `+mediaColumns+`, m.storage_path, m.extracted_taken_at, m.extracted_latitude, m.extracted_longitude
+		FROM media m
+		WHERE m.user_id = $1
+		ORDER BY COALESCE(m.taken_at, m.uploaded_at), m.id
+	`, userID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query media: %w", err)
+	}
+	defer rows.Close()
+
+	used := map[string]bool{accountDumpName: true}
+	d.Media = []*accountDumpMedia{}
+	for rows.Next() {
+		dm := &accountDumpMedia{Media: &Media{}}
+		if err := scanMedia(rows, dm.Media, &dm.StoragePath, &dm.Extracted.TakenAt, &dm.Extracted.Latitude, &dm.Extracted.Longitude); err != nil {
+			return nil, fmt.Errorf("failed to scan media: %w", err)
+		}
+		dm.path = filepath.Join(s.UploadDir, dm.StoragePath)
+		if info, err := os.Stat(dm.path); err == nil {
+			dm.modTime = dm.UploadedAt
+			if dm.TakenAt != nil {
+				dm.modTime = *dm.TakenAt
+			}
+			name := uniqueZipName(fmt.Sprintf("media/%d/%s", dm.modTime.Year(), sanitizeZipName(dm.Media)), used)
+			dm.File, dm.size = &name, info.Size()
+		} else {
+			fmt.Printf("account export: missing original for %s: %v\n", dm.ID, err)
+		}
+		d.Media = append(d.Media, dm)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to iterate media: %w", err)
+	}
+	rows.Close()
+
+	if d.Albums, err = s.accountDumpAlbums(ctx, userID); err != nil {
+		return nil, err
+	}
+	if d.SmartAlbums, err = s.ListSmartAlbums(ctx, userID); err != nil {
+		return nil, err
+	}
+	if d.Tags, err = s.accountDumpTags(ctx, userID); err != nil {
+		return nil, err
+	}
+	return d, nil
+}
+
+func (s *Service) accountDumpAlbums(ctx context.Context, userID string) ([]*accountDumpAlbum, error) {
+	rows, err := s.DB.QueryContext(ctx, `
+		SELECT a.id, a.title, a.description, a.cover_media_id, a.created_at, a.updated_at,
+		       (SELECT COALESCE(json_agg(am.media_id ORDER BY am.position, am.added_at), '[]')
+		        FROM album_media am WHERE am.album_id = a.id)
+		FROM albums a
+		WHERE a.user_id = $1
+		ORDER BY a.created_at, a.id
+	`, userID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query albums: %w", err)
+	}
+	defer rows.Close()
+
+	list := []*accountDumpAlbum{}
+	for rows.Next() {
+		a := &accountDumpAlbum{}
+		var ids []byte
+		if err := rows.Scan(&a.ID, &a.Title, &a.Description, &a.CoverMediaID, &a.CreatedAt, &a.UpdatedAt, &ids); err != nil {
+			return nil, fmt.Errorf("failed to scan album: %w", err)
+		}
+		a.MediaIDs = json.RawMessage(ids)
+		list = append(list, a)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to iterate albums: %w", err)
+	}
+	return list, nil
+}
+
+func (s *Service) accountDumpTags(ctx context.Context, userID string) ([]string, error) {
+	rows, err := s.DB.QueryContext(ctx, `SELECT name FROM tags WHERE user_id = $1 ORDER BY lower(name)`, userID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query tags: %w", err)
+	}
+	defer rows.Close()
+
+	list := []string{}
+	for rows.Next() {
+		var name string
+		if err := rows.Scan(&name); err != nil {
+			return nil, fmt.Errorf("failed to scan tag: %w", err)
+		}
+		list = append(list, name)
+	}
+	return list, rows.Err()
+}
+
+// AccountExporter 以背景 worker 產生帳號匯出，並定期刪除過期的檔案
+//
+// 建立匯出時呼叫 Enqueue；佇列已滿或伺服器重啟時項目保持 pending，由 ResumePending 補做
+type AccountExporter struct {
+	Service *Service
+
+	jobs chan string
+}
+
+func NewAccountExporter(s *Service) *AccountExporter {
+	return &AccountExporter{Service: s, jobs: make(chan string, 64)}
+}
+
+// Start 啟動 worker (一次處理一個匯出，避免大量磁碟 I/O 互相干擾)，ctx 結束時停止
+func (x *AccountExporter) Start(ctx context.Context) {
+	go func() {
+		ticker := time.NewTicker(accountExportPurgeInterval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case id := <-x.jobs:
+				if err := x.process(ctx, id); err != nil {
+					fmt.Printf("account export failed (export: %s): %v\n", id, err)
+				}
+			case <-ticker.C:
+				if err := x.PurgeExpired(ctx); err != nil {
+					fmt.Printf("failed to purge expired exports: %v\n", err)
+				}
+				// 多選匯出沒有自己的 worker，過期記錄一併在此清除
+				if err := x.Service.PurgeExpiredZipExports(ctx); err != nil {
+					fmt.Printf("failed to purge expired zip exports: %v\n", err)
+				}
+			}
+		}
+	}()
+}
+
+// Enqueue 加入匯出佇列 (不阻塞)
+func (x *AccountExporter) Enqueue(exportID string) {
+	select {
+	case x.jobs <- exportID:
+	default:
+		fmt.Printf("account export queue full, export %s stays pending\n", exportID)
+	}
+}
+
+// ResumePending 重新排入等待中的匯出 (含伺服器異常結束時停在 processing 的匯出，進度歸零重新產生)
+// 伺服器啟動時呼叫
+func (x *AccountExporter) ResumePending(ctx context.Context) error {
+	db := x.Service.DB
+	if _, err := db.ExecContext(ctx, `UPDATE account_exports SET status = $1, done_bytes = 0 WHERE status = $2`, ExportPending, ExportProcessing); err != nil {
+		return fmt.Errorf("failed to reset processing exports: %w", err)
+	}
+
+	rows, err := db.QueryContext(ctx, `SELECT id FROM account_exports WHERE status = $1 ORDER BY created_at`, ExportPending)
+	if err != nil {
+		return fmt.Errorf("failed to query pending exports: %w", err)
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var id string
+		if err := rows.Scan(&id); err != nil {
+			return fmt.Errorf("failed to scan pending export: %w", err)
+		}
+		x.Enqueue(id)
+	}
+	return rows.Err()
+}
+
+// PurgeExpired 刪除過期的匯出檔案並標記為 expired
+func (x *AccountExporter) PurgeExpired(ctx context.Context) error {
+	s := x.Service
+	rows, err := s.DB.QueryContext(ctx, `SELECT id FROM account_exports WHERE status = $1 AND expires_at <= $2`, ExportReady, time.Now())
+	if err != nil {
+		return fmt.Errorf("failed to query expired exports: %w", err)
+	}
+	var ids []string
+	for rows.Next() {
+		var id string
+		if err := rows.Scan(&id); err != nil {
+			rows.Close()
+			return fmt.Errorf("failed to scan expired export: %w", err)
+		}
+		ids = append(ids, id)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return fmt.Errorf("failed to iterate expired exports: %w", err)
+	}
+
+	for _, id := range ids {
+		if err := os.Remove(s.accountExportPath(id)); err != nil && !os.IsNotExist(err) {
+			fmt.Printf("failed to remove export %s: %v\n", id, err)
+			continue
+		}
+		if _, err := s.DB.ExecContext(ctx, `UPDATE account_exports SET status = $2 WHERE id = $1`, id, ExportExpired); err != nil {
+			return fmt.Errorf("failed to expire export: %w", err)
+		}
+	}
+	return nil
+}
+
+// process 產生單一匯出；以 UPDATE ... WHERE status = pending 搶占，避免重複處理
+func (x *AccountExporter) process(ctx context.Context, exportID string) error {
+	s := x.Service
+
+	var userID string
+	claim := `UPDATE account_exports SET status = $2 WHERE id = $1 AND status = $3 RETURNING user_id`
+	err := s.DB.QueryRowContext(ctx, claim, exportID, ExportProcessing, ExportPending).Scan(&userID)
+	if err == sql.ErrNoRows {
+		return nil // 已被處理
+	}
+	if err != nil {
+		return fmt.Errorf("failed to claim export: %w", err)
+	}
+
+	size, buildErr := x.build(ctx, exportID, userID)
+
+	// ctx 在關閉時已取消，結果改以新的 context 寫入
+	updateCtx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	switch {
+	case buildErr != nil && ctx.Err() != nil:
+		// 伺服器重啟中斷了匯出：放回 pending 從頭產生，使用者不會看到失敗的匯出
+		_, err = s.DB.ExecContext(updateCtx, `
+			UPDATE account_exports SET status = $2, done_bytes = 0 WHERE id = $1
+		`, exportID, ExportPending)
+		if err == nil {
+			return nil
+		}
+	case buildErr != nil:
+		_, err = s.DB.ExecContext(updateCtx, `
+			UPDATE account_exports SET status = $2, error = $3, completed_at = NOW() WHERE id = $1
+		`, exportID, ExportFailed, buildErr.Error())
+	default:
+		_, err = s.DB.ExecContext(updateCtx, `
+			UPDATE account_exports SET status = $2, size_bytes = $3, done_bytes = total_bytes,
+				completed_at = NOW(), expires_at = $4
+			WHERE id = $1
+		`, exportID, ExportReady, size, time.Now().Add(AccountExportTTL))
+	}
+	if err != nil {
+		return fmt.Errorf("failed to update export status: %w", err)
+	}
+	return buildErr
+}
+
+// build 寫入 ZIP (先寫暫存檔，完成後再改名)；原始檔不壓縮
+func (x *AccountExporter) build(ctx context.Context, exportID, userID string) (int64, error) {
+	s := x.Service
+	dump, err := s.accountDump(ctx, userID)
+	if err != nil {
+		return 0, err
+	}
+
+	progress := &accountExportProgress{ctx: ctx, db: s.DB, exportID: exportID, lastUpdate: time.Now()}
+	for _, m := range dump.Media {
+		progress.total += m.size
+	}
+	_, err = s.DB.ExecContext(ctx, `UPDATE account_exports SET total_bytes = $2, media_count = $3 WHERE id = $1`,
+		exportID, progress.total, len(dump.Media))
+	if err != nil {
+		return 0, fmt.Errorf("failed to update export: %w", err)
+	}
+
+	target := s.accountExportPath(exportID)
+	if err := os.MkdirAll(filepath.Dir(target), 0755); err != nil {
+		return 0, fmt.Errorf("failed to create export directory: %w", err)
+	}
+	tmp := target + ".tmp"
+	f, err := os.Create(tmp)
+	if err != nil {
+		return 0, fmt.Errorf("failed to create export file: %w", err)
+	}
+	defer os.Remove(tmp) // 成功改名後無作用
+
+	if err := writeAccountArchive(f, dump, progress); err != nil {
+		f.Close()
+		return 0, err
+	}
+	info, err := f.Stat()
+	if err != nil {
+		f.Close()
+		return 0, fmt.Errorf("failed to stat export file: %w", err)
+	}
+	if err := f.Close(); err != nil {
+		return 0, fmt.Errorf("failed to write export file: %w", err)
+	}
+	if err := os.Rename(tmp, target); err != nil {
+		return 0, fmt.Errorf("failed to finalize export file: %w", err)
+	}
+	return info.Size(), nil
+}
+
+// writeAccountArchive 寫入 gogallery-export.json 與所有原始檔
+func writeAccountArchive(w io.Writer, dump *accountDump, progress *accountExportProgress) error {
+	zw := zip.NewWriter(w)
+
+	data, err := json.MarshalIndent(dump, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to encode metadata: %w", err)
+	}
+	dw, err := zw.CreateHeader(&zip.FileHeader{Name: accountDumpName, Method: zip.Deflate, Modified: dump.ExportedAt})
+	if err != nil {
+		return fmt.Errorf("failed to write metadata: %w", err)
+	}
+	if _, err := dw.Write(data); err != nil {
+		return fmt.Errorf("failed to write metadata: %w", err)
+	}
+
+	for _, m := range dump.Media {
+		if m.File == nil {
+			continue
+		}
+		if err := progress.ctx.Err(); err != nil {
+			return err
+		}
+		fw, err := zw.CreateHeader(&zip.FileHeader{Name: *m.File, Method: zip.Store, Modified: m.modTime})
+		if err != nil {
+			return fmt.Errorf("failed to add %s: %w", *m.File, err)
+		}
+		src, err := os.Open(m.path)
+		if err != nil {
+			return fmt.Errorf("failed to open original for %s: %w", m.ID, err)
+		}
+		_, err = io.Copy(fw, io.TeeReader(src, progress))
+		src.Close()
+		if err != nil {
+			return fmt.Errorf("failed to write %s: %w", *m.File, err)
+		}
+	}
+	return zw.Close()
+}
+
+// accountExportProgress 記錄已寫入的原始檔位元組數，定期寫回資料庫
+type accountExportProgress struct {
+	ctx      context.Context
+	db       *sql.DB
+	exportID string
+
+	total, done int64
+	lastUpdate  time.Time
+}
+
+// Write 實作 io.Writer (搭配 io.TeeReader 計數)
+func (p *accountExportProgress) Write(b []byte) (int, error) {
+	p.done += int64(len(b))
+	if p.db != nil && time.Since(p.lastUpdate) >= accountExportProgressInterval {
+		p.lastUpdate = time.Now()
+		if _, err := p.db.ExecContext(p.ctx, `UPDATE account_exports SET done_bytes = $2 WHERE id = $1`, p.exportID, p.done); err != nil {
+			fmt.Printf("failed to update export progress: %v\n", err)
+		}
+	}
+	return len(b), nil
+}
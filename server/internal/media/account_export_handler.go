@@ -0,0 +1,108 @@
+package media
+
+import (
+	"errors"
+	"mime"
+	"net/http"
+	"os"
+
+	"github.com/gin-gonic/gin"
+)
+
+// respondAccountExportError 將帳號匯出錯誤轉換為 HTTP 狀態碼
+func respondAccountExportError(c *gin.Context, err error) {
+	switch {
+	case errors.Is(err, ErrExportNotFound):
+		c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+	case errors.Is(err, ErrExportNotReady):
+		c.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+	case errors.Is(err, ErrExportExpired):
+		c.JSON(http.StatusGone, gin.H{"error": err.Error()})
+	default:
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+	}
+}
+
+// accountExportParam 取得並驗證路徑中的匯出 ID
+func accountExportParam(c *gin.Context) (string, bool) {
+	exportID := c.Param("id")
+	if !uuidPattern.MatchString(exportID) {
+		c.JSON(http.StatusNotFound, gin.H{"error": ErrExportNotFound.Error()})
+		return "", false
+	}
+	return exportID, true
+}
+
+// RequestAccountExportHandler 匯出整個帳號 (POST /account/exports)
+// 於背景產生包含所有原始檔與 gogallery-export.json 的 ZIP；已有進行中的匯出時回傳該筆
+func (h *Handler) RequestAccountExportHandler(c *gin.Context) {
+	userID := c.MustGet("userID").(string)
+
+	export, err := h.Service.RequestAccountExport(c.Request.Context(), userID)
+	if err != nil {
+		respondAccountExportError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusAccepted, export)
+}
+
+// ListAccountExportsHandler 列出帳號匯出 (GET /account/exports)
+func (h *Handler) ListAccountExportsHandler(c *gin.Context) {
+	userID := c.MustGet("userID").(string)
+
+	list, err := h.Service.ListAccountExports(c.Request.Context(), userID)
+	if err != nil {
+		respondAccountExportError(c, err)
+		return
+	}
+
+	jsonWithETag(c, http.StatusOK, list)
+}
+
+// GetAccountExportHandler 查詢匯出狀態與進度 (GET /account/exports/:id)
+func (h *Handler) GetAccountExportHandler(c *gin.Context) {
+	userID := c.MustGet("userID").(string)
+	exportID, ok := accountExportParam(c)
+	if !ok {
+		return
+	}
+
+	export, err := h.Service.GetAccountExport(c.Request.Context(), userID, exportID)
+	if err != nil {
+		respondAccountExportError(c, err)
+		return
+	}
+
+	jsonWithETag(c, http.StatusOK, export)
+}
+
+// DownloadAccountExportHandler 下載完成的匯出 (GET /account/exports/:id/download)，支援 Range 續傳
+// 尚未完成時回傳 409，過期後回傳 410
+func (h *Handler) DownloadAccountExportHandler(c *gin.Context) {
+	userID := c.MustGet("userID").(string)
+	exportID, ok := accountExportParam(c)
+	if !ok {
+		return
+	}
+
+	path, export, err := h.Service.AccountExportFile(c.Request.Context(), userID, exportID)
+	if err != nil {
+		respondAccountExportError(c, err)
+		return
+	}
+
+	f, err := os.Open(path)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "export file is missing"})
+		return
+	}
+	defer f.Close()
+
+	filename := "gogallery-export-" + export.CompletedAt.UTC().Format("2006-01-02") + ".zip"
+	c.Header("Content-Type", "application/zip")
+	c.Header("Content-Disposition", mime.FormatMediaType("attachment", map[string]string{"filename": filename}))
+	c.Header("Cache-Control", "private, no-store")
+	c.Header("ETag", `"`+export.ID+`"`)
+	http.ServeContent(c.Writer, c.Request, "", *export.CompletedAt, f)
+}
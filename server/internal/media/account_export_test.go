@@ -0,0 +1,162 @@
+package media
+
+import (
+	"archive/zip"
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+)
+
+const accountExportID = "66666666-6666-6666-6666-666666666666"
+
+func TestAccountExporterProcess(t *testing.T) {
+	uploadDir := t.TempDir()
+	writeTakeoutFile(t, filepath.Join(uploadDir, "user-1", "m-1.jpg"), "original bytes")
+
+	db, mock := newMockDB(t)
+	s := &Service{DB: db, UploadDir: uploadDir}
+	x := NewAccountExporter(s)
+	takenAt := time.Date(2021, 7, 4, 10, 0, 0, 0, time.UTC)
+
+	mock.ExpectQuery(`UPDATE account_exports SET status = \$2 WHERE id = \$1 AND status = \$3 RETURNING user_id`).
+		WithArgs(accountExportID, ExportProcessing, ExportPending).
+		WillReturnRows(sqlmock.NewRows([]string{"user_id"}).AddRow("user-1"))
+	mock.ExpectQuery(`SELECT email FROM users`).
+		WithArgs("user-1").
+		WillReturnRows(sqlmock.NewRows([]string{"email"}).AddRow("alice@example.com"))
+	columns := append(append([]string{}, mediaTestColumns...), "storage_path", "extracted_taken_at", "extracted_latitude", "extracted_longitude")
+	favorite := mediaTestRow("m-1", takenAt)
+	favorite[22] = true // is_favorite
+	mock.ExpectQuery(`FROM media m\s+WHERE m.user_id = \$1`).
+		WithArgs("user-1").
+		WillReturnRows(sqlmock.NewRows(columns).
+			AddRow(append(favorite, "user-1/m-1.jpg", takenAt, nil, nil)...).
+			AddRow(append(mediaTestRow("m-2", takenAt), "user-1/missing.jpg", nil, nil, nil)...))
+	mock.ExpectQuery(`FROM albums a\s+WHERE a.user_id = \$1`).
+		WithArgs("user-1").
+		WillReturnRows(sqlmock.NewRows([]string{"id", "title", "description", "cover_media_id", "created_at", "updated_at", "media_ids"}).
+			AddRow("album-1", "Summer", "", nil, takenAt, takenAt, []byte(`["m-1"]`)))
+	mock.ExpectQuery(`FROM smart_albums WHERE user_id = \$1`).
+		WithArgs("user-1").
+		WillReturnRows(sqlmock.NewRows([]string{"id"}))
+	mock.ExpectQuery(`SELECT name FROM tags`).
+		WithArgs("user-1").
+		WillReturnRows(sqlmock.NewRows([]string{"name"}).AddRow("Travel"))
+	mock.ExpectExec(`UPDATE account_exports SET total_bytes = \$2, media_count = \$3`).
+		WithArgs(accountExportID, int64(len("original bytes")), 2).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectExec(`UPDATE account_exports SET status = \$2, size_bytes = \$3`).
+		WithArgs(accountExportID, ExportReady, sqlmock.AnyArg(), sqlmock.AnyArg()).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+
+	if err := x.process(context.Background(), accountExportID); err != nil {
+		t.Fatalf("process failed: %v", err)
+	}
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("unmet expectations: %v", err)
+	}
+
+	zr, err := zip.OpenReader(s.accountExportPath(accountExportID))
+	if err != nil {
+		t.Fatalf("export is not a valid zip: %v", err)
+	}
+	defer zr.Close()
+	if len(zr.File) != 2 || zr.File[0].Name != accountDumpName || zr.File[1].Name != "media/2021/m-1.jpg" {
+		t.Fatalf("unexpected entries: %v", zr.File)
+	}
+	if _, err := os.Stat(s.accountExportPath(accountExportID) + ".tmp"); !os.IsNotExist(err) {
+		t.Error("temporary file should be removed")
+	}
+
+	rc, _ := zr.File[0].Open()
+	data, _ := io.ReadAll(rc)
+	rc.Close()
+	var dump struct {
+		User   accountDumpUser `json:"user"`
+		Media  []map[string]any
+		Albums []struct {
+			MediaIDs []string `json:"media_ids"`
+		}
+		Tags []string
+	}
+	if err := json.Unmarshal(data, &dump); err != nil {
+		t.Fatalf("invalid metadata: %v", err)
+	}
+	if dump.User.Email != "alice@example.com" || len(dump.Media) != 2 || len(dump.Tags) != 1 {
+		t.Fatalf("unexpected dump: %s", data)
+	}
+	if dump.Media[0]["file"] != "media/2021/m-1.jpg" || dump.Media[0]["is_favorite"] != true || dump.Media[1]["file"] != nil {
+		t.Errorf("unexpected media entries: %v", dump.Media)
+	}
+	if len(dump.Albums) != 1 || len(dump.Albums[0].MediaIDs) != 1 || dump.Albums[0].MediaIDs[0] != "m-1" {
+		t.Errorf("unexpected albums: %+v", dump.Albums)
+	}
+}
+
+func TestAccountExportFileStates(t *testing.T) {
+	db, mock := newMockDB(t)
+	s := &Service{DB: db, UploadDir: t.TempDir()}
+	columns := []string{"id", "status", "media_count", "total_bytes", "done_bytes", "size_bytes", "error", "created_at", "completed_at", "expires_at"}
+	now := time.Now()
+
+	mock.ExpectQuery(`FROM account_exports WHERE id = \$1 AND user_id = \$2`).
+		WithArgs(accountExportID, "user-1").
+		WillReturnRows(sqlmock.NewRows(columns).AddRow(accountExportID, ExportProcessing, 10, int64(400), int64(100), int64(0), "", now, nil, nil))
+	export, err := s.GetAccountExport(context.Background(), "user-1", accountExportID)
+	if err != nil || export.Progress != 0.25 {
+		t.Fatalf("unexpected export: %+v, %v", export, err)
+	}
+
+	mock.ExpectQuery(`FROM account_exports WHERE id = \$1 AND user_id = \$2`).
+		WithArgs(accountExportID, "user-1").
+		WillReturnRows(sqlmock.NewRows(columns).AddRow(accountExportID, ExportProcessing, 10, int64(400), int64(100), int64(0), "", now, nil, nil))
+	if _, _, err := s.AccountExportFile(context.Background(), "user-1", accountExportID); !errors.Is(err, ErrExportNotReady) {
+		t.Errorf("expected ErrExportNotReady, got %v", err)
+	}
+
+	past := now.Add(-time.Hour)
+	mock.ExpectQuery(`FROM account_exports WHERE id = \$1 AND user_id = \$2`).
+		WithArgs(accountExportID, "user-1").
+		WillReturnRows(sqlmock.NewRows(columns).AddRow(accountExportID, ExportReady, 10, int64(400), int64(400), int64(500), "", past, past, past))
+	if _, _, err := s.AccountExportFile(context.Background(), "user-1", accountExportID); !errors.Is(err, ErrExportExpired) {
+		t.Errorf("expected ErrExportExpired, got %v", err)
+	}
+
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("unmet expectations: %v", err)
+	}
+}
+
+// 伺服器關閉中斷匯出時放回 pending (進度歸零)，不標記為失敗
+func TestAccountExporterProcessInterrupted(t *testing.T) {
+	db, mock := newMockDB(t)
+	x := NewAccountExporter(&Service{DB: db, UploadDir: t.TempDir()})
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	mock.ExpectQuery(`UPDATE account_exports SET status = \$2 WHERE id = \$1 AND status = \$3 RETURNING user_id`).
+		WithArgs(accountExportID, ExportProcessing, ExportPending).
+		WillReturnRows(sqlmock.NewRows([]string{"user_id"}).AddRow("user-1"))
+	// 讀取資料時被取消
+	mock.ExpectQuery(`SELECT email FROM users`).
+		WillDelayFor(time.Second).
+		WillReturnRows(sqlmock.NewRows([]string{"email"}).AddRow("alice@example.com"))
+	mock.ExpectExec(`UPDATE account_exports SET status = \$2, done_bytes = 0 WHERE id = \$1`).
+		WithArgs(accountExportID, ExportPending).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+
+	time.AfterFunc(50*time.Millisecond, cancel)
+	if err := x.process(ctx, accountExportID); err != nil {
+		t.Fatalf("interrupted export should not be an error: %v", err)
+	}
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Error(err)
+	}
+}
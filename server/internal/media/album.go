@@ -0,0 +1,338 @@
+package media
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"slices"
+)
+
+var (
+	ErrAlbumNotFound   = errors.New("album not found")
+	ErrMediaNotInAlbum = errors.New("media not found in album")
+	ErrAlbumForbidden  = errors.New("insufficient album permission")
+)
+
+// 相簿角色：擁有者以外的角色記錄於 album_members
+const (
+	AlbumRoleOwner       = "owner"
+	AlbumRoleContributor = "contributor" // 可加入與移除自己的媒體
+	AlbumRoleViewer      = "viewer"      // 只能瀏覽與下載
+)
+
+// albumColumns 相簿查詢共用欄位 (資料表別名須為 a)
+// 封面與數量皆排除垃圾桶中的媒體
+const albumColumns = `a.id, a.user_id, a.title, a.description, a.created_at, a.updated_at,
+	(SELECT COUNT(*) FROM album_media am JOIN media m ON m.id = am.media_id
+	 WHERE am.album_id = a.id AND m.deleted_at IS NULL) AS media_count,
+	COALESCE(
+		(SELECT m.id FROM media m WHERE m.id = a.cover_media_id AND m.deleted_at IS NULL),
+		(SELECT am.media_id FROM album_media am JOIN media m ON m.id = am.media_id
+		 WHERE am.album_id = a.id AND m.deleted_at IS NULL
+		 ORDER BY am.position LIMIT 1)
+	) AS cover_media_id`
+
+// albumMemberJoin 連結目前使用者 (userParam) 的成員資料 (別名 mb)，搭配 albumRoleColumn 與 albumAccessible 使用
+const albumMemberJoin = `LEFT JOIN album_members mb ON mb.album_id = a.id AND mb.user_id = `
+
+// albumRoleColumn 目前使用者在相簿中的角色 (userParam 為使用者 ID 的參數位置)
+func albumRoleColumn(userParam string) string {
+	return `CASE WHEN a.user_id = ` + userParam + ` THEN '` + AlbumRoleOwner + `' ELSE mb.role END`
+}
+
+// albumAccessible 使用者為擁有者或成員
+func albumAccessible(userParam string) string {
+	return `(a.user_id = ` + userParam + ` OR mb.user_id IS NOT NULL)`
+}
+
+func scanAlbum(row rowScanner, a *Album) error {
+	return row.Scan(&a.ID, &a.UserID, &a.Title, &a.Description, &a.CreatedAt, &a.UpdatedAt, &a.MediaCount, &a.CoverMediaID, &a.Role)
+}
+
+// CreateAlbum 建立相簿
+func (s *Service) CreateAlbum(ctx context.Context, userID, title, description string) (*Album, error) {
+	var id string
+	query := `INSERT INTO albums (user_id, title, description) VALUES ($1, $2, $3) RETURNING id`
+	if err := s.DB.QueryRowContext(ctx, query, userID, title, description).Scan(&id); err != nil {
+		return nil, fmt.Errorf("failed to create album: %w", err)
+	}
+	return s.GetAlbum(ctx, userID, id)
+}
+
+// ListAlbums 取得使用者的相簿列表 (包含受邀加入的共享相簿，最近更新優先)
+func (s *Service) ListAlbums(ctx context.Context, userID string) ([]*Album, error) {
+	query := `
+		SELECT ` + albumColumns + `, ` + albumRoleColumn("$1") + `
+		FROM albums a
+		` + albumMemberJoin + `$1
+		WHERE ` + albumAccessible("$1") + `
+		ORDER BY a.updated_at DESC
+	`
+	rows, err := s.DB.QueryContext(ctx, query, userID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query albums: %w", err)
+	}
+	defer rows.Close()
+
+	list := []*Album{}
+	for rows.Next() {
+		a := &Album{}
+		if err := scanAlbum(rows, a); err != nil {
+			return nil, fmt.Errorf("failed to scan album: %w", err)
+		}
+		list = append(list, a)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to iterate albums: %w", err)
+	}
+	return list, nil
+}
+
+// GetAlbum 取得單一相簿 (擁有者或成員)
+func (s *Service) GetAlbum(ctx context.Context, userID, albumID string) (*Album, error) {
+	query := `
+		SELECT ` + albumColumns + `, ` + albumRoleColumn("$2") + `
+		FROM albums a
+		` + albumMemberJoin + `$2
+		WHERE a.id = $1 AND ` + albumAccessible("$2")
+	a := &Album{}
+	if err := scanAlbum(s.DB.QueryRowContext(ctx, query, albumID, userID), a); err != nil {
+		if err == sql.ErrNoRows {
+			return nil, ErrAlbumNotFound
+		}
+		return nil, fmt.Errorf("failed to query album: %w", err)
+	}
+	return a, nil
+}
+
+// UpdateAlbum 更新相簿標題與描述 (nil 代表不修改)
+func (s *Service) UpdateAlbum(ctx context.Context, userID, albumID string, title, description *string) (*Album, error) {
+	query := `
+		UPDATE albums
+		SET title = COALESCE($3, title), description = COALESCE($4, description), updated_at = NOW()
+		WHERE id = $1 AND user_id = $2
+	`
+	if err := s.execAlbum(ctx, query, albumID, userID, title, description); err != nil {
+		return nil, err
+	}
+	return s.GetAlbum(ctx, userID, albumID)
+}
+
+// DeleteAlbum 刪除相簿 (不影響其中的媒體)
+func (s *Service) DeleteAlbum(ctx context.Context, userID, albumID string) error {
+	return s.execAlbum(ctx, `DELETE FROM albums WHERE id = $1 AND user_id = $2`, albumID, userID)
+}
+
+// execAlbum 執行以 (album id, user id) 為前兩個參數的更新，沒有影響任何資料列時回傳 ErrAlbumNotFound
+func (s *Service) execAlbum(ctx context.Context, query string, albumID, userID string, args ...any) error {
+	res, err := s.DB.ExecContext(ctx, query, append([]any{albumID, userID}, args...)...)
+	if err != nil {
+		return fmt.Errorf("failed to update album: %w", err)
+	}
+	if n, err := res.RowsAffected(); err == nil && n == 0 {
+		return ErrAlbumNotFound
+	}
+	return nil
+}
+
+// AddToAlbum 批次加入媒體 (依傳入順序附加在最後)，已存在或在垃圾桶中的項目略過
+// 擁有者與 contributor 只能加入自己的媒體；回傳實際新增的數量
+func (s *Service) AddToAlbum(ctx context.Context, userID, albumID string, mediaIDs []string) (int64, error) {
+	if _, err := s.requireAlbumRole(ctx, userID, albumID, AlbumRoleOwner, AlbumRoleContributor); err != nil {
+		return 0, err
+	}
+
+	n, err := s.addToAlbum(ctx, s.DB, userID, albumID, mediaIDs)
+	if err != nil {
+		return 0, err
+	}
+	s.touchAlbum(ctx, albumID)
+	return n, nil
+}
+
+// addToAlbum 附加媒體至相簿 (呼叫端需先確認相簿權限)
+func (s *Service) addToAlbum(ctx context.Context, db execer, userID, albumID string, mediaIDs []string) (int64, error) {
+	query := `
+		INSERT INTO album_media (album_id, media_id, position)
+		SELECT $1, m.id,
+		       (SELECT COALESCE(MAX(position), 0) FROM album_media WHERE album_id = $1) + v.ord
+		FROM unnest($3::uuid[]) WITH ORDINALITY AS v(media_id, ord)
+		JOIN media m ON m.id = v.media_id AND m.user_id = $2 AND m.deleted_at IS NULL
+		ON CONFLICT (album_id, media_id) DO NOTHING
+	`
+	res, err := db.ExecContext(ctx, query, albumID, userID, mediaIDs)
+	if err != nil {
+		return 0, fmt.Errorf("failed to add media to album: %w", err)
+	}
+	return res.RowsAffected()
+}
+
+// RemoveFromAlbum 批次移除媒體 (媒體本身不刪除)；若移除的是封面則清除封面設定
+// 擁有者可移除任何項目，contributor 只能移除自己的媒體 (其他項目略過)
+func (s *Service) RemoveFromAlbum(ctx context.Context, userID, albumID string, mediaIDs []string) (int64, error) {
+	role, err := s.requireAlbumRole(ctx, userID, albumID, AlbumRoleOwner, AlbumRoleContributor)
+	if err != nil {
+		return 0, err
+	}
+	ownerFilter := ""
+	if role != AlbumRoleOwner {
+		ownerFilter = ` AND media_id IN (SELECT id FROM media WHERE user_id = $3)`
+	}
+	args := []any{albumID, mediaIDs}
+	if ownerFilter != "" {
+		args = append(args, userID)
+	}
+
+	tx, err := s.DB.BeginTx(ctx, nil)
+	if err != nil {
+		return 0, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	n, err := removeAlbumMedia(ctx, tx, `album_id = $1 AND media_id = ANY($2::uuid[])`+ownerFilter, args...)
+	if err != nil {
+		return 0, err
+	}
+	if err := tx.Commit(); err != nil {
+		return 0, fmt.Errorf("failed to commit: %w", err)
+	}
+
+	s.touchAlbum(ctx, albumID)
+	return n, nil
+}
+
+// removeAlbumMedia 刪除符合條件 (where 的 $1 必須為相簿 ID) 的相簿成員，並清除指向被移除項目的封面設定
+func removeAlbumMedia(ctx context.Context, db execer, where string, args ...any) (int64, error) {
+	var n int64
+	err := db.QueryRowContext(ctx, `
+		WITH removed AS (
+			DELETE FROM album_media WHERE `+where+` RETURNING media_id
+		), reset AS (
+			UPDATE albums SET cover_media_id = NULL, updated_at = NOW()
+			WHERE id = $1 AND cover_media_id IN (SELECT media_id FROM removed)
+		)
+		SELECT COUNT(*) FROM removed
+	`, args...).Scan(&n)
+	if err != nil {
+		return 0, fmt.Errorf("failed to remove media from album: %w", err)
+	}
+	return n, nil
+}
+
+// ReorderAlbum 手動排序：mediaIDs 依序排在最前面，未列出的項目保持原本相對順序接在後面
+func (s *Service) ReorderAlbum(ctx context.Context, userID, albumID string, mediaIDs []string) error {
+	if _, err := s.requireAlbumRole(ctx, userID, albumID, AlbumRoleOwner); err != nil {
+		return err
+	}
+
+	query := `
+		UPDATE album_media am SET position = o.new_position
+		FROM (
+			SELECT cur.media_id,
+			       ROW_NUMBER() OVER (ORDER BY v.ord NULLS LAST, cur.position, cur.added_at) AS new_position
+			FROM album_media cur
+			LEFT JOIN unnest($2::uuid[]) WITH ORDINALITY AS v(media_id, ord) ON v.media_id = cur.media_id
+			WHERE cur.album_id = $1
+		) o
+		WHERE am.album_id = $1 AND am.media_id = o.media_id
+	`
+	if _, err := s.DB.ExecContext(ctx, query, albumID, mediaIDs); err != nil {
+		return fmt.Errorf("failed to reorder album: %w", err)
+	}
+	s.touchAlbum(ctx, albumID)
+	return nil
+}
+
+// SetAlbumCover 指定封面 (必須為相簿成員)；mediaID 為空字串時恢復為預設封面
+func (s *Service) SetAlbumCover(ctx context.Context, userID, albumID, mediaID string) (*Album, error) {
+	if mediaID == "" {
+		err := s.execAlbum(ctx, `UPDATE albums SET cover_media_id = NULL, updated_at = NOW() WHERE id = $1 AND user_id = $2`, albumID, userID)
+		if err != nil {
+			return nil, err
+		}
+		return s.GetAlbum(ctx, userID, albumID)
+	}
+
+	if _, err := s.requireAlbumRole(ctx, userID, albumID, AlbumRoleOwner); err != nil {
+		return nil, err
+	}
+	query := `
+		UPDATE albums SET cover_media_id = $3, updated_at = NOW()
+		WHERE id = $1 AND user_id = $2
+		  AND EXISTS (SELECT 1 FROM album_media WHERE album_id = $1 AND media_id = $3)
+	`
+	if err := s.execAlbum(ctx, query, albumID, userID, mediaID); err != nil {
+		if errors.Is(err, ErrAlbumNotFound) {
+			return nil, ErrMediaNotInAlbum
+		}
+		return nil, err
+	}
+	return s.GetAlbum(ctx, userID, albumID)
+}
+
+// ListAlbumMedia 取得相簿內容 (依手動排序，分頁方式與 List 相同)
+// 垃圾桶中的媒體不顯示，還原後會回到原本位置
+func (s *Service) ListAlbumMedia(ctx context.Context, userID, albumID string, limit, offset int) ([]*Media, error) {
+	if _, err := s.requireAlbumRole(ctx, userID, albumID); err != nil {
+		return nil, err
+	}
+
+	query := `
+		SELECT ` + mediaColumns + `
+		FROM album_media am
+		JOIN media m ON m.id = am.media_id
+		WHERE am.album_id = $1 AND m.deleted_at IS NULL
+		ORDER BY am.position, am.added_at
+		LIMIT $2 OFFSET $3
+	`
+	rows, err := s.DB.QueryContext(ctx, query, albumID, limit, offset)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query album media: %w", err)
+	}
+	defer rows.Close()
+
+	return collectMedia(rows)
+}
+
+// requireAlbumRole 取得使用者在相簿中的角色；不是擁有者或成員時回傳 ErrAlbumNotFound，
+// 角色不在 allowed 中時回傳 ErrAlbumForbidden (allowed 為空代表任何角色皆可)
+func (s *Service) requireAlbumRole(ctx context.Context, userID, albumID string, allowed ...string) (string, error) {
+	query := `
+		SELECT ` + albumRoleColumn("$2") + `
+		FROM albums a
+		` + albumMemberJoin + `$2
+		WHERE a.id = $1 AND ` + albumAccessible("$2")
+	var role string
+	err := s.DB.QueryRowContext(ctx, query, albumID, userID).Scan(&role)
+	if err == sql.ErrNoRows {
+		return "", ErrAlbumNotFound
+	}
+	if err != nil {
+		return "", fmt.Errorf("failed to query album: %w", err)
+	}
+	if len(allowed) > 0 && !slices.Contains(allowed, role) {
+		return "", ErrAlbumForbidden
+	}
+	return role, nil
+}
+
+// ensureAlbum 確認相簿存在且屬於該使用者
+func (s *Service) ensureAlbum(ctx context.Context, userID, albumID string) error {
+	var id string
+	err := s.DB.QueryRowContext(ctx, `SELECT id FROM albums WHERE id = $1 AND user_id = $2`, albumID, userID).Scan(&id)
+	if err == sql.ErrNoRows {
+		return ErrAlbumNotFound
+	}
+	if err != nil {
+		return fmt.Errorf("failed to query album: %w", err)
+	}
+	return nil
+}
+
+// touchAlbum 更新相簿的 updated_at (失敗不影響主要操作)
+func (s *Service) touchAlbum(ctx context.Context, albumID string) {
+	if _, err := s.DB.ExecContext(ctx, `UPDATE albums SET updated_at = NOW() WHERE id = $1`, albumID); err != nil {
+		fmt.Printf("failed to touch album %s: %v\n", albumID, err)
+	}
+}
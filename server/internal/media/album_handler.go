@@ -0,0 +1,262 @@
+package media
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+)
+
+type albumRequest struct {
+	Title       *string `json:"title"`
+	Description *string `json:"description"`
+}
+
+type mediaIDsRequest struct {
+	MediaIDs []string `json:"media_ids"`
+}
+
+type albumCoverRequest struct {
+	MediaID string `json:"media_id"` // 空字串代表恢復預設封面
+}
+
+// respondAlbumError 將相簿錯誤轉換為 HTTP 狀態碼
+func respondAlbumError(c *gin.Context, err error) {
+	switch {
+	case errors.Is(err, ErrAlbumNotFound), errors.Is(err, ErrMediaNotInAlbum), errors.Is(err, ErrMemberNotFound):
+		c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+	case errors.Is(err, ErrAlbumForbidden):
+		c.JSON(http.StatusForbidden, gin.H{"error": err.Error()})
+	default:
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+	}
+}
+
+// albumParam 取得並驗證路徑中的相簿 ID
+func albumParam(c *gin.Context) (string, bool) {
+	albumID := c.Param("id")
+	if !uuidPattern.MatchString(albumID) {
+		c.JSON(http.StatusNotFound, gin.H{"error": ErrAlbumNotFound.Error()})
+		return "", false
+	}
+	return albumID, true
+}
+
+// bindMediaIDs 解析並驗證 {"media_ids": [...]}
+func bindMediaIDs(c *gin.Context) ([]string, bool) {
+	var req mediaIDsRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid request body"})
+		return nil, false
+	}
+	if err := validateIDs(req.MediaIDs); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return nil, false
+	}
+	return req.MediaIDs, true
+}
+
+// ListAlbumsHandler 取得相簿列表 (GET /albums)
+func (h *Handler) ListAlbumsHandler(c *gin.Context) {
+	userID := c.MustGet("userID").(string)
+
+	albums, err := h.Service.ListAlbums(c.Request.Context(), userID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	jsonWithETag(c, http.StatusOK, albums)
+}
+
+// CreateAlbumHandler 建立相簿 (POST /albums)
+func (h *Handler) CreateAlbumHandler(c *gin.Context) {
+	userID := c.MustGet("userID").(string)
+
+	var req albumRequest
+	if err := c.ShouldBindJSON(&req); err != nil || req.Title == nil || *req.Title == "" || len(*req.Title) > 255 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "title is required (max 255 characters)"})
+		return
+	}
+	description := ""
+	if req.Description != nil {
+		description = *req.Description
+	}
+
+	album, err := h.Service.CreateAlbum(c.Request.Context(), userID, *req.Title, description)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusCreated, album)
+}
+
+// GetAlbumHandler 取得單一相簿 (GET /albums/:id)
+func (h *Handler) GetAlbumHandler(c *gin.Context) {
+	userID := c.MustGet("userID").(string)
+	albumID, ok := albumParam(c)
+	if !ok {
+		return
+	}
+
+	album, err := h.Service.GetAlbum(c.Request.Context(), userID, albumID)
+	if err != nil {
+		respondAlbumError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, album)
+}
+
+// UpdateAlbumHandler 修改相簿標題與描述 (PATCH /albums/:id)
+func (h *Handler) UpdateAlbumHandler(c *gin.Context) {
+	userID := c.MustGet("userID").(string)
+	albumID, ok := albumParam(c)
+	if !ok {
+		return
+	}
+
+	var req albumRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid request body"})
+		return
+	}
+	if req.Title != nil && (*req.Title == "" || len(*req.Title) > 255) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "title must be 1-255 characters"})
+		return
+	}
+
+	album, err := h.Service.UpdateAlbum(c.Request.Context(), userID, albumID, req.Title, req.Description)
+	if err != nil {
+		respondAlbumError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, album)
+}
+
+// DeleteAlbumHandler 刪除相簿，媒體本身保留 (DELETE /albums/:id)
+func (h *Handler) DeleteAlbumHandler(c *gin.Context) {
+	userID := c.MustGet("userID").(string)
+	albumID, ok := albumParam(c)
+	if !ok {
+		return
+	}
+
+	if err := h.Service.DeleteAlbum(c.Request.Context(), userID, albumID); err != nil {
+		respondAlbumError(c, err)
+		return
+	}
+
+	c.Status(http.StatusNoContent)
+}
+
+// ListAlbumMediaHandler 取得相簿內容 (GET /albums/:id/media?page=1&limit=20)
+func (h *Handler) ListAlbumMediaHandler(c *gin.Context) {
+	userID := c.MustGet("userID").(string)
+	albumID, ok := albumParam(c)
+	if !ok {
+		return
+	}
+	limit, offset := parsePagination(c)
+
+	list, err := h.Service.ListAlbumMedia(c.Request.Context(), userID, albumID, limit, offset)
+	if err != nil {
+		respondAlbumError(c, err)
+		return
+	}
+
+	h.attachURLs(userID, list...)
+	jsonWithETag(c, http.StatusOK, list)
+}
+
+// AddAlbumMediaHandler 批次加入媒體 (POST /albums/:id/media)
+func (h *Handler) AddAlbumMediaHandler(c *gin.Context) {
+	userID := c.MustGet("userID").(string)
+	albumID, ok := albumParam(c)
+	if !ok {
+		return
+	}
+	mediaIDs, ok := bindMediaIDs(c)
+	if !ok {
+		return
+	}
+
+	added, err := h.Service.AddToAlbum(c.Request.Context(), userID, albumID, mediaIDs)
+	if err != nil {
+		respondAlbumError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"added": added})
+}
+
+// RemoveAlbumMediaHandler 批次移除媒體 (DELETE /albums/:id/media)
+func (h *Handler) RemoveAlbumMediaHandler(c *gin.Context) {
+	userID := c.MustGet("userID").(string)
+	albumID, ok := albumParam(c)
+	if !ok {
+		return
+	}
+	mediaIDs, ok := bindMediaIDs(c)
+	if !ok {
+		return
+	}
+
+	removed, err := h.Service.RemoveFromAlbum(c.Request.Context(), userID, albumID, mediaIDs)
+	if err != nil {
+		respondAlbumError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"removed": removed})
+}
+
+// ReorderAlbumHandler 手動排序 (PUT /albums/:id/order)
+// media_ids 依序排在最前面，未列出的項目維持原本順序
+func (h *Handler) ReorderAlbumHandler(c *gin.Context) {
+	userID := c.MustGet("userID").(string)
+	albumID, ok := albumParam(c)
+	if !ok {
+		return
+	}
+	mediaIDs, ok := bindMediaIDs(c)
+	if !ok {
+		return
+	}
+
+	if err := h.Service.ReorderAlbum(c.Request.Context(), userID, albumID, mediaIDs); err != nil {
+		respondAlbumError(c, err)
+		return
+	}
+
+	c.Status(http.StatusNoContent)
+}
+
+// SetAlbumCoverHandler 指定相簿封面 (PUT /albums/:id/cover)
+func (h *Handler) SetAlbumCoverHandler(c *gin.Context) {
+	userID := c.MustGet("userID").(string)
+	albumID, ok := albumParam(c)
+	if !ok {
+		return
+	}
+
+	var req albumCoverRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid request body"})
+		return
+	}
+	if req.MediaID != "" && !uuidPattern.MatchString(req.MediaID) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid media_id"})
+		return
+	}
+
+	album, err := h.Service.SetAlbumCover(c.Request.Context(), userID, albumID, req.MediaID)
+	if err != nil {
+		respondAlbumError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, album)
+}
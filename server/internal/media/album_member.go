@@ -0,0 +1,132 @@
+package media
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+)
+
+var (
+	ErrInviteeNotFound = errors.New("no user with this email")
+	ErrInviteSelf      = errors.New("cannot invite yourself")
+	ErrMemberNotFound  = errors.New("album member not found")
+)
+
+// ValidAlbumMemberRole 可邀請的角色 (擁有者不可指定)
+func ValidAlbumMemberRole(role string) bool {
+	return role == AlbumRoleViewer || role == AlbumRoleContributor
+}
+
+// ListAlbumMembers 列出相簿擁有者與成員 (擁有者在前，其餘依加入時間)；任何成員皆可查看
+func (s *Service) ListAlbumMembers(ctx context.Context, userID, albumID string) ([]*AlbumMember, error) {
+	if _, err := s.requireAlbumRole(ctx, userID, albumID); err != nil {
+		return nil, err
+	}
+
+	query := `
+		SELECT u.id, u.email, r.role, r.created_at
+		FROM (
+			SELECT user_id, '` + AlbumRoleOwner + `' AS role, created_at, 0 AS rank FROM albums WHERE id = $1
+			UNION ALL
+			SELECT user_id, role, created_at, 1 FROM album_members WHERE album_id = $1
+		) r
+		JOIN users u ON u.id = r.user_id
+		ORDER BY r.rank, r.created_at, u.id
+	`
+	rows, err := s.DB.QueryContext(ctx, query, albumID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query album members: %w", err)
+	}
+	defer rows.Close()
+
+	members := []*AlbumMember{}
+	for rows.Next() {
+		m := &AlbumMember{}
+		if err := rows.Scan(&m.UserID, &m.Email, &m.Role, &m.CreatedAt); err != nil {
+			return nil, fmt.Errorf("failed to scan album member: %w", err)
+		}
+		members = append(members, m)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to iterate album members: %w", err)
+	}
+	return members, nil
+}
+
+// InviteAlbumMember 以既有帳號的 Email 邀請使用者 (只有擁有者可操作)；已是成員時更新角色
+func (s *Service) InviteAlbumMember(ctx context.Context, ownerID, albumID, email, role string) (*AlbumMember, error) {
+	if err := s.ensureAlbum(ctx, ownerID, albumID); err != nil {
+		return nil, err
+	}
+
+	m := &AlbumMember{Role: role}
+	var err error
+	if m.UserID, m.Email, err = s.findInvitee(ctx, ownerID, email); err != nil {
+		return nil, err
+	}
+
+	err = s.DB.QueryRowContext(ctx, `
+		INSERT INTO album_members (album_id, user_id, role) VALUES ($1, $2, $3)
+		ON CONFLICT (album_id, user_id) DO UPDATE SET role = EXCLUDED.role
+		RETURNING created_at
+	`, albumID, m.UserID, role).Scan(&m.CreatedAt)
+	if err != nil {
+		return nil, fmt.Errorf("failed to add album member: %w", err)
+	}
+	s.touchAlbum(ctx, albumID)
+	return m, nil
+}
+
+// findInvitee 以 Email (不分大小寫) 查詢要邀請的既有帳號，不可為邀請者本人
+func (s *Service) findInvitee(ctx context.Context, inviterID, email string) (id, foundEmail string, err error) {
+	err = s.DB.QueryRowContext(ctx, `
+		SELECT id, email FROM users WHERE LOWER(email) = LOWER($1) ORDER BY created_at LIMIT 1
+	`, email).Scan(&id, &foundEmail)
+	if err == sql.ErrNoRows {
+		return "", "", ErrInviteeNotFound
+	}
+	if err != nil {
+		return "", "", fmt.Errorf("failed to query user: %w", err)
+	}
+	if id == inviterID {
+		return "", "", ErrInviteSelf
+	}
+	return id, foundEmail, nil
+}
+
+// RemoveAlbumMember 移除成員：擁有者可移除任何成員，成員可移除自己 (退出相簿)
+// 該成員加入的自己的媒體一併從相簿移除，離開後不再對其他成員公開
+func (s *Service) RemoveAlbumMember(ctx context.Context, userID, albumID, memberID string) error {
+	role, err := s.requireAlbumRole(ctx, userID, albumID)
+	if err != nil {
+		return err
+	}
+	if role != AlbumRoleOwner && userID != memberID {
+		return ErrAlbumForbidden
+	}
+
+	tx, err := s.DB.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	res, err := tx.ExecContext(ctx, `DELETE FROM album_members WHERE album_id = $1 AND user_id = $2`, albumID, memberID)
+	if err != nil {
+		return fmt.Errorf("failed to remove album member: %w", err)
+	}
+	if n, _ := res.RowsAffected(); n == 0 {
+		return ErrMemberNotFound
+	}
+	_, err = removeAlbumMedia(ctx, tx, `album_id = $1 AND media_id IN (SELECT id FROM media WHERE user_id = $2)`, albumID, memberID)
+	if err != nil {
+		return err
+	}
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("failed to commit: %w", err)
+	}
+
+	s.touchAlbum(ctx, albumID)
+	return nil
+}
@@ -0,0 +1,93 @@
+package media
+
+import (
+	"errors"
+	"net/http"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+)
+
+type albumMemberRequest struct {
+	Email string `json:"email"`
+	Role  string `json:"role"` // viewer 或 contributor
+}
+
+// ListAlbumMembersHandler 列出相簿擁有者與成員 (GET /albums/:id/members)
+func (h *Handler) ListAlbumMembersHandler(c *gin.Context) {
+	userID := c.MustGet("userID").(string)
+	albumID, ok := albumParam(c)
+	if !ok {
+		return
+	}
+
+	members, err := h.Service.ListAlbumMembers(c.Request.Context(), userID, albumID)
+	if err != nil {
+		respondAlbumError(c, err)
+		return
+	}
+
+	jsonWithETag(c, http.StatusOK, members)
+}
+
+// InviteAlbumMemberHandler 邀請使用者或變更成員角色 (POST /albums/:id/members)
+// body: {"email": "...", "role": "viewer" | "contributor"}；只有擁有者可操作
+func (h *Handler) InviteAlbumMemberHandler(c *gin.Context) {
+	userID := c.MustGet("userID").(string)
+	albumID, ok := albumParam(c)
+	if !ok {
+		return
+	}
+
+	var req albumMemberRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid request body"})
+		return
+	}
+	req.Email = strings.TrimSpace(req.Email)
+	if req.Email == "" || len(req.Email) > 255 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "email is required"})
+		return
+	}
+	if !ValidAlbumMemberRole(req.Role) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "role must be viewer or contributor"})
+		return
+	}
+
+	member, err := h.Service.InviteAlbumMember(c.Request.Context(), userID, albumID, req.Email, req.Role)
+	if err != nil {
+		switch {
+		case errors.Is(err, ErrInviteeNotFound):
+			c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+		case errors.Is(err, ErrInviteSelf):
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		default:
+			respondAlbumError(c, err)
+		}
+		return
+	}
+
+	c.JSON(http.StatusCreated, member)
+}
+
+// RemoveAlbumMemberHandler 移除成員或退出相簿 (DELETE /albums/:id/members/:user_id)
+// 被移除成員加入的自己的媒體會一併從相簿移除
+func (h *Handler) RemoveAlbumMemberHandler(c *gin.Context) {
+	userID := c.MustGet("userID").(string)
+	albumID, ok := albumParam(c)
+	if !ok {
+		return
+	}
+	memberID := strings.ToLower(c.Param("user_id"))
+	if !uuidPattern.MatchString(memberID) {
+		c.JSON(http.StatusNotFound, gin.H{"error": ErrMemberNotFound.Error()})
+		return
+	}
+
+	if err := h.Service.RemoveAlbumMember(c.Request.Context(), userID, albumID, memberID); err != nil {
+		respondAlbumError(c, err)
+		return
+	}
+
+	c.Status(http.StatusNoContent)
+}
@@ -0,0 +1,70 @@
+package media
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/DATA-DOG/go-sqlmock"
+)
+
+const (
+	memberAlbumID = "44444444-4444-4444-4444-444444444444"
+	memberOtherID = "55555555-5555-5555-5555-555555555555"
+)
+
+func TestRemoveFromAlbumContributorOnlyOwnMedia(t *testing.T) {
+	db, mock := newMockDB(t)
+	s := &Service{DB: db}
+	ids := []string{batchActiveID, batchTrashedID}
+
+	mock.ExpectQuery(`SELECT CASE WHEN a.user_id = \$2`).
+		WithArgs(memberAlbumID, "user-2").
+		WillReturnRows(sqlmock.NewRows([]string{"role"}).AddRow(AlbumRoleContributor))
+	mock.ExpectBegin()
+	mock.ExpectQuery(`DELETE FROM album_media WHERE album_id = \$1 AND media_id = ANY\(\$2::uuid\[\]\) AND media_id IN \(SELECT id FROM media WHERE user_id = \$3\)`).
+		WithArgs(memberAlbumID, ids, "user-2").
+		WillReturnRows(sqlmock.NewRows([]string{"count"}).AddRow(int64(1)))
+	mock.ExpectCommit()
+	mock.ExpectExec(`UPDATE albums SET updated_at = NOW\(\)`).WillReturnResult(sqlmock.NewResult(0, 1))
+
+	n, err := s.RemoveFromAlbum(context.Background(), "user-2", memberAlbumID, ids)
+	if err != nil {
+		t.Fatalf("RemoveFromAlbum failed: %v", err)
+	}
+	if n != 1 {
+		t.Errorf("expected 1 removed, got %d", n)
+	}
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("unmet expectations: %v", err)
+	}
+}
+
+func TestAlbumViewerCannotAddMedia(t *testing.T) {
+	db, mock := newMockDB(t)
+	s := &Service{DB: db}
+
+	mock.ExpectQuery(`SELECT CASE WHEN a.user_id = \$2`).
+		WillReturnRows(sqlmock.NewRows([]string{"role"}).AddRow(AlbumRoleViewer))
+
+	_, err := s.AddToAlbum(context.Background(), "user-2", memberAlbumID, []string{batchActiveID})
+	if !errors.Is(err, ErrAlbumForbidden) {
+		t.Errorf("expected ErrAlbumForbidden, got %v", err)
+	}
+}
+
+func TestRemoveAlbumMemberRequiresOwnerOrSelf(t *testing.T) {
+	db, mock := newMockDB(t)
+	s := &Service{DB: db}
+
+	mock.ExpectQuery(`SELECT CASE WHEN a.user_id = \$2`).
+		WillReturnRows(sqlmock.NewRows([]string{"role"}).AddRow(AlbumRoleContributor))
+
+	err := s.RemoveAlbumMember(context.Background(), "user-2", memberAlbumID, memberOtherID)
+	if !errors.Is(err, ErrAlbumForbidden) {
+		t.Errorf("expected ErrAlbumForbidden, got %v", err)
+	}
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("unmet expectations: %v", err)
+	}
+}
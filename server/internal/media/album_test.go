@@ -0,0 +1,142 @@
+package media
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+)
+
+var albumTestColumns = []string{"id", "user_id", "title", "description", "created_at", "updated_at", "media_count", "cover_media_id", "role"}
+
+func expectAlbumRole(mock sqlmock.Sqlmock, userID, role string) {
+	mock.ExpectQuery(`SELECT CASE WHEN a.user_id = \$2`).
+		WithArgs(memberAlbumID, userID).
+		WillReturnRows(sqlmock.NewRows([]string{"role"}).AddRow(role))
+}
+
+// 新項目依傳入順序接在目前最大 position 之後，垃圾桶中的媒體不加入
+func TestAddToAlbumAppendsAfterLastPosition(t *testing.T) {
+	db, mock := newMockDB(t)
+	s := &Service{DB: db}
+	ids := []string{batchActiveID, batchTrashedID}
+
+	expectAlbumRole(mock, "user-1", AlbumRoleOwner)
+	mock.ExpectExec(`\(SELECT COALESCE\(MAX\(position\), 0\) FROM album_media WHERE album_id = \$1\) \+ v.ord\s+`+
+		`FROM unnest\(\$3::uuid\[\]\) WITH ORDINALITY AS v\(media_id, ord\)\s+`+
+		`JOIN media m ON m.id = v.media_id AND m.user_id = \$2 AND m.deleted_at IS NULL\s+`+
+		`ON CONFLICT \(album_id, media_id\) DO NOTHING`).
+		WithArgs(memberAlbumID, "user-1", ids).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectExec(`UPDATE albums SET updated_at = NOW\(\)`).
+		WithArgs(memberAlbumID).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+
+	n, err := s.AddToAlbum(context.Background(), "user-1", memberAlbumID, ids)
+	if err != nil {
+		t.Fatalf("AddToAlbum failed: %v", err)
+	}
+	if n != 1 {
+		t.Errorf("expected 1 added, got %d", n)
+	}
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("unmet expectations: %v", err)
+	}
+}
+
+// 列出的項目依序排在最前面，其餘以原本的 position 接在後面
+func TestReorderAlbumPutsUnlistedLast(t *testing.T) {
+	db, mock := newMockDB(t)
+	s := &Service{DB: db}
+	ids := []string{batchTrashedID, batchActiveID}
+
+	expectAlbumRole(mock, "user-1", AlbumRoleOwner)
+	mock.ExpectExec(`UPDATE album_media am SET position = o.new_position\s+FROM \(\s+`+
+		`SELECT cur.media_id,\s+ROW_NUMBER\(\) OVER \(ORDER BY v.ord NULLS LAST, cur.position, cur.added_at\) AS new_position\s+`+
+		`FROM album_media cur\s+`+
+		`LEFT JOIN unnest\(\$2::uuid\[\]\) WITH ORDINALITY AS v\(media_id, ord\) ON v.media_id = cur.media_id\s+`+
+		`WHERE cur.album_id = \$1`).
+		WithArgs(memberAlbumID, ids).
+		WillReturnResult(sqlmock.NewResult(0, 3))
+	mock.ExpectExec(`UPDATE albums SET updated_at = NOW\(\)`).
+		WithArgs(memberAlbumID).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+
+	if err := s.ReorderAlbum(context.Background(), "user-1", memberAlbumID, ids); err != nil {
+		t.Fatalf("ReorderAlbum failed: %v", err)
+	}
+
+	// 只有擁有者可以排序
+	expectAlbumRole(mock, "user-2", AlbumRoleContributor)
+	if err := s.ReorderAlbum(context.Background(), "user-2", memberAlbumID, ids); !errors.Is(err, ErrAlbumForbidden) {
+		t.Errorf("expected ErrAlbumForbidden, got %v", err)
+	}
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("unmet expectations: %v", err)
+	}
+}
+
+func TestSetAlbumCoverRequiresMembership(t *testing.T) {
+	db, mock := newMockDB(t)
+	s := &Service{DB: db}
+	now := time.Now()
+
+	expectAlbumRole(mock, "user-1", AlbumRoleOwner)
+	mock.ExpectExec(`UPDATE albums SET cover_media_id = \$3.+EXISTS \(SELECT 1 FROM album_media WHERE album_id = \$1 AND media_id = \$3\)`).
+		WithArgs(memberAlbumID, "user-1", batchMissingID).
+		WillReturnResult(sqlmock.NewResult(0, 0))
+	if _, err := s.SetAlbumCover(context.Background(), "user-1", memberAlbumID, batchMissingID); !errors.Is(err, ErrMediaNotInAlbum) {
+		t.Errorf("expected ErrMediaNotInAlbum, got %v", err)
+	}
+
+	expectAlbumRole(mock, "user-1", AlbumRoleOwner)
+	mock.ExpectExec(`UPDATE albums SET cover_media_id = \$3`).
+		WithArgs(memberAlbumID, "user-1", batchActiveID).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectQuery(`FROM albums a`).
+		WithArgs(memberAlbumID, "user-1").
+		WillReturnRows(sqlmock.NewRows(albumTestColumns).
+			AddRow(memberAlbumID, "user-1", "Summer", "", now, now, 2, batchActiveID, AlbumRoleOwner))
+	album, err := s.SetAlbumCover(context.Background(), "user-1", memberAlbumID, batchActiveID)
+	if err != nil {
+		t.Fatalf("SetAlbumCover failed: %v", err)
+	}
+	if album.CoverMediaID == nil || *album.CoverMediaID != batchActiveID {
+		t.Errorf("unexpected cover: %v", album.CoverMediaID)
+	}
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("unmet expectations: %v", err)
+	}
+}
+
+// 移除的項目是封面時，同一語句清除封面設定
+func TestRemoveFromAlbumResetsCover(t *testing.T) {
+	db, mock := newMockDB(t)
+	s := &Service{DB: db}
+	ids := []string{batchActiveID}
+
+	expectAlbumRole(mock, "user-1", AlbumRoleOwner)
+	mock.ExpectBegin()
+	mock.ExpectQuery(`DELETE FROM album_media WHERE album_id = \$1 AND media_id = ANY\(\$2::uuid\[\]\) RETURNING media_id\s+`+
+		`\), reset AS \(\s+UPDATE albums SET cover_media_id = NULL, updated_at = NOW\(\)\s+`+
+		`WHERE id = \$1 AND cover_media_id IN \(SELECT media_id FROM removed\)`).
+		WithArgs(memberAlbumID, ids).
+		WillReturnRows(sqlmock.NewRows([]string{"count"}).AddRow(int64(1)))
+	mock.ExpectCommit()
+	mock.ExpectExec(`UPDATE albums SET updated_at = NOW\(\)`).
+		WithArgs(memberAlbumID).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+
+	n, err := s.RemoveFromAlbum(context.Background(), "user-1", memberAlbumID, ids)
+	if err != nil {
+		t.Fatalf("RemoveFromAlbum failed: %v", err)
+	}
+	if n != 1 {
+		t.Errorf("expected 1 removed, got %d", n)
+	}
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("unmet expectations: %v", err)
+	}
+}
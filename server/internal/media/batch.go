@@ -0,0 +1,200 @@
+package media
+
+import (
+	"context"
+	"fmt"
+	"strings"
+)
+
+// 批次操作種類
+const (
+	BatchTrash      = "trash"
+	BatchRestore    = "restore"
+	BatchDelete     = "delete" // 永久刪除
+	BatchFavorite   = "favorite"
+	BatchUnfavorite = "unfavorite"
+	BatchArchive    = "archive"
+	BatchUnarchive  = "unarchive"
+	BatchAddToAlbum = "add_to_album"
+	BatchTag        = "tag"
+)
+
+// 單一項目的處理結果
+const (
+	BatchStatusOK       = "ok"
+	BatchStatusNotFound = "not_found"
+	BatchStatusConflict = "conflict" // 項目存在但目前狀態不允許此操作
+)
+
+// BatchRequest 批次操作請求
+type BatchRequest struct {
+	Action   string   `json:"action"`
+	MediaIDs []string `json:"media_ids"`
+	AlbumID  string   `json:"album_id,omitempty"` // add_to_album 使用
+	Tags     []string `json:"tags,omitempty"`     // tag 使用
+}
+
+// BatchItemResult 單一媒體的處理結果
+type BatchItemResult struct {
+	ID     string `json:"id"`
+	Status string `json:"status"`
+	Error  string `json:"error,omitempty"`
+}
+
+// BatchResult 批次操作結果 (Results 依請求順序，重複的 ID 只列一次)
+type BatchResult struct {
+	Action    string            `json:"action"`
+	Results   []BatchItemResult `json:"results"`
+	Succeeded int               `json:"succeeded"`
+	Failed    int               `json:"failed"`
+}
+
+// Validate 檢查操作種類與必要參數
+func (r *BatchRequest) Validate() error {
+	switch r.Action {
+	case BatchTrash, BatchRestore, BatchDelete,
+		BatchFavorite, BatchUnfavorite, BatchArchive, BatchUnarchive:
+	case BatchAddToAlbum:
+		if !uuidPattern.MatchString(r.AlbumID) {
+			return fmt.Errorf("album_id is required for %s", r.Action)
+		}
+	case BatchTag:
+		if len(NormalizeTags(r.Tags)) == 0 {
+			return fmt.Errorf("tags must contain at least one valid name")
+		}
+	default:
+		return fmt.Errorf("unknown action: %q", r.Action)
+	}
+	return validateIDs(r.MediaIDs)
+}
+
+// batchState 批次操作前鎖定的媒體狀態
+type batchState struct {
+	trashed     bool
+	storagePath string
+}
+
+// Batch 在單一交易中對多個媒體執行同一操作
+// 先以 FOR UPDATE 鎖定並檢查每個項目的狀態，再以一次集合式 SQL 套用至允許的項目；
+// 不存在或不屬於該使用者的項目回報 not_found，狀態不符 (例如垃圾桶中的項目加入相簿) 回報 conflict
+func (s *Service) Batch(ctx context.Context, userID string, req BatchRequest) (*BatchResult, error) {
+	if err := req.Validate(); err != nil {
+		return nil, err
+	}
+	if req.Action == BatchAddToAlbum {
+		if _, err := s.requireAlbumRole(ctx, userID, req.AlbumID, AlbumRoleOwner, AlbumRoleContributor); err != nil {
+			return nil, err
+		}
+	}
+
+	tx, err := s.DB.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	rows, err := tx.QueryContext(ctx, `
+		SELECT id, deleted_at IS NOT NULL, storage_path
+		FROM media
+		WHERE user_id = $1 AND id = ANY($2::uuid[])
+		ORDER BY id
+		FOR UPDATE
+	`, userID, req.MediaIDs)
+	if err != nil {
+		return nil, fmt.Errorf("failed to lock media: %w", err)
+	}
+	states := make(map[string]batchState, len(req.MediaIDs))
+	for rows.Next() {
+		var id string
+		var st batchState
+		if err := rows.Scan(&id, &st.trashed, &st.storagePath); err != nil {
+			rows.Close()
+			return nil, fmt.Errorf("failed to scan media: %w", err)
+		}
+		states[id] = st
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to iterate media: %w", err)
+	}
+
+	result := &BatchResult{Action: req.Action, Results: make([]BatchItemResult, 0, len(req.MediaIDs))}
+	var allowed []string
+	seen := make(map[string]bool, len(req.MediaIDs))
+	for _, id := range req.MediaIDs {
+		id = strings.ToLower(id) // PostgreSQL 以小寫輸出 uuid
+		if seen[id] {
+			continue
+		}
+		seen[id] = true
+
+		item := BatchItemResult{ID: id, Status: BatchStatusOK}
+		st, ok := states[id]
+		switch {
+		case !ok:
+			item.Status, item.Error = BatchStatusNotFound, ErrMediaNotFound.Error()
+		case req.Action == BatchDelete:
+		case req.Action == BatchRestore:
+			if !st.trashed {
+				item.Status, item.Error = BatchStatusConflict, "media is not in trash"
+			}
+		case st.trashed:
+			item.Status, item.Error = BatchStatusConflict, "media is in trash"
+		}
+
+		if item.Status == BatchStatusOK {
+			allowed = append(allowed, id)
+			result.Succeeded++
+		} else {
+			result.Failed++
+		}
+		result.Results = append(result.Results, item)
+	}
+
+	if len(allowed) > 0 {
+		if err := s.applyBatch(ctx, tx, userID, req, allowed); err != nil {
+			return nil, err
+		}
+	}
+	if err := tx.Commit(); err != nil {
+		return nil, fmt.Errorf("failed to commit: %w", err)
+	}
+
+	// 交易成功後才處理檔案與相簿時間戳記
+	switch req.Action {
+	case BatchDelete:
+		for _, id := range allowed {
+			s.removeMediaFiles(id, states[id].storagePath)
+		}
+	case BatchAddToAlbum:
+		if len(allowed) > 0 {
+			s.touchAlbum(ctx, req.AlbumID)
+		}
+	}
+	return result, nil
+}
+
+// applyBatch 對已確認狀態的項目執行操作
+func (s *Service) applyBatch(ctx context.Context, tx execer, userID string, req BatchRequest, ids []string) error {
+	var err error
+	switch req.Action {
+	case BatchTrash:
+		_, err = tx.ExecContext(ctx, `UPDATE media SET deleted_at = NOW() WHERE user_id = $1 AND id = ANY($2::uuid[])`, userID, ids)
+	case BatchRestore:
+		_, err = tx.ExecContext(ctx, `UPDATE media SET deleted_at = NULL WHERE user_id = $1 AND id = ANY($2::uuid[])`, userID, ids)
+	case BatchDelete:
+		_, err = tx.ExecContext(ctx, `DELETE FROM media WHERE user_id = $1 AND id = ANY($2::uuid[])`, userID, ids)
+	case BatchFavorite, BatchUnfavorite:
+		_, err = setMediaFlag(ctx, tx, "is_favorite", userID, ids, req.Action == BatchFavorite)
+	case BatchArchive, BatchUnarchive:
+		_, err = setMediaFlag(ctx, tx, "is_archived", userID, ids, req.Action == BatchArchive)
+	case BatchAddToAlbum:
+		_, err = s.addToAlbum(ctx, tx, userID, req.AlbumID, ids)
+	case BatchTag:
+		_, err = s.addTags(ctx, tx, userID, ids, req.Tags, TagSourceUser)
+	}
+	if err != nil {
+		return fmt.Errorf("failed to apply %s: %w", req.Action, err)
+	}
+	return nil
+}
@@ -0,0 +1,33 @@
+package media
+
+import (
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+)
+
+// BatchHandler 對多個媒體執行同一操作 (POST /media/batch)
+// body: {"action": "trash", "media_ids": [...], "album_id": "...", "tags": [...]}
+// action: trash, restore, delete, favorite, unfavorite, archive, unarchive, add_to_album, tag
+// 回傳每個 ID 的結果 (ok / not_found / conflict)；個別項目失敗不影響其他項目，整體仍回傳 200
+func (h *Handler) BatchHandler(c *gin.Context) {
+	userID := c.MustGet("userID").(string)
+
+	var req BatchRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid request body"})
+		return
+	}
+	if err := req.Validate(); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	result, err := h.Service.Batch(c.Request.Context(), userID, req)
+	if err != nil {
+		respondAlbumError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, result)
+}
@@ -0,0 +1,94 @@
+package media
+
+import (
+	"context"
+	"testing"
+
+	"github.com/DATA-DOG/go-sqlmock"
+)
+
+const (
+	batchActiveID  = "11111111-1111-1111-1111-111111111111"
+	batchTrashedID = "22222222-2222-2222-2222-222222222222"
+	batchMissingID = "33333333-3333-3333-3333-333333333333"
+)
+
+func TestBatchRequestValidate(t *testing.T) {
+	ids := []string{batchActiveID}
+	invalid := []BatchRequest{
+		{Action: "explode", MediaIDs: ids},
+		{Action: BatchTrash},
+		{Action: BatchAddToAlbum, MediaIDs: ids},
+		{Action: BatchTag, MediaIDs: ids, Tags: []string{"  "}},
+	}
+	for _, req := range invalid {
+		if err := req.Validate(); err == nil {
+			t.Errorf("expected error for %+v", req)
+		}
+	}
+	valid := BatchRequest{Action: BatchTag, MediaIDs: ids, Tags: []string{"beach"}}
+	if err := valid.Validate(); err != nil {
+		t.Errorf("unexpected error: %v", err)
+	}
+}
+
+func TestBatchPerItemResults(t *testing.T) {
+	db, mock := newMockDB(t)
+	s := &Service{DB: db}
+
+	locked := sqlmock.NewRows([]string{"id", "trashed", "storage_path"}).
+		AddRow(batchActiveID, false, "a.jpg").
+		AddRow(batchTrashedID, true, "b.jpg")
+
+	mock.ExpectBegin()
+	mock.ExpectQuery(`SELECT id, deleted_at IS NOT NULL, storage_path`).WillReturnRows(locked)
+	mock.ExpectExec(`UPDATE media SET is_favorite = \$3`).
+		WithArgs("user-1", []string{batchActiveID}, true).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectCommit()
+
+	res, err := s.Batch(context.Background(), "user-1", BatchRequest{
+		Action:   BatchFavorite,
+		MediaIDs: []string{batchActiveID, batchTrashedID, batchMissingID, batchActiveID},
+	})
+	if err != nil {
+		t.Fatalf("Batch failed: %v", err)
+	}
+
+	want := []string{BatchStatusOK, BatchStatusConflict, BatchStatusNotFound}
+	if len(res.Results) != len(want) {
+		t.Fatalf("expected %d results, got %+v", len(want), res.Results)
+	}
+	for i, status := range want {
+		if res.Results[i].Status != status {
+			t.Errorf("result %d: expected %s, got %s", i, status, res.Results[i].Status)
+		}
+	}
+	if res.Succeeded != 1 || res.Failed != 2 {
+		t.Errorf("unexpected counts: %+v", res)
+	}
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Error(err)
+	}
+}
+
+func TestBatchRestoreSkipsActive(t *testing.T) {
+	db, mock := newMockDB(t)
+	s := &Service{DB: db}
+
+	mock.ExpectBegin()
+	mock.ExpectQuery(`FOR UPDATE`).WillReturnRows(
+		sqlmock.NewRows([]string{"id", "trashed", "storage_path"}).AddRow(batchActiveID, false, "a.jpg"))
+	mock.ExpectCommit()
+
+	res, err := s.Batch(context.Background(), "user-1", BatchRequest{Action: BatchRestore, MediaIDs: []string{batchActiveID}})
+	if err != nil {
+		t.Fatalf("Batch failed: %v", err)
+	}
+	if res.Results[0].Status != BatchStatusConflict || res.Succeeded != 0 {
+		t.Errorf("expected conflict, got %+v", res)
+	}
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Error(err)
+	}
+}
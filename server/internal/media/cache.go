@@ -0,0 +1,100 @@
+package media
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// immutableCacheControl 檔案以 file_hash 定址，內容永不改變；private 因為需要驗證身分
+const immutableCacheControl = "private, max-age=31536000, immutable"
+
+// listCacheControl 列表內容會變動，允許快取但每次都需重新驗證
+const listCacheControl = "private, no-cache"
+
+// strongETag 以基底 (檔案 Hash 或媒體 ID) 與衍生參數組成強 ETag
+func strongETag(base string, parts ...string) string {
+	if len(parts) == 0 {
+		return `"` + base + `"`
+	}
+	return `"` + base + "-" + strings.Join(parts, "-") + `"`
+}
+
+// etagMatches 以弱比較 (RFC 9110 §13.1.2) 判斷 If-None-Match 是否符合
+func etagMatches(ifNoneMatch, etag string) bool {
+	if ifNoneMatch == "" {
+		return false
+	}
+	if strings.TrimSpace(ifNoneMatch) == "*" {
+		return true
+	}
+	target := strings.TrimPrefix(etag, "W/")
+	for _, candidate := range strings.Split(ifNoneMatch, ",") {
+		if strings.TrimPrefix(strings.TrimSpace(candidate), "W/") == target {
+			return true
+		}
+	}
+	return false
+}
+
+// notModified 設定驗證標頭；條件請求符合時回應 304 並回傳 true
+// If-None-Match 優先於 If-Modified-Since
+func notModified(c *gin.Context, etag string, lastModified time.Time) bool {
+	c.Header("ETag", etag)
+	if !lastModified.IsZero() {
+		c.Header("Last-Modified", lastModified.UTC().Format(http.TimeFormat))
+	}
+
+	if inm := c.GetHeader("If-None-Match"); inm != "" {
+		if etagMatches(inm, etag) {
+			c.Status(http.StatusNotModified)
+			return true
+		}
+		return false
+	}
+
+	if ims := c.GetHeader("If-Modified-Since"); ims != "" && !lastModified.IsZero() {
+		if t, err := http.ParseTime(ims); err == nil && !lastModified.Truncate(time.Second).After(t) {
+			c.Status(http.StatusNotModified)
+			return true
+		}
+	}
+	return false
+}
+
+// serveFile 提供檔案內容 (ETag 需已設定，ServeContent 會處理 If-Range 與 Range)
+func serveFile(c *gin.Context, path string, lastModified time.Time) {
+	f, err := os.Open(path)
+	if err != nil {
+		c.Header("Cache-Control", "no-store")
+		c.JSON(http.StatusNotFound, gin.H{"error": "file not found"})
+		return
+	}
+	defer f.Close()
+
+	http.ServeContent(c.Writer, c.Request, filepath.Base(path), lastModified, f)
+}
+
+// jsonWithETag 以內容雜湊產生弱 ETag 回應 JSON；內容未變時回應 304
+func jsonWithETag(c *gin.Context, status int, obj any) {
+	body, err := json.Marshal(obj)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	sum := sha256.Sum256(body)
+	etag := `W/"` + hex.EncodeToString(sum[:16]) + `"`
+
+	c.Header("Cache-Control", listCacheControl)
+	if notModified(c, etag, time.Time{}) {
+		return
+	}
+	c.Data(status, "application/json; charset=utf-8", body)
+}
@@ -0,0 +1,64 @@
+package media
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+func TestEtagMatches(t *testing.T) {
+	tests := []struct {
+		header, etag string
+		want         bool
+	}{
+		{`"abc"`, `"abc"`, true},
+		{`W/"abc"`, `"abc"`, true},
+		{`"x", "abc"`, `W/"abc"`, true},
+		{`*`, `"abc"`, true},
+		{`"abcd"`, `"abc"`, false},
+		{``, `"abc"`, false},
+	}
+	for _, tt := range tests {
+		if got := etagMatches(tt.header, tt.etag); got != tt.want {
+			t.Errorf("etagMatches(%q, %q) = %v, want %v", tt.header, tt.etag, got, tt.want)
+		}
+	}
+}
+
+func TestNotModified(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	lastModified := time.Date(2024, 5, 1, 12, 0, 0, 500, time.UTC)
+
+	tests := []struct {
+		name    string
+		headers map[string]string
+		want    bool
+	}{
+		{"no conditions", nil, false},
+		{"etag match", map[string]string{"If-None-Match": `"hash"`}, true},
+		{"etag mismatch wins over date", map[string]string{
+			"If-None-Match":     `"other"`,
+			"If-Modified-Since": lastModified.Format(http.TimeFormat),
+		}, false},
+		{"not modified since", map[string]string{"If-Modified-Since": lastModified.Format(http.TimeFormat)}, true},
+		{"modified since", map[string]string{"If-Modified-Since": lastModified.Add(-time.Hour).Format(http.TimeFormat)}, false},
+	}
+	for _, tt := range tests {
+		w := httptest.NewRecorder()
+		c, _ := gin.CreateTestContext(w)
+		c.Request = httptest.NewRequest(http.MethodGet, "/media/1/file", nil)
+		for k, v := range tt.headers {
+			c.Request.Header.Set(k, v)
+		}
+
+		if got := notModified(c, strongETag("hash"), lastModified); got != tt.want {
+			t.Errorf("%s: notModified = %v, want %v", tt.name, got, tt.want)
+		}
+		if w.Header().Get("ETag") != `"hash"` {
+			t.Errorf("%s: expected ETag header, got %q", tt.name, w.Header().Get("ETag"))
+		}
+	}
+}
@@ -0,0 +1,196 @@
+package media
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+)
+
+// AlbumRef 相簿的簡要資訊
+type AlbumRef struct {
+	ID    string `json:"id"`
+	Title string `json:"title"`
+}
+
+// DuplicateItem 重複群組中的一筆媒體與其所屬相簿
+type DuplicateItem struct {
+	*Media
+	Albums []AlbumRef `json:"albums"`
+}
+
+// DuplicateGroup 內容完全相同 (file_hash 相同) 的一組媒體
+type DuplicateGroup struct {
+	FileHash         string           `json:"file_hash"`
+	SizeBytes        int64            `json:"size_bytes"`        // 單一檔案大小
+	ReclaimableBytes int64            `json:"reclaimable_bytes"` // 只保留一份時可釋放的空間
+	Media            []*DuplicateItem `json:"media"`             // 依上傳時間排序 (最早者在前)
+}
+
+// DuplicateResolution 重複項目處理結果
+type DuplicateResolution struct {
+	Kept    *Media   `json:"kept"`
+	Trashed []string `json:"trashed"`
+}
+
+// ListDuplicates 列出完全重複的媒體群組 (不含垃圾桶)，可釋放空間最多者優先，分頁以群組為單位
+func (s *Service) ListDuplicates(ctx context.Context, userID string, limit, offset int) ([]*DuplicateGroup, error) {
+	query := `
+		SELECT file_hash, MAX(size_bytes), COUNT(*)
+		FROM media
+		WHERE user_id = $1 AND deleted_at IS NULL
+		GROUP BY file_hash
+		HAVING COUNT(*) > 1
+		ORDER BY MAX(size_bytes) * (COUNT(*) - 1) DESC, file_hash
+		LIMIT $2 OFFSET $3
+	`
+	rows, err := s.DB.QueryContext(ctx, query, userID, limit, offset)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query duplicates: %w", err)
+	}
+	groups := []*DuplicateGroup{}
+	byHash := map[string]*DuplicateGroup{}
+	var hashes []string
+	for rows.Next() {
+		g := &DuplicateGroup{Media: []*DuplicateItem{}}
+		var count int64
+		if err := rows.Scan(&g.FileHash, &g.SizeBytes, &count); err != nil {
+			rows.Close()
+			return nil, fmt.Errorf("failed to scan duplicate group: %w", err)
+		}
+		g.ReclaimableBytes = g.SizeBytes * (count - 1)
+		groups = append(groups, g)
+		byHash[g.FileHash] = g
+		hashes = append(hashes, g.FileHash)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to iterate duplicate groups: %w", err)
+	}
+	if len(groups) == 0 {
+		return groups, nil
+	}
+
+	query = `
+		SELECT ` + mediaColumns + `,
+			(SELECT COALESCE(json_agg(json_build_object('id', a.id, 'title', a.title) ORDER BY a.title), '[]')
+			 FROM album_media am JOIN albums a ON a.id = am.album_id WHERE am.media_id = m.id) AS albums
+		FROM media m
+		WHERE m.user_id = $1 AND m.deleted_at IS NULL AND m.file_hash = ANY($2::text[])
+		ORDER BY m.uploaded_at, m.id
+	`
+	rows, err = s.DB.QueryContext(ctx, query, userID, hashes)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query duplicate media: %w", err)
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		item := &DuplicateItem{Media: &Media{}}
+		var albums []byte
+		if err := scanMedia(rows, item.Media, &albums); err != nil {
+			return nil, fmt.Errorf("failed to scan media: %w", err)
+		}
+		if err := json.Unmarshal(albums, &item.Albums); err != nil {
+			return nil, fmt.Errorf("failed to decode albums: %w", err)
+		}
+		if g, ok := byHash[item.FileHash]; ok {
+			g.Media = append(g.Media, item)
+		}
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to iterate duplicate media: %w", err)
+	}
+	return groups, nil
+}
+
+// ResolveDuplicates 保留 keepID，將相同內容的其他項目的標籤、我的最愛、說明與相簿成員關係
+// 合併至保留項目後移至垃圾桶 (可從垃圾桶還原)
+func (s *Service) ResolveDuplicates(ctx context.Context, userID, keepID string) (*DuplicateResolution, error) {
+	tx, err := s.DB.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	var fileHash string
+	err = tx.QueryRowContext(ctx, `
+		SELECT file_hash FROM media WHERE id = $1 AND user_id = $2 AND deleted_at IS NULL FOR UPDATE
+	`, keepID, userID).Scan(&fileHash)
+	if err == sql.ErrNoRows {
+		return nil, ErrMediaNotFound
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to query media: %w", err)
+	}
+
+	rows, err := tx.QueryContext(ctx, `
+		SELECT id FROM media
+		WHERE user_id = $1 AND file_hash = $2 AND id <> $3 AND deleted_at IS NULL
+		ORDER BY id
+		FOR UPDATE
+	`, userID, fileHash, keepID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query duplicates: %w", err)
+	}
+	others := []string{}
+	for rows.Next() {
+		var id string
+		if err := rows.Scan(&id); err != nil {
+			rows.Close()
+			return nil, fmt.Errorf("failed to scan duplicate: %w", err)
+		}
+		others = append(others, id)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to iterate duplicates: %w", err)
+	}
+
+	if len(others) > 0 {
+		steps := []struct {
+			name  string
+			query string
+			args  []any
+		}{
+			{"merge tags", `
+				INSERT INTO media_tags (media_id, tag_id, source)
+				SELECT DISTINCT ON (tag_id) $1::uuid, tag_id, source
+				FROM media_tags WHERE media_id = ANY($2::uuid[])
+				ORDER BY tag_id, source DESC -- 'user' 優先於 'embedded'
+				ON CONFLICT (media_id, tag_id) DO NOTHING`, []any{keepID, others}},
+			{"merge flags", `
+				UPDATE media k SET
+					is_favorite = k.is_favorite OR EXISTS (SELECT 1 FROM media o WHERE o.id = ANY($2::uuid[]) AND o.is_favorite),
+					caption = CASE WHEN k.caption <> '' THEN k.caption ELSE COALESCE(
+						(SELECT o.caption FROM media o WHERE o.id = ANY($2::uuid[]) AND o.caption <> '' ORDER BY o.uploaded_at LIMIT 1), '') END
+				WHERE k.id = $1`, []any{keepID, others}},
+			// 加在相簿最後 (與 addToAlbum 相同)，不沿用被移除項目的位置以免與其他項目重複
+			{"merge albums", `
+				INSERT INTO album_media (album_id, media_id, position)
+				SELECT d.album_id, $1::uuid,
+				       (SELECT COALESCE(MAX(position), 0) FROM album_media x WHERE x.album_id = d.album_id) + 1
+				FROM (SELECT DISTINCT album_id FROM album_media WHERE media_id = ANY($2::uuid[])) d
+				ON CONFLICT (album_id, media_id) DO NOTHING`, []any{keepID, others}},
+			{"move album covers", `
+				UPDATE albums SET cover_media_id = $1 WHERE cover_media_id = ANY($2::uuid[])`, []any{keepID, others}},
+			{"trash duplicates", `
+				UPDATE media SET deleted_at = NOW() WHERE id = ANY($1::uuid[])`, []any{others}},
+		}
+		for _, step := range steps {
+			if _, err := tx.ExecContext(ctx, step.query, step.args...); err != nil {
+				return nil, fmt.Errorf("failed to %s: %w", step.name, err)
+			}
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, fmt.Errorf("failed to commit: %w", err)
+	}
+
+	kept, err := s.GetByID(ctx, userID, keepID)
+	if err != nil {
+		return nil, err
+	}
+	return &DuplicateResolution{Kept: kept, Trashed: others}, nil
+}
@@ -0,0 +1,55 @@
+package media
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+)
+
+type resolveDuplicatesRequest struct {
+	KeepID string `json:"keep_id"`
+}
+
+// ListDuplicatesHandler 列出完全重複的媒體群組 (GET /media/duplicates?page=1&limit=20)
+func (h *Handler) ListDuplicatesHandler(c *gin.Context) {
+	userID := c.MustGet("userID").(string)
+	limit, offset := parsePagination(c)
+
+	groups, err := h.Service.ListDuplicates(c.Request.Context(), userID, limit, offset)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	for _, g := range groups {
+		for _, item := range g.Media {
+			h.attachURLs(userID, item.Media)
+		}
+	}
+	jsonWithETag(c, http.StatusOK, groups)
+}
+
+// ResolveDuplicatesHandler 保留一筆並合併其餘重複項目 (POST /media/duplicates/resolve，body: {"keep_id": "..."})
+func (h *Handler) ResolveDuplicatesHandler(c *gin.Context) {
+	userID := c.MustGet("userID").(string)
+
+	var req resolveDuplicatesRequest
+	if err := c.ShouldBindJSON(&req); err != nil || !uuidPattern.MatchString(req.KeepID) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "keep_id is required"})
+		return
+	}
+
+	result, err := h.Service.ResolveDuplicates(c.Request.Context(), userID, req.KeepID)
+	if err != nil {
+		if errors.Is(err, ErrMediaNotFound) {
+			c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	h.attachURLs(userID, result.Kept)
+	c.JSON(http.StatusOK, result)
+}
@@ -0,0 +1,109 @@
+package media
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+)
+
+func TestResolveDuplicatesMergesAndTrashes(t *testing.T) {
+	db, mock := newMockDB(t)
+	s := &Service{DB: db}
+	keep := "11111111-1111-1111-1111-111111111111"
+	other := "22222222-2222-2222-2222-222222222222"
+
+	mock.ExpectBegin()
+	mock.ExpectQuery(`SELECT file_hash FROM media`).WithArgs(keep, "user-1").
+		WillReturnRows(sqlmock.NewRows([]string{"file_hash"}).AddRow("hash-1"))
+	mock.ExpectQuery(`SELECT id FROM media`).WithArgs("user-1", "hash-1", keep).
+		WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(other))
+	mock.ExpectExec(`INSERT INTO media_tags`).WithArgs(keep, []string{other}).WillReturnResult(sqlmock.NewResult(0, 2))
+	mock.ExpectExec(`UPDATE media k SET`).WithArgs(keep, []string{other}).WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectExec(`INSERT INTO album_media .+COALESCE\(MAX\(position\), 0\)`).WithArgs(keep, []string{other}).WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectExec(`UPDATE albums SET cover_media_id`).WithArgs(keep, []string{other}).WillReturnResult(sqlmock.NewResult(0, 0))
+	mock.ExpectExec(`UPDATE media SET deleted_at = NOW\(\)`).WithArgs([]string{other}).WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectCommit()
+	mock.ExpectQuery(`SELECT .+ FROM media m`).
+		WillReturnRows(sqlmock.NewRows(append(mediaTestColumns, "storage_path")).
+			AddRow(append(mediaTestRow(keep, time.Now()), "a.jpg")...))
+
+	res, err := s.ResolveDuplicates(context.Background(), "user-1", keep)
+	if err != nil {
+		t.Fatalf("ResolveDuplicates failed: %v", err)
+	}
+	if res.Kept.ID != keep || len(res.Trashed) != 1 || res.Trashed[0] != other {
+		t.Errorf("unexpected resolution: %+v", res)
+	}
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Error(err)
+	}
+}
+
+func TestResolveDuplicatesNotFound(t *testing.T) {
+	db, mock := newMockDB(t)
+	s := &Service{DB: db}
+
+	mock.ExpectBegin()
+	mock.ExpectQuery(`SELECT file_hash FROM media`).WillReturnRows(sqlmock.NewRows([]string{"file_hash"}))
+	mock.ExpectRollback()
+
+	_, err := s.ResolveDuplicates(context.Background(), "user-1", "11111111-1111-1111-1111-111111111111")
+	if !errors.Is(err, ErrMediaNotFound) {
+		t.Errorf("expected ErrMediaNotFound, got %v", err)
+	}
+}
+
+func TestListDuplicates(t *testing.T) {
+	db, mock := newMockDB(t)
+	s := &Service{DB: db}
+	now := time.Now()
+
+	mock.ExpectQuery(`SELECT file_hash, MAX\(size_bytes\), COUNT\(\*\)`).WithArgs("user-1", 20, 0).
+		WillReturnRows(sqlmock.NewRows([]string{"file_hash", "size", "count"}).
+			AddRow("hash-big", 3000, 2).
+			AddRow("hash-small", 100, 3))
+
+	rows := sqlmock.NewRows(append(mediaTestColumns, "albums"))
+	for _, r := range []struct{ id, hash, albums string }{
+		{"m1", "hash-big", `[{"id": "a1", "title": "Trip"}]`},
+		{"m2", "hash-small", `[]`},
+		{"m3", "hash-big", `[]`},
+		{"m4", "hash-small", `[]`},
+		{"m5", "hash-small", `[]`},
+	} {
+		row := mediaTestRow(r.id, now)
+		row[3] = r.hash // file_hash
+		rows.AddRow(append(row, []byte(r.albums))...)
+	}
+	mock.ExpectQuery(`SELECT .+ FROM media m\s+WHERE m.user_id = \$1 AND m.deleted_at IS NULL AND m.file_hash = ANY`).
+		WithArgs("user-1", []string{"hash-big", "hash-small"}).
+		WillReturnRows(rows)
+
+	groups, err := s.ListDuplicates(context.Background(), "user-1", 20, 0)
+	if err != nil {
+		t.Fatalf("ListDuplicates failed: %v", err)
+	}
+	if len(groups) != 2 || groups[0].FileHash != "hash-big" || groups[1].FileHash != "hash-small" {
+		t.Fatalf("unexpected groups: %+v", groups)
+	}
+	if groups[0].ReclaimableBytes != 3000 || groups[1].ReclaimableBytes != 200 {
+		t.Errorf("unexpected reclaimable bytes: %d, %d", groups[0].ReclaimableBytes, groups[1].ReclaimableBytes)
+	}
+	var ids []string
+	for _, item := range groups[1].Media {
+		ids = append(ids, item.ID)
+	}
+	if !reflect.DeepEqual(ids, []string{"m2", "m4", "m5"}) {
+		t.Errorf("unexpected members: %v", ids)
+	}
+	if a := groups[0].Media[0].Albums; len(a) != 1 || a[0] != (AlbumRef{ID: "a1", Title: "Trip"}) {
+		t.Errorf("unexpected albums: %+v", a)
+	}
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Error(err)
+	}
+}
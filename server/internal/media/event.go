@@ -0,0 +1,424 @@
+package media
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"math"
+	"time"
+)
+
+// 事件分群參數
+const (
+	EventTimeGap        = 16 * time.Hour // 相鄰兩筆超過此間隔即切分 (跨夜的旅行仍算同一事件)
+	EventDistanceJump   = 100.0          // 公里；位置跳動超過此距離且間隔超過 EventDistanceMinGap 時切分
+	EventDistanceMinGap = 2 * time.Hour
+	EventMinSize        = 5 // 少於此數量的群組不視為事件
+)
+
+var ErrEventNotFound = errors.New("event not found")
+
+// eventColumns 事件查詢共用欄位 (資料表別名須為 e)
+// 數量與封面排除垃圾桶與封存中的媒體；封面以我的最愛、照片優先，其次為最早拍攝者
+const eventColumns = `e.id, e.start_at, e.end_at, e.latitude, e.longitude,
+	(SELECT COUNT(*) FROM event_media em JOIN media m ON m.id = em.media_id
+	 WHERE em.event_id = e.id AND m.deleted_at IS NULL AND NOT m.is_archived) AS media_count,
+	(SELECT m.id FROM event_media em JOIN media m ON m.id = em.media_id
+	 WHERE em.event_id = e.id AND m.deleted_at IS NULL AND NOT m.is_archived
+	 ORDER BY m.is_favorite DESC, m.mime_type LIKE 'image/%' DESC, m.taken_at, m.id
+	 LIMIT 1) AS cover_media_id`
+
+// eventVisible 事件至少有一個可顯示的成員
+const eventVisible = `EXISTS (SELECT 1 FROM event_media em JOIN media m ON m.id = em.media_id
+	WHERE em.event_id = e.id AND m.deleted_at IS NULL AND NOT m.is_archived)`
+
+func scanEvent(row rowScanner, e *Event) error {
+	return row.Scan(&e.ID, &e.StartAt, &e.EndAt, &e.Latitude, &e.Longitude, &e.MediaCount, &e.CoverMediaID)
+}
+
+// eventPoint 分群所需的媒體欄位
+type eventPoint struct {
+	MediaID   string
+	TakenAt   time.Time
+	Latitude  *float64
+	Longitude *float64
+	EventID   string // 目前所屬事件，空字串代表沒有
+}
+
+// haversineKm 兩點間的大圓距離 (公里)
+func haversineKm(lat1, lng1, lat2, lng2 float64) float64 {
+	const earthRadiusKm = 6371.0
+	toRad := func(d float64) float64 { return d * math.Pi / 180 }
+	dLat, dLng := toRad(lat2-lat1), toRad(lng2-lng1)
+	a := math.Sin(dLat/2)*math.Sin(dLat/2) +
+		math.Cos(toRad(lat1))*math.Cos(toRad(lat2))*math.Sin(dLng/2)*math.Sin(dLng/2)
+	return 2 * earthRadiusKm * math.Asin(math.Min(1, math.Sqrt(a)))
+}
+
+// clusterEvents 依時間順序切分 (points 必須已依 taken_at 排序)，只回傳達到 EventMinSize 的群組
+// 位置比較使用群組內最後一個有 GPS 的點，沒有 GPS 的項目只依時間判斷
+func clusterEvents(points []eventPoint) [][]eventPoint {
+	var clusters [][]eventPoint
+	var current []eventPoint
+	var lastLat, lastLng *float64
+
+	flush := func() {
+		if len(current) >= EventMinSize {
+			clusters = append(clusters, current)
+		}
+		current, lastLat, lastLng = nil, nil, nil
+	}
+
+	for _, p := range points {
+		if len(current) > 0 {
+			gap := p.TakenAt.Sub(current[len(current)-1].TakenAt)
+			jumped := p.Latitude != nil && p.Longitude != nil && lastLat != nil &&
+				gap > EventDistanceMinGap &&
+				haversineKm(*lastLat, *lastLng, *p.Latitude, *p.Longitude) > EventDistanceJump
+			if gap > EventTimeGap || jumped {
+				flush()
+			}
+		}
+		current = append(current, p)
+		if p.Latitude != nil && p.Longitude != nil {
+			lastLat, lastLng = p.Latitude, p.Longitude
+		}
+	}
+	flush()
+	return clusters
+}
+
+// RebuildEvents 重新計算使用者所有事件，回傳事件數量
+func (s *Service) RebuildEvents(ctx context.Context, userID string) (int, error) {
+	return s.recomputeEvents(ctx, userID, nil)
+}
+
+// refreshEventsAround 上傳後只重算拍攝時間附近的事件
+func (s *Service) refreshEventsAround(ctx context.Context, userID string, takenAt time.Time) error {
+	_, err := s.recomputeEvents(ctx, userID, &takenAt)
+	return err
+}
+
+// recomputeEvents 重新分群；around 不為 nil 時只處理其前後相連的媒體 (間隔不超過 EventTimeGap) 與重疊的事件
+// 範圍兩端的間隔超過 EventTimeGap，分群在此必定切分，因此結果與完整重算相同
+// 新群組沿用成員重疊最多的舊事件 ID，讓客戶端持有的事件連結在重算後仍有效
+func (s *Service) recomputeEvents(ctx context.Context, userID string, around *time.Time) (int, error) {
+	tx, err := s.DB.BeginTx(ctx, nil)
+	if err != nil {
+		return 0, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	// 同一使用者的重算依序執行
+	if _, err := tx.ExecContext(ctx, `SELECT pg_advisory_xact_lock(hashtextextended('events:' || $1::text, 0))`, userID); err != nil {
+		return 0, fmt.Errorf("failed to lock events: %w", err)
+	}
+
+	var lo, hi *time.Time
+	if around != nil {
+		l, h := *around, *around
+		for {
+			nl, nh, err := eventChainBounds(ctx, tx, userID, l, h)
+			if err != nil {
+				return 0, err
+			}
+			// 包含所有重疊的既有事件 (成員可能已被刪除或封存)，再從新的邊界繼續走訪
+			var minStart, maxEnd sql.NullTime
+			err = tx.QueryRowContext(ctx, `
+				SELECT MIN(start_at), MAX(end_at) FROM events
+				WHERE user_id = $1 AND end_at >= $2 AND start_at <= $3
+			`, userID, nl, nh).Scan(&minStart, &maxEnd)
+			if err != nil {
+				return 0, fmt.Errorf("failed to query event range: %w", err)
+			}
+			if minStart.Valid && minStart.Time.Before(nl) {
+				nl = minStart.Time
+			}
+			if maxEnd.Valid && maxEnd.Time.After(nh) {
+				nh = maxEnd.Time
+			}
+			if nl.Equal(l) && nh.Equal(h) {
+				break
+			}
+			l, h = nl, nh
+		}
+		lo, hi = &l, &h
+	}
+
+	rows, err := tx.QueryContext(ctx, `
+		SELECT m.id, m.taken_at, m.latitude, m.longitude, COALESCE(em.event_id::text, '')
+		FROM media m
+		LEFT JOIN event_media em ON em.media_id = m.id
+		WHERE m.user_id = $1 AND m.deleted_at IS NULL AND NOT m.is_archived AND m.taken_at IS NOT NULL
+		  AND ($2::timestamptz IS NULL OR m.taken_at >= $2)
+		  AND ($3::timestamptz IS NULL OR m.taken_at <= $3)
+		ORDER BY m.taken_at, m.id
+	`, userID, lo, hi)
+	if err != nil {
+		return 0, fmt.Errorf("failed to query media for events: %w", err)
+	}
+	var points []eventPoint
+	var loaded []string
+	for rows.Next() {
+		var p eventPoint
+		if err := rows.Scan(&p.MediaID, &p.TakenAt, &p.Latitude, &p.Longitude, &p.EventID); err != nil {
+			rows.Close()
+			return 0, fmt.Errorf("failed to scan event point: %w", err)
+		}
+		points = append(points, p)
+		loaded = append(loaded, p.MediaID)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return 0, fmt.Errorf("failed to iterate event points: %w", err)
+	}
+
+	rows, err = tx.QueryContext(ctx, `
+		SELECT id FROM events
+		WHERE user_id = $1
+		  AND ($2::timestamptz IS NULL OR end_at >= $2)
+		  AND ($3::timestamptz IS NULL OR start_at <= $3)
+	`, userID, lo, hi)
+	if err != nil {
+		return 0, fmt.Errorf("failed to query events: %w", err)
+	}
+	unused := map[string]bool{}
+	for rows.Next() {
+		var id string
+		if err := rows.Scan(&id); err != nil {
+			rows.Close()
+			return 0, fmt.Errorf("failed to scan event: %w", err)
+		}
+		unused[id] = true
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return 0, fmt.Errorf("failed to iterate events: %w", err)
+	}
+
+	if len(loaded) > 0 {
+		if _, err := tx.ExecContext(ctx, `DELETE FROM event_media WHERE media_id = ANY($1::uuid[])`, loaded); err != nil {
+			return 0, fmt.Errorf("failed to clear event membership: %w", err)
+		}
+	}
+
+	clusters := clusterEvents(points)
+	for _, cluster := range clusters {
+		eventID := pickEventID(cluster, unused)
+		start, end := cluster[0].TakenAt, cluster[len(cluster)-1].TakenAt
+		lat, lng := centroid(cluster)
+
+		if eventID != "" {
+			delete(unused, eventID)
+			_, err = tx.ExecContext(ctx, `
+				UPDATE events SET start_at = $2, end_at = $3, latitude = $4, longitude = $5, updated_at = NOW()
+				WHERE id = $1
+			`, eventID, start, end, lat, lng)
+		} else {
+			err = tx.QueryRowContext(ctx, `
+				INSERT INTO events (user_id, start_at, end_at, latitude, longitude)
+				VALUES ($1, $2, $3, $4, $5) RETURNING id
+			`, userID, start, end, lat, lng).Scan(&eventID)
+		}
+		if err != nil {
+			return 0, fmt.Errorf("failed to save event: %w", err)
+		}
+
+		ids := make([]string, len(cluster))
+		for i, p := range cluster {
+			ids[i] = p.MediaID
+		}
+		_, err = tx.ExecContext(ctx, `
+			INSERT INTO event_media (media_id, event_id)
+			SELECT id, $2 FROM unnest($1::uuid[]) AS id
+		`, ids, eventID)
+		if err != nil {
+			return 0, fmt.Errorf("failed to assign event media: %w", err)
+		}
+	}
+
+	if len(unused) > 0 {
+		stale := make([]string, 0, len(unused))
+		for id := range unused {
+			stale = append(stale, id)
+		}
+		if _, err := tx.ExecContext(ctx, `DELETE FROM events WHERE id = ANY($1::uuid[])`, stale); err != nil {
+			return 0, fmt.Errorf("failed to delete stale events: %w", err)
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		return 0, fmt.Errorf("failed to commit: %w", err)
+	}
+	return len(clusters), nil
+}
+
+// eventChainBounds 從 [lo, hi] 沿拍攝時間往前後走訪，直到相鄰媒體的間隔超過 EventTimeGap，回傳新的邊界
+// 每一步只查詢邊界前後 EventTimeGap 內最遠的一筆 (taken_at 索引範圍查詢)
+func eventChainBounds(ctx context.Context, tx *sql.Tx, userID string, lo, hi time.Time) (time.Time, time.Time, error) {
+	query := `
+		WITH RECURSIVE back(t) AS (
+			SELECT $2::timestamptz
+			UNION ALL
+			SELECT (SELECT MIN(m.taken_at) FROM media m
+			        WHERE m.user_id = $1 AND m.deleted_at IS NULL AND NOT m.is_archived
+			          AND m.taken_at >= b.t - $4 * INTERVAL '1 second' AND m.taken_at < b.t)
+			FROM back b WHERE b.t IS NOT NULL
+		), fwd(t) AS (
+			SELECT $3::timestamptz
+			UNION ALL
+			SELECT (SELECT MAX(m.taken_at) FROM media m
+			        WHERE m.user_id = $1 AND m.deleted_at IS NULL AND NOT m.is_archived
+			          AND m.taken_at > f.t AND m.taken_at <= f.t + $4 * INTERVAL '1 second')
+			FROM fwd f WHERE f.t IS NOT NULL
+		)
+		SELECT (SELECT MIN(t) FROM back), (SELECT MAX(t) FROM fwd)
+	`
+	var l, h time.Time
+	if err := tx.QueryRowContext(ctx, query, userID, lo, hi, int64(EventTimeGap/time.Second)).Scan(&l, &h); err != nil {
+		return lo, hi, fmt.Errorf("failed to query event window: %w", err)
+	}
+	return l, h, nil
+}
+
+// pickEventID 選出成員重疊最多且尚未被使用的舊事件 ID (candidates 為可沿用的舊事件)
+func pickEventID(cluster []eventPoint, candidates map[string]bool) string {
+	votes := map[string]int{}
+	best, bestVotes := "", 0
+	for _, p := range cluster {
+		if p.EventID == "" || !candidates[p.EventID] {
+			continue
+		}
+		votes[p.EventID]++
+		if v := votes[p.EventID]; v > bestVotes || (v == bestVotes && p.EventID < best) {
+			best, bestVotes = p.EventID, v
+		}
+	}
+	return best
+}
+
+// centroid 有 GPS 成員的平均位置
+func centroid(cluster []eventPoint) (*float64, *float64) {
+	var sumLat, sumLng float64
+	n := 0
+	for _, p := range cluster {
+		if p.Latitude != nil && p.Longitude != nil {
+			sumLat += *p.Latitude
+			sumLng += *p.Longitude
+			n++
+		}
+	}
+	if n == 0 {
+		return nil, nil
+	}
+	lat, lng := sumLat/float64(n), sumLng/float64(n)
+	return &lat, &lng
+}
+
+// ListEvents 取得事件列表 (最近的事件優先)
+func (s *Service) ListEvents(ctx context.Context, userID string, limit, offset int) ([]*Event, error) {
+	query := `
+		SELECT ` + eventColumns + `
+		FROM events e
+		WHERE e.user_id = $1 AND ` + eventVisible + `
+		ORDER BY e.start_at DESC
+		LIMIT $2 OFFSET $3
+	`
+	rows, err := s.DB.QueryContext(ctx, query, userID, limit, offset)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query events: %w", err)
+	}
+	defer rows.Close()
+
+	list := []*Event{}
+	for rows.Next() {
+		e := &Event{}
+		if err := scanEvent(rows, e); err != nil {
+			return nil, fmt.Errorf("failed to scan event: %w", err)
+		}
+		list = append(list, e)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to iterate events: %w", err)
+	}
+	return list, nil
+}
+
+// GetEvent 取得單一事件
+func (s *Service) GetEvent(ctx context.Context, userID, eventID string) (*Event, error) {
+	query := `SELECT ` + eventColumns + ` FROM events e WHERE e.id = $1 AND e.user_id = $2`
+	e := &Event{}
+	if err := scanEvent(s.DB.QueryRowContext(ctx, query, eventID, userID), e); err != nil {
+		if err == sql.ErrNoRows {
+			return nil, ErrEventNotFound
+		}
+		return nil, fmt.Errorf("failed to query event: %w", err)
+	}
+	return e, nil
+}
+
+// ListEventMedia 取得事件內容 (依拍攝時間由舊到新)
+func (s *Service) ListEventMedia(ctx context.Context, userID, eventID string, limit, offset int) ([]*Media, error) {
+	if _, err := s.GetEvent(ctx, userID, eventID); err != nil {
+		return nil, err
+	}
+
+	query := `
+		SELECT ` + mediaColumns + `
+		FROM event_media em
+		JOIN media m ON m.id = em.media_id
+		WHERE em.event_id = $1 AND m.deleted_at IS NULL AND NOT m.is_archived
+		ORDER BY m.taken_at, m.id
+		LIMIT $2 OFFSET $3
+	`
+	rows, err := s.DB.QueryContext(ctx, query, eventID, limit, offset)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query event media: %w", err)
+	}
+	defer rows.Close()
+
+	return collectMedia(rows)
+}
+
+// PromoteEvent 將事件轉為一般相簿 (依拍攝時間排序)；title 為空時以日期範圍 (UTC) 命名
+// 之後重新分群不會影響已建立的相簿
+func (s *Service) PromoteEvent(ctx context.Context, userID, eventID, title string) (*Album, error) {
+	e, err := s.GetEvent(ctx, userID, eventID)
+	if err != nil {
+		return nil, err
+	}
+	if title == "" {
+		start, end := e.StartAt.UTC().Format("2006-01-02"), e.EndAt.UTC().Format("2006-01-02")
+		title = start
+		if end != start {
+			title = start + " – " + end
+		}
+	}
+
+	tx, err := s.DB.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	var albumID string
+	if err := tx.QueryRowContext(ctx, `INSERT INTO albums (user_id, title) VALUES ($1, $2) RETURNING id`, userID, title).Scan(&albumID); err != nil {
+		return nil, fmt.Errorf("failed to create album: %w", err)
+	}
+	_, err = tx.ExecContext(ctx, `
+		INSERT INTO album_media (album_id, media_id, position)
+		SELECT $1, m.id, ROW_NUMBER() OVER (ORDER BY m.taken_at, m.id)
+		FROM event_media em
+		JOIN media m ON m.id = em.media_id
+		WHERE em.event_id = $2 AND m.deleted_at IS NULL AND NOT m.is_archived
+	`, albumID, eventID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to add event media to album: %w", err)
+	}
+	if err := tx.Commit(); err != nil {
+		return nil, fmt.Errorf("failed to commit: %w", err)
+	}
+
+	return s.GetAlbum(ctx, userID, albumID)
+}
@@ -0,0 +1,119 @@
+package media
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+)
+
+type promoteEventRequest struct {
+	Title string `json:"title"` // 選填，預設為日期範圍
+}
+
+// respondEventError 將事件錯誤轉換為 HTTP 狀態碼
+func respondEventError(c *gin.Context, err error) {
+	if errors.Is(err, ErrEventNotFound) {
+		c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+}
+
+// eventParam 取得並驗證路徑中的事件 ID
+func eventParam(c *gin.Context) (string, bool) {
+	eventID := c.Param("id")
+	if !uuidPattern.MatchString(eventID) {
+		c.JSON(http.StatusNotFound, gin.H{"error": ErrEventNotFound.Error()})
+		return "", false
+	}
+	return eventID, true
+}
+
+// ListEventsHandler 取得自動分群的事件列表 (GET /events?page=1&limit=20)
+func (h *Handler) ListEventsHandler(c *gin.Context) {
+	userID := c.MustGet("userID").(string)
+	limit, offset := parsePagination(c)
+
+	events, err := h.Service.ListEvents(c.Request.Context(), userID, limit, offset)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	jsonWithETag(c, http.StatusOK, events)
+}
+
+// GetEventHandler 取得單一事件 (GET /events/:id)
+func (h *Handler) GetEventHandler(c *gin.Context) {
+	userID := c.MustGet("userID").(string)
+	eventID, ok := eventParam(c)
+	if !ok {
+		return
+	}
+
+	event, err := h.Service.GetEvent(c.Request.Context(), userID, eventID)
+	if err != nil {
+		respondEventError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, event)
+}
+
+// ListEventMediaHandler 取得事件內容 (GET /events/:id/media?page=1&limit=20)
+func (h *Handler) ListEventMediaHandler(c *gin.Context) {
+	userID := c.MustGet("userID").(string)
+	eventID, ok := eventParam(c)
+	if !ok {
+		return
+	}
+	limit, offset := parsePagination(c)
+
+	list, err := h.Service.ListEventMedia(c.Request.Context(), userID, eventID, limit, offset)
+	if err != nil {
+		respondEventError(c, err)
+		return
+	}
+
+	h.attachURLs(userID, list...)
+	jsonWithETag(c, http.StatusOK, list)
+}
+
+// PromoteEventHandler 將事件轉為一般相簿 (POST /events/:id/promote，body 選填: {"title": "..."})
+func (h *Handler) PromoteEventHandler(c *gin.Context) {
+	userID := c.MustGet("userID").(string)
+	eventID, ok := eventParam(c)
+	if !ok {
+		return
+	}
+
+	var req promoteEventRequest
+	if c.Request.ContentLength != 0 {
+		if err := c.ShouldBindJSON(&req); err != nil || len(req.Title) > 255 {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "title must be at most 255 characters"})
+			return
+		}
+	}
+
+	album, err := h.Service.PromoteEvent(c.Request.Context(), userID, eventID, req.Title)
+	if err != nil {
+		respondEventError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusCreated, album)
+}
+
+// RebuildEventsHandler 重新計算所有事件 (POST /events/rebuild)
+func (h *Handler) RebuildEventsHandler(c *gin.Context) {
+	userID := c.MustGet("userID").(string)
+
+	count, err := h.Service.RebuildEvents(c.Request.Context(), userID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"events": count})
+}
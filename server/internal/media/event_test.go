@@ -0,0 +1,174 @@
+package media
+
+import (
+	"context"
+	"database/sql/driver"
+	"fmt"
+	"reflect"
+	"sort"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+)
+
+func TestClusterEvents(t *testing.T) {
+	base := time.Date(2024, 6, 7, 18, 0, 0, 0, time.UTC)
+	taipei := [2]float64{25.03, 121.56}
+	tokyo := [2]float64{35.68, 139.69}
+
+	var points []eventPoint
+	add := func(offset time.Duration, loc *[2]float64) {
+		p := eventPoint{MediaID: offset.String(), TakenAt: base.Add(offset)}
+		if loc != nil {
+			p.Latitude, p.Longitude = &loc[0], &loc[1]
+		}
+		points = append(points, p)
+	}
+
+	// 週末旅行：跨兩晚，中間有沒有 GPS 的照片
+	for _, h := range []int{0, 1, 14, 15, 16, 30, 31} {
+		add(time.Duration(h)*time.Hour, &tokyo)
+	}
+	add(32*time.Hour, nil)
+	// 同一天回到台北 (超過 2 小時且距離跳動) 應切分
+	for _, h := range []int{36, 37, 38, 39, 40} {
+		add(time.Duration(h)*time.Hour, &taipei)
+	}
+	// 太少的群組不成為事件
+	add(100*time.Hour, nil)
+	add(101*time.Hour, nil)
+
+	clusters := clusterEvents(points)
+	if len(clusters) != 2 {
+		t.Fatalf("expected 2 events, got %d", len(clusters))
+	}
+	if len(clusters[0]) != 8 || len(clusters[1]) != 5 {
+		t.Errorf("unexpected cluster sizes: %d, %d", len(clusters[0]), len(clusters[1]))
+	}
+}
+
+func TestPickEventID(t *testing.T) {
+	cluster := []eventPoint{{EventID: "a"}, {EventID: "b"}, {EventID: "b"}, {EventID: "c"}, {}}
+	if got := pickEventID(cluster, map[string]bool{"a": true, "b": true}); got != "b" {
+		t.Errorf("expected b, got %q", got)
+	}
+	if got := pickEventID(cluster, map[string]bool{"a": true}); got != "a" {
+		t.Errorf("expected a, got %q", got)
+	}
+	if got := pickEventID(cluster, map[string]bool{}); got != "" {
+		t.Errorf("expected new event, got %q", got)
+	}
+}
+
+func TestHaversineKm(t *testing.T) {
+	// 台北 - 東京約 2100 公里
+	if d := haversineKm(25.03, 121.56, 35.68, 139.69); d < 2050 || d > 2150 {
+		t.Errorf("unexpected distance: %.0f", d)
+	}
+}
+
+// captureArg 記錄參數值的 sqlmock 比對器
+type captureArg struct{ dst *[]string }
+
+func (c captureArg) Match(v driver.Value) bool {
+	ids, ok := v.([]string)
+	*c.dst = append([]string(nil), ids...)
+	return ok
+}
+
+// expectRecompute 依模擬的媒體資料設定一次重算的查詢；around 為 nil 時為完整重算
+// 資料只構成一個事件，回傳寫入該事件的成員
+func expectRecompute(mock sqlmock.Sqlmock, media []time.Time, around *time.Time) *[]string {
+	mock.ExpectBegin()
+	mock.ExpectExec(`pg_advisory_xact_lock`).WillReturnResult(sqlmock.NewResult(0, 0))
+
+	var lo, hi any
+	if around != nil {
+		// 模擬 eventChainBounds：沿相鄰間隔不超過 EventTimeGap 的媒體往前後走訪
+		l, h := *around, *around
+		for {
+			nl, nh := l, h
+			for _, t := range media {
+				if t.Before(nl) && !t.Before(nl.Add(-EventTimeGap)) {
+					nl = t
+				}
+			}
+			for _, t := range media {
+				if t.After(nh) && !t.After(nh.Add(EventTimeGap)) {
+					nh = t
+				}
+			}
+			if nl.Equal(l) && nh.Equal(h) {
+				break
+			}
+			l, h = nl, nh
+		}
+		mock.ExpectQuery(`WITH RECURSIVE back`).
+			WithArgs("user-1", *around, *around, int64(EventTimeGap/time.Second)).
+			WillReturnRows(sqlmock.NewRows([]string{"lo", "hi"}).AddRow(l, h))
+		mock.ExpectQuery(`SELECT MIN\(start_at\), MAX\(end_at\) FROM events`).
+			WillReturnRows(sqlmock.NewRows([]string{"min", "max"}).AddRow(nil, nil))
+		mock.ExpectQuery(`WITH RECURSIVE back`).
+			WithArgs("user-1", l, h, int64(EventTimeGap/time.Second)).
+			WillReturnRows(sqlmock.NewRows([]string{"lo", "hi"}).AddRow(l, h))
+		mock.ExpectQuery(`SELECT MIN\(start_at\), MAX\(end_at\) FROM events`).
+			WillReturnRows(sqlmock.NewRows([]string{"min", "max"}).AddRow(nil, nil))
+		lo, hi = l, h
+	}
+
+	points := sqlmock.NewRows([]string{"id", "taken_at", "latitude", "longitude", "event_id"})
+	var loaded int
+	for i, t := range media {
+		if around == nil || (!t.Before(lo.(time.Time)) && !t.After(hi.(time.Time))) {
+			points.AddRow(fmt.Sprintf("m%d", i), t, nil, nil, "")
+			loaded++
+		}
+	}
+	mock.ExpectQuery(`SELECT m.id, m.taken_at`).WithArgs("user-1", lo, hi).WillReturnRows(points)
+	mock.ExpectQuery(`SELECT id FROM events`).WillReturnRows(sqlmock.NewRows([]string{"id"}))
+	if loaded > 0 {
+		mock.ExpectExec(`DELETE FROM event_media`).WillReturnResult(sqlmock.NewResult(0, 0))
+	}
+
+	members := &[]string{}
+	mock.ExpectQuery(`INSERT INTO events`).WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow("e1"))
+	mock.ExpectExec(`INSERT INTO event_media`).
+		WithArgs(captureArg{members}, "e1").
+		WillReturnResult(sqlmock.NewResult(0, 5))
+	mock.ExpectCommit()
+	return members
+}
+
+func TestRefreshEventsMatchesRebuild(t *testing.T) {
+	base := time.Date(2024, 6, 7, 0, 0, 0, 0, time.UTC)
+	// 四張照片不足以成為事件，第五張 (40 小時) 上傳後整串相連成為一個事件
+	// 上傳時間前後 EventTimeGap 內只有 30 小時的一張，需沿著間隔往前找到 0 小時
+	var media []time.Time
+	for _, h := range []int{0, 10, 20, 30, 40} {
+		media = append(media, base.Add(time.Duration(h)*time.Hour))
+	}
+	uploaded := media[len(media)-1]
+
+	db, mock := newMockDB(t)
+	s := &Service{DB: db}
+
+	rebuilt := expectRecompute(mock, media, nil)
+	if n, err := s.RebuildEvents(context.Background(), "user-1"); err != nil || n != 1 {
+		t.Fatalf("RebuildEvents = %d, %v", n, err)
+	}
+	refreshed := expectRecompute(mock, media, &uploaded)
+	if err := s.refreshEventsAround(context.Background(), "user-1", uploaded); err != nil {
+		t.Fatalf("refreshEventsAround failed: %v", err)
+	}
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Fatal(err)
+	}
+
+	want, got := *rebuilt, *refreshed
+	sort.Strings(want)
+	sort.Strings(got)
+	if len(want) != len(media) || !reflect.DeepEqual(got, want) {
+		t.Errorf("incremental event %v differs from rebuild %v", got, want)
+	}
+}
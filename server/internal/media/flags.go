@@ -0,0 +1,26 @@
+package media
+
+import (
+	"context"
+	"fmt"
+)
+
+// SetFavorite 批次設定我的最愛，回傳實際符合的媒體數量 (不含垃圾桶中的項目)
+func (s *Service) SetFavorite(ctx context.Context, userID string, mediaIDs []string, favorite bool) (int64, error) {
+	return setMediaFlag(ctx, s.DB, "is_favorite", userID, mediaIDs, favorite)
+}
+
+// SetArchived 批次設定封存，封存的項目不會出現在主時間軸與「那年今日」
+func (s *Service) SetArchived(ctx context.Context, userID string, mediaIDs []string, archived bool) (int64, error) {
+	return setMediaFlag(ctx, s.DB, "is_archived", userID, mediaIDs, archived)
+}
+
+// setMediaFlag 更新布林欄位 (column 僅限程式內的固定值，不可來自使用者輸入)
+func setMediaFlag(ctx context.Context, db execer, column, userID string, mediaIDs []string, value bool) (int64, error) {
+	query := `UPDATE media SET ` + column + ` = $3 WHERE user_id = $1 AND id = ANY($2::uuid[]) AND deleted_at IS NULL`
+	res, err := db.ExecContext(ctx, query, userID, mediaIDs, value)
+	if err != nil {
+		return 0, fmt.Errorf("failed to update %s: %w", column, err)
+	}
+	return res.RowsAffected()
+}
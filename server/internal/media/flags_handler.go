@@ -0,0 +1,81 @@
+package media
+
+import (
+	"context"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+)
+
+// flagSetter 為 SetFavorite / SetArchived 的共同簽名
+type flagSetter func(ctx context.Context, userID string, mediaIDs []string, value bool) (int64, error)
+
+type bulkFlagRequest struct {
+	MediaIDs []string `json:"media_ids"`
+	Value    *bool    `json:"value"`
+}
+
+// FavoriteHandler 加入 (PUT) 或移除 (DELETE) 我的最愛 (/media/:id/favorite)
+func (h *Handler) FavoriteHandler(c *gin.Context) {
+	h.setFlag(c, h.Service.SetFavorite)
+}
+
+// ArchiveHandler 封存 (PUT) 或取消封存 (DELETE) (/media/:id/archive)
+func (h *Handler) ArchiveHandler(c *gin.Context) {
+	h.setFlag(c, h.Service.SetArchived)
+}
+
+// BulkFavoriteHandler 批次設定我的最愛 (POST /media/favorite，body: {"media_ids": [...], "value": true})
+func (h *Handler) BulkFavoriteHandler(c *gin.Context) {
+	h.setFlagBulk(c, h.Service.SetFavorite)
+}
+
+// BulkArchiveHandler 批次設定封存 (POST /media/archive，body: {"media_ids": [...], "value": true})
+func (h *Handler) BulkArchiveHandler(c *gin.Context) {
+	h.setFlagBulk(c, h.Service.SetArchived)
+}
+
+func (h *Handler) setFlag(c *gin.Context, set flagSetter) {
+	userID := c.MustGet("userID").(string)
+	mediaID := c.Param("id")
+
+	if !uuidPattern.MatchString(mediaID) {
+		c.JSON(http.StatusNotFound, gin.H{"error": ErrMediaNotFound.Error()})
+		return
+	}
+
+	value := c.Request.Method != http.MethodDelete
+	n, err := set(c.Request.Context(), userID, []string{mediaID}, value)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	if n == 0 {
+		c.JSON(http.StatusNotFound, gin.H{"error": ErrMediaNotFound.Error()})
+		return
+	}
+
+	c.Status(http.StatusNoContent)
+}
+
+func (h *Handler) setFlagBulk(c *gin.Context, set flagSetter) {
+	userID := c.MustGet("userID").(string)
+
+	var req bulkFlagRequest
+	if err := c.ShouldBindJSON(&req); err != nil || req.Value == nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "media_ids and value are required"})
+		return
+	}
+	if err := validateIDs(req.MediaIDs); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	n, err := set(c.Request.Context(), userID, req.MediaIDs, *req.Value)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"updated": n})
+}
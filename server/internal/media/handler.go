@@ -4,21 +4,63 @@ import (
 	"fmt"
 	"net/http"
 	"path/filepath"
+	"regexp"
 	"strconv"
+	"strings"
 	"time"
 
 	"github.com/gin-gonic/gin"
+
+	"gogallery/internal/auth"
 )
 
 type Handler struct {
 	Service *Service
+	Signer  *auth.URLSigner // 回應中的簽章網址；nil 時不產生
 }
 
 func NewHandler(s *Service) *Handler {
 	return &Handler{Service: s}
 }
 
+// uuidPattern 用於驗證路徑與 body 中的 ID，避免無效輸入造成資料庫錯誤
+var uuidPattern = regexp.MustCompile(`^[0-9a-fA-F]{8}-[0-9a-fA-F]{4}-[0-9a-fA-F]{4}-[0-9a-fA-F]{4}-[0-9a-fA-F]{12}$`)
+
+// maxBatchSize 單次批次操作的 ID 上限
+const maxBatchSize = 1000
+
+// parsePagination 解析 page / limit 參數 (limit 1-100，預設 20)
+func parsePagination(c *gin.Context) (limit, offset int) {
+	page, _ := strconv.Atoi(c.DefaultQuery("page", "1"))
+	limit, _ = strconv.Atoi(c.DefaultQuery("limit", "20"))
+	if page < 1 {
+		page = 1
+	}
+	if limit < 1 || limit > 100 {
+		limit = 20
+	}
+	return limit, (page - 1) * limit
+}
+
+// validateIDs 檢查批次 ID 列表 (非空、不超過上限、皆為 UUID)
+func validateIDs(ids []string) error {
+	if len(ids) == 0 {
+		return fmt.Errorf("ids must not be empty")
+	}
+	if len(ids) > maxBatchSize {
+		return fmt.Errorf("too many ids (max %d)", maxBatchSize)
+	}
+	for _, id := range ids {
+		if !uuidPattern.MatchString(id) {
+			return fmt.Errorf("invalid id: %s", id)
+		}
+	}
+	return nil
+}
+
 // UploadHandler 處理檔案上傳
+// ?warn_similar=true 時回應中的 near_duplicates 列出視覺上相似的既有媒體 (可搭配 similarity)
+// 選填的 multipart 欄位 sidecar 為同一張照片的 XMP sidecar (Lightroom / darktable)，上傳後一併套用
 func (h *Handler) UploadHandler(c *gin.Context) {
 	userID := c.MustGet("userID").(string)
 
@@ -28,6 +70,20 @@ func (h *Handler) UploadHandler(c *gin.Context) {
 		return
 	}
 
+	// 先解析 sidecar，格式錯誤時不寫入任何資料
+	var sidecar *xmpData
+	if sidecarHeader, err := c.FormFile("sidecar"); err == nil {
+		f, err := sidecarHeader.Open()
+		if err == nil {
+			sidecar, err = readSidecar(f)
+			f.Close()
+		}
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+	}
+
 	force := c.Query("force") == "true"
 	var takenAt *time.Time
 	if ta := c.PostForm("taken_at"); ta != "" {
@@ -58,45 +114,67 @@ func (h *Handler) UploadHandler(c *gin.Context) {
 		return
 	}
 
+	// 套用 sidecar (失敗不影響上傳結果)
+	if sidecar != nil {
+		updated, err := h.Service.applySidecar(c.Request.Context(), userID, result.Media.ID, sidecar)
+		if err != nil {
+			fmt.Printf("failed to apply sidecar for %s: %v\n", result.Media.ID, err)
+		} else {
+			updated.PHash = result.Media.PHash
+			result.Media = updated
+		}
+	}
+
+	// ?warn_similar=true 時附上相似的既有媒體 (失敗不影響上傳結果)
+	if c.Query("warn_similar") == "true" && result.Media.PHash != nil {
+		similarity, err := parseSimilarity(c)
+		if err != nil {
+			similarity = DefaultSimilarity
+		}
+		ids, err := h.Service.FindNearDuplicates(c.Request.Context(), userID, result.Media.ID, *result.Media.PHash, similarity)
+		if err != nil {
+			fmt.Printf("failed to check near duplicates for %s: %v\n", result.Media.ID, err)
+		} else {
+			result.Media.NearDuplicates = ids
+		}
+	}
+
+	h.attachURLs(userID, result.Media)
 	c.JSON(http.StatusCreated, result.Media)
 }
 
 // ListHandler 取得媒體列表
+// 預設排除封存項目；?favorite=true 只列出我的最愛，?archived=true 只列出封存項目
+// ?tag=a&tag=b 依標籤篩選，tag_mode=all 需符合全部標籤 (預設 any)
+// 一般時間軸會合併選擇加入的伴侶媒體 (from_partner=true)；我的最愛與封存列表只含自己的媒體
 func (h *Handler) ListHandler(c *gin.Context) {
 	userID := c.MustGet("userID").(string)
 
-	page, _ := strconv.Atoi(c.DefaultQuery("page", "1"))
-	limit, _ := strconv.Atoi(c.DefaultQuery("limit", "20"))
-	if page < 1 {
-		page = 1
-	}
-	if limit < 1 || limit > 100 {
-		limit = 20
+	limit, offset := parsePagination(c)
+
+	opts := ListOptions{
+		FavoritesOnly: c.Query("favorite") == "true",
+		ArchivedOnly:  c.Query("archived") == "true",
+		Tags:          c.QueryArray("tag"),
+		MatchAllTags:  c.Query("tag_mode") == "all",
 	}
-	offset := (page - 1) * limit
+	opts.IncludePartners = !opts.FavoritesOnly && !opts.ArchivedOnly
 
-	list, err := h.Service.List(c.Request.Context(), userID, limit, offset)
+	list, err := h.Service.List(c.Request.Context(), userID, opts, limit, offset)
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
 		return
 	}
+	h.attachURLs(userID, list...)
 
-	c.JSON(http.StatusOK, list)
+	jsonWithETag(c, http.StatusOK, list)
 }
 
 // ListTrashHandler 取得垃圾桶列表
 func (h *Handler) ListTrashHandler(c *gin.Context) {
 	userID := c.MustGet("userID").(string)
 
-	page, _ := strconv.Atoi(c.DefaultQuery("page", "1"))
-	limit, _ := strconv.Atoi(c.DefaultQuery("limit", "20"))
-	if page < 1 {
-		page = 1
-	}
-	if limit < 1 || limit > 100 {
-		limit = 20
-	}
-	offset := (page - 1) * limit
+	limit, offset := parsePagination(c)
 
 	list, err := h.Service.ListTrash(c.Request.Context(), userID, limit, offset)
 	if err != nil {
@@ -104,7 +182,8 @@ func (h *Handler) ListTrashHandler(c *gin.Context) {
 		return
 	}
 
-	c.JSON(http.StatusOK, list)
+	h.attachURLs(userID, list...)
+	jsonWithETag(c, http.StatusOK, list)
 }
 
 // RestoreHandler 還原媒體
@@ -168,6 +247,10 @@ func (h *Handler) DeleteHandler(c *gin.Context) {
 }
 
 // GetFileHandler 提供檔案下載
+//
+// 預設回傳可顯示的版本：依 Accept 協商 WebP/JPEG (HEIC 等格式會轉檔，影片為封面畫格)，
+// 原始檔本身即可顯示時直接回傳；w、h、fit 指定縮放，只有 format=original 才回傳位元組完全相同的原始檔
+// 共享相簿的成員也可讀取相簿中他人的媒體
 func (h *Handler) GetFileHandler(c *gin.Context) {
 	userID := c.MustGet("userID").(string)
 	mediaID := c.Param("id")
@@ -178,15 +261,84 @@ func (h *Handler) GetFileHandler(c *gin.Context) {
 	}
 
 	// 查詢檔案路徑
-	media, err := h.Service.GetByID(c.Request.Context(), userID, mediaID)
+	media, err := h.Service.GetAccessibleByID(c.Request.Context(), userID, mediaID)
 	if err != nil {
 		c.JSON(http.StatusNotFound, gin.H{"error": "media not found"})
 		return
 	}
 
-	// 提供檔案
-	filePath := filepath.Join(h.Service.UploadDir, media.StoragePath)
-	c.File(filePath)
+	opts, original, err := parseVariantQuery(c)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if !original && opts.CanServeOriginal(media) {
+		original = true
+	}
+
+	h.serveMedia(c, media, media.FileHash, opts, original, immutableCacheControl)
+}
+
+// serveMedia 提供原始檔或衍生檔案，以 etagBase 組成 ETag，並以 cacheControl 設定快取標頭
+func (h *Handler) serveMedia(c *gin.Context, media *Media, etagBase string, opts VariantOptions, original bool, cacheControl string) {
+	c.Header("Cache-Control", cacheControl)
+
+	// 提供原始檔案
+	if original {
+		if notModified(c, strongETag(etagBase), media.UploadedAt) {
+			return
+		}
+		filePath := filepath.Join(h.Service.UploadDir, media.StoragePath)
+		serveFile(c, filePath, media.UploadedAt)
+		return
+	}
+
+	etag := strongETag(etagBase, fmt.Sprintf("%dx%d", opts.Width, opts.Height), opts.Fit, opts.Format)
+	if notModified(c, etag, media.UploadedAt) {
+		return
+	}
+	variant, err := h.Service.Variant(c.Request.Context(), media, opts)
+	if err != nil {
+		c.Header("Cache-Control", "no-store")
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	defer variant.Close()
+	c.Header("Content-Type", opts.ContentType())
+	http.ServeContent(c.Writer, c.Request, "", media.UploadedAt, variant)
+}
+
+// parseVariantQuery 解析 w、h、fit、format 參數；只有明確指定 format=original 時回傳 original=true
+func parseVariantQuery(c *gin.Context) (VariantOptions, bool, error) {
+	format, err := NegotiateFormat(c.Query("format"), c.GetHeader("Accept"))
+	if err != nil {
+		return VariantOptions{}, false, err
+	}
+	if format == FormatOriginal {
+		return VariantOptions{}, true, nil
+	}
+	// 自動協商的結果依 Accept 而不同，快取需區分
+	f := c.Query("format")
+	negotiated := f == "" || strings.EqualFold(f, FormatAuto)
+	if negotiated {
+		c.Header("Vary", "Accept")
+	}
+
+	opts := VariantOptions{Fit: c.Query("fit"), Format: format, negotiated: negotiated}
+	if w := c.Query("w"); w != "" {
+		if opts.Width, err = strconv.Atoi(w); err != nil || opts.Width < 1 {
+			return VariantOptions{}, false, fmt.Errorf("invalid w")
+		}
+	}
+	if hv := c.Query("h"); hv != "" {
+		if opts.Height, err = strconv.Atoi(hv); err != nil || opts.Height < 1 {
+			return VariantOptions{}, false, fmt.Errorf("invalid h")
+		}
+	}
+	if err := opts.Validate(); err != nil {
+		return VariantOptions{}, false, err
+	}
+	return opts, false, nil
 }
 
 // CheckHashHandler 檢查 Hash 是否已存在
@@ -210,5 +362,6 @@ func (h *Handler) CheckHashHandler(c *gin.Context) {
 		return
 	}
 
+	h.attachURLs(userID, media)
 	c.JSON(http.StatusOK, media)
 }
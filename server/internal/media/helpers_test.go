@@ -0,0 +1,57 @@
+package media
+
+import (
+	"database/sql"
+	"database/sql/driver"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+)
+
+// mediaTestColumns 對應 mediaColumns 的欄位名稱 (供 sqlmock 建立資料列)
+var mediaTestColumns = []string{
+	"id", "user_id", "original_filename", "file_hash", "size_bytes", "mime_type",
+	"width", "height", "duration", "taken_at", "latitude", "longitude",
+	"camera_make", "camera_model", "exposure_time", "aperture", "iso",
+	"blur_hash", "dominant_color", "uploaded_at", "deleted_at",
+	"stream_status", "is_favorite", "is_archived", "tags",
+	"caption", "taken_at_overridden", "location_overridden",
+	"stack_id", "stack_size",
+	"rating",
+}
+
+// mediaTestRow 產生一筆符合 mediaTestColumns 的資料
+func mediaTestRow(id string, takenAt time.Time) []driver.Value {
+	return []driver.Value{
+		id, "user-1", id + ".jpg", "hash-" + id, int64(100), "image/jpeg",
+		4000, 3000, 0.0, takenAt, nil, nil,
+		"SONY", "ILCE-7M4", "1/100", 2.8, 100,
+		"", "", takenAt, nil,
+		"", false, false, "[]",
+		"", false, false,
+		nil, 0,
+		nil,
+	}
+}
+
+// passthroughConverter 讓 sqlmock 接受 []string 等 pgx 可直接編碼的參數
+type passthroughConverter struct{}
+
+func (passthroughConverter) ConvertValue(v any) (driver.Value, error) {
+	if cv, err := driver.DefaultParameterConverter.ConvertValue(v); err == nil {
+		return cv, nil
+	}
+	return v, nil
+}
+
+// newMockDB 建立 sqlmock 連線
+func newMockDB(t *testing.T) (*sql.DB, sqlmock.Sqlmock) {
+	t.Helper()
+	db, mock, err := sqlmock.New(sqlmock.ValueConverterOption(passthroughConverter{}))
+	if err != nil {
+		t.Fatalf("failed to open sqlmock: %v", err)
+	}
+	t.Cleanup(func() { db.Close() })
+	return db, mock
+}
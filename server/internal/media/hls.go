@@ -0,0 +1,274 @@
+package media
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"math"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+// 影片串流轉檔狀態 (media.stream_status)
+const (
+	StreamPending    = "pending"
+	StreamProcessing = "processing"
+	StreamReady      = "ready"
+	StreamFailed     = "failed"
+)
+
+// HLSRendition 定義一個 HLS 畫質階層 (H.264/AAC)
+type HLSRendition struct {
+	Name         string // 子目錄名稱，例如 "720p"
+	Height       int    // 短邊像素
+	VideoBitrate int    // kbps
+	AudioBitrate int    // kbps
+}
+
+// DefaultHLSLadder 預設的畫質階層：行動網路可退到 360p，Wi-Fi 下可播放 1080p
+var DefaultHLSLadder = []HLSRendition{
+	{Name: "360p", Height: 360, VideoBitrate: 800, AudioBitrate: 96},
+	{Name: "720p", Height: 720, VideoBitrate: 2800, AudioBitrate: 128},
+	{Name: "1080p", Height: 1080, VideoBitrate: 5000, AudioBitrate: 128},
+}
+
+// hlsSegmentSeconds 每個 TS 片段的長度
+const hlsSegmentSeconds = 6
+
+// HLSTranscoder 以背景 worker 透過 ffmpeg 產生 HLS 多畫質串流
+//
+// 上傳完成後呼叫 Enqueue；佇列已滿時項目保持 pending，由 ResumePending 補做
+type HLSTranscoder struct {
+	Service *Service
+	Ladder  []HLSRendition
+	Workers int
+
+	jobs chan string
+}
+
+func NewHLSTranscoder(s *Service, workers int) *HLSTranscoder {
+	if workers < 1 {
+		workers = 1
+	}
+	return &HLSTranscoder{
+		Service: s,
+		Ladder:  DefaultHLSLadder,
+		Workers: workers,
+		jobs:    make(chan string, 256),
+	}
+}
+
+// Start 啟動 worker，ctx 結束時停止
+func (t *HLSTranscoder) Start(ctx context.Context) {
+	for i := 0; i < t.Workers; i++ {
+		go func() {
+			for {
+				select {
+				case <-ctx.Done():
+					return
+				case id := <-t.jobs:
+					if err := t.process(ctx, id); err != nil {
+						fmt.Printf("HLS transcode failed (media: %s): %v\n", id, err)
+					}
+				}
+			}
+		}()
+	}
+}
+
+// Enqueue 加入轉檔佇列 (不阻塞)
+func (t *HLSTranscoder) Enqueue(mediaID string) {
+	select {
+	case t.jobs <- mediaID:
+	default:
+		fmt.Printf("HLS queue full, media %s stays pending\n", mediaID)
+	}
+}
+
+// ResumePending 將中斷的 processing 重設為 pending，並重新排入所有 pending 項目
+// 應於伺服器啟動時呼叫
+func (t *HLSTranscoder) ResumePending(ctx context.Context) error {
+	db := t.Service.DB
+	if _, err := db.ExecContext(ctx, `UPDATE media SET stream_status = $1 WHERE stream_status = $2`, StreamPending, StreamProcessing); err != nil {
+		return fmt.Errorf("failed to reset processing streams: %w", err)
+	}
+
+	rows, err := db.QueryContext(ctx, `SELECT id FROM media WHERE stream_status = $1 ORDER BY uploaded_at`, StreamPending)
+	if err != nil {
+		return fmt.Errorf("failed to query pending streams: %w", err)
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var id string
+		if err := rows.Scan(&id); err != nil {
+			return fmt.Errorf("failed to scan pending stream: %w", err)
+		}
+		t.Enqueue(id)
+	}
+	return rows.Err()
+}
+
+// process 轉檔單一影片；以 UPDATE ... WHERE stream_status = pending 搶占，避免重複處理
+func (t *HLSTranscoder) process(ctx context.Context, mediaID string) error {
+	s := t.Service
+
+	var storagePath string
+	var width, height int
+	claim := `
+		UPDATE media SET stream_status = $2
+		WHERE id = $1 AND stream_status = $3
+		RETURNING storage_path, COALESCE(width, 0), COALESCE(height, 0)
+	`
+	err := s.DB.QueryRowContext(ctx, claim, mediaID, StreamProcessing, StreamPending).Scan(&storagePath, &width, &height)
+	if err == sql.ErrNoRows {
+		return nil // 已被其他 worker 處理或已刪除
+	}
+	if err != nil {
+		return fmt.Errorf("failed to claim media: %w", err)
+	}
+
+	status := StreamReady
+	if err := t.transcode(ctx, mediaID, filepath.Join(s.UploadDir, storagePath), width, height); err != nil {
+		status = StreamFailed
+		if ctx.Err() != nil {
+			// 伺服器關閉時 ffmpeg 被終止，不是影片本身的問題：放回 pending，下次啟動由 ResumePending 重新轉檔
+			status = StreamPending
+		} else {
+			fmt.Printf("HLS transcode error (media: %s): %v\n", mediaID, err)
+		}
+	}
+
+	// 使用獨立 context：即使伺服器正在關閉也要寫回最終狀態
+	updateCtx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	if _, err := s.DB.ExecContext(updateCtx, `UPDATE media SET stream_status = $2 WHERE id = $1`, mediaID, status); err != nil {
+		return fmt.Errorf("failed to update stream status: %w", err)
+	}
+	return nil
+}
+
+// transcode 產生所有畫質階層與 master playlist
+// 先寫入暫存目錄，全部成功後再搬移，確保播放端不會讀到半成品
+func (t *HLSTranscoder) transcode(ctx context.Context, mediaID, srcPath string, width, height int) error {
+	finalDir := t.Service.hlsDir(mediaID)
+	tmpDir := finalDir + ".tmp"
+	if err := os.RemoveAll(tmpDir); err != nil {
+		return err
+	}
+	if err := os.MkdirAll(tmpDir, 0755); err != nil {
+		return fmt.Errorf("failed to create hls directory: %w", err)
+	}
+	defer os.RemoveAll(tmpDir)
+
+	ladder := selectRenditions(t.Ladder, width, height)
+	var master strings.Builder
+	master.WriteString("#EXTM3U\n#EXT-X-VERSION:3\n")
+
+	for _, r := range ladder {
+		outDir := filepath.Join(tmpDir, r.Name)
+		if err := os.MkdirAll(outDir, 0755); err != nil {
+			return err
+		}
+		if err := runFFmpegHLS(ctx, srcPath, outDir, r); err != nil {
+			return fmt.Errorf("rendition %s: %w", r.Name, err)
+		}
+
+		bandwidth := (r.VideoBitrate + r.AudioBitrate) * 1000
+		master.WriteString(fmt.Sprintf("#EXT-X-STREAM-INF:BANDWIDTH=%d", bandwidth))
+		if w, h := scaledResolution(width, height, r.Height); w > 0 {
+			master.WriteString(fmt.Sprintf(",RESOLUTION=%dx%d", w, h))
+		}
+		master.WriteString("\n" + r.Name + "/index.m3u8\n")
+	}
+
+	if err := os.WriteFile(filepath.Join(tmpDir, "master.m3u8"), []byte(master.String()), 0644); err != nil {
+		return fmt.Errorf("failed to write master playlist: %w", err)
+	}
+
+	if err := os.RemoveAll(finalDir); err != nil {
+		return err
+	}
+	return os.Rename(tmpDir, finalDir)
+}
+
+// runFFmpegHLS 輸出單一畫質的 VOD playlist 與 TS 片段
+func runFFmpegHLS(ctx context.Context, srcPath, outDir string, r HLSRendition) error {
+	// 以短邊縮放至目標高度 (直式影片同樣適用)，並保持偶數尺寸
+	scale := fmt.Sprintf("scale='if(gt(iw,ih),-2,%d)':'if(gt(iw,ih),%d,-2)'", r.Height, r.Height)
+	gop := fmt.Sprintf("%d", hlsSegmentSeconds*30)
+
+	cmd := exec.CommandContext(ctx, "ffmpeg",
+		"-v", "error",
+		"-y",
+		"-i", srcPath,
+		"-map", "0:v:0",
+		"-map", "0:a:0?", // 沒有音軌的影片也能轉檔
+		"-vf", scale,
+		"-c:v", "libx264",
+		"-preset", "veryfast",
+		"-profile:v", "main",
+		"-pix_fmt", "yuv420p",
+		"-b:v", fmt.Sprintf("%dk", r.VideoBitrate),
+		"-maxrate", fmt.Sprintf("%dk", r.VideoBitrate*107/100),
+		"-bufsize", fmt.Sprintf("%dk", r.VideoBitrate*2),
+		"-g", gop,
+		"-keyint_min", gop,
+		"-sc_threshold", "0",
+		"-c:a", "aac",
+		"-b:a", fmt.Sprintf("%dk", r.AudioBitrate),
+		"-ac", "2",
+		"-f", "hls",
+		"-hls_time", fmt.Sprintf("%d", hlsSegmentSeconds),
+		"-hls_playlist_type", "vod",
+		"-hls_segment_filename", filepath.Join(outDir, "seg_%03d.ts"),
+		filepath.Join(outDir, "index.m3u8"),
+	)
+
+	if out, err := cmd.CombinedOutput(); err != nil {
+		return fmt.Errorf("ffmpeg execution failed: %w: %s", err, strings.TrimSpace(string(out)))
+	}
+	return nil
+}
+
+// selectRenditions 排除高於原始解析度的階層 (至少保留最低的一階)
+func selectRenditions(ladder []HLSRendition, width, height int) []HLSRendition {
+	short := width
+	if height < short {
+		short = height
+	}
+	if short <= 0 {
+		return ladder[:1]
+	}
+
+	selected := []HLSRendition{}
+	for _, r := range ladder {
+		if r.Height <= short {
+			selected = append(selected, r)
+		}
+	}
+	if len(selected) == 0 {
+		selected = ladder[:1]
+	}
+	return selected
+}
+
+// scaledResolution 計算短邊縮放至 target 後的寬高 (與 ffmpeg -2 相同取偶數)
+func scaledResolution(width, height, target int) (int, int) {
+	if width <= 0 || height <= 0 {
+		return 0, 0
+	}
+	even := func(v float64) int { return int(math.Round(v/2)) * 2 }
+	if width > height {
+		return even(float64(width) * float64(target) / float64(height)), target
+	}
+	return target, even(float64(height) * float64(target) / float64(width))
+}
+
+// hlsDir 回傳媒體 HLS 輸出目錄
+func (s *Service) hlsDir(mediaID string) string {
+	return filepath.Join(s.UploadDir, "hls", mediaID)
+}
@@ -0,0 +1,65 @@
+package media
+
+import (
+	"net/http"
+	"path/filepath"
+	"regexp"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+)
+
+// hlsFilePattern 只允許 master playlist、variant playlist 與 TS 片段，防止路徑穿越
+var hlsFilePattern = regexp.MustCompile(`^(master\.m3u8|[0-9]+p/(index\.m3u8|seg_[0-9]+\.ts))$`)
+
+// StreamHandler 提供 HLS 串流檔案
+//
+// GET /media/:id/hls/*file (例如 master.m3u8、720p/index.m3u8、720p/seg_000.ts)
+// 每一個 playlist 與片段請求都會重新驗證存取權 (擁有者或共享相簿成員)
+func (h *Handler) StreamHandler(c *gin.Context) {
+	userID := c.MustGet("userID").(string)
+	mediaID := c.Param("id")
+	file := strings.TrimPrefix(c.Param("file"), "/")
+
+	if mediaID == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "missing media id"})
+		return
+	}
+	if !hlsFilePattern.MatchString(file) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid stream file"})
+		return
+	}
+
+	media, err := h.Service.GetAccessibleByID(c.Request.Context(), userID, mediaID)
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "media not found"})
+		return
+	}
+
+	// 轉檔完成後不再變動，與原始檔相同採不可變快取
+	h.serveStream(c, media, media.FileHash, file, immutableCacheControl)
+}
+
+// serveStream 提供已驗證存取權的媒體的 HLS 檔案 (file 須符合 hlsFilePattern)，以 etagBase 組成 ETag，
+// 並以 cacheControl 設定快取標頭
+func (h *Handler) serveStream(c *gin.Context, media *Media, etagBase, file, cacheControl string) {
+	if media.StreamStatus != StreamReady {
+		status := media.StreamStatus
+		if status == "" {
+			status = "unavailable"
+		}
+		c.JSON(http.StatusNotFound, gin.H{"error": "stream not ready", "stream_status": status})
+		return
+	}
+
+	c.Header("Cache-Control", cacheControl)
+	if notModified(c, strongETag(etagBase, "hls", file), media.UploadedAt) {
+		return
+	}
+	if strings.HasSuffix(file, ".m3u8") {
+		c.Header("Content-Type", "application/vnd.apple.mpegurl")
+	} else {
+		c.Header("Content-Type", "video/mp2t")
+	}
+	serveFile(c, filepath.Join(h.Service.hlsDir(media.ID), filepath.FromSlash(file)), media.UploadedAt)
+}
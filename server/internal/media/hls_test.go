@@ -0,0 +1,80 @@
+package media
+
+import (
+	"context"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+)
+
+func TestSelectRenditions(t *testing.T) {
+	tests := []struct {
+		name          string
+		width, height int
+		want          []string
+	}{
+		{"4K landscape", 3840, 2160, []string{"360p", "720p", "1080p"}},
+		{"720p portrait", 720, 1280, []string{"360p", "720p"}},
+		{"tiny", 320, 240, []string{"360p"}},
+		{"unknown size", 0, 0, []string{"360p"}},
+	}
+	for _, tt := range tests {
+		got := selectRenditions(DefaultHLSLadder, tt.width, tt.height)
+		if len(got) != len(tt.want) {
+			t.Errorf("%s: expected %d renditions, got %d", tt.name, len(tt.want), len(got))
+			continue
+		}
+		for i, r := range got {
+			if r.Name != tt.want[i] {
+				t.Errorf("%s: expected %s at %d, got %s", tt.name, tt.want[i], i, r.Name)
+			}
+		}
+	}
+}
+
+func TestScaledResolution(t *testing.T) {
+	if w, h := scaledResolution(3840, 2160, 720); w != 1280 || h != 720 {
+		t.Errorf("expected 1280x720, got %dx%d", w, h)
+	}
+	if w, h := scaledResolution(1080, 1920, 360); w != 360 || h != 640 {
+		t.Errorf("expected 360x640, got %dx%d", w, h)
+	}
+}
+
+// 轉檔途中伺服器關閉 (ffmpeg 被終止) 時放回 pending，下次啟動重新轉檔，而不是永久標記為失敗
+func TestHLSTranscoderProcessInterrupted(t *testing.T) {
+	// 以不會結束的假 ffmpeg 模擬轉檔中
+	sleep, err := exec.LookPath("sleep")
+	if err != nil {
+		t.Skip("sleep unavailable")
+	}
+	bin := t.TempDir()
+	if err := os.WriteFile(filepath.Join(bin, "ffmpeg"), []byte("#!/bin/sh\nexec "+sleep+" 10\n"), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	t.Setenv("PATH", bin)
+
+	db, mock := newMockDB(t)
+	tr := NewHLSTranscoder(&Service{DB: db, UploadDir: t.TempDir()}, 1)
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	mock.ExpectQuery(`UPDATE media SET stream_status = \$2\s+WHERE id = \$1 AND stream_status = \$3`).
+		WithArgs("m-1", StreamProcessing, StreamPending).
+		WillReturnRows(sqlmock.NewRows([]string{"storage_path", "width", "height"}).AddRow("a.mp4", 1920, 1080))
+	mock.ExpectExec(`UPDATE media SET stream_status = \$2 WHERE id = \$1`).
+		WithArgs("m-1", StreamPending).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+
+	time.AfterFunc(100*time.Millisecond, cancel)
+	if err := tr.process(ctx, "m-1"); err != nil {
+		t.Fatalf("process failed: %v", err)
+	}
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Error(err)
+	}
+}
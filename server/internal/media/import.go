@@ -0,0 +1,350 @@
+package media
+
+import (
+	"context"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+)
+
+// ImportProgress 匯入進度，每處理完一個檔案回報一次
+type ImportProgress struct {
+	Total    int    `json:"total"` // 找到的媒體檔案數
+	Done     int    `json:"done"`
+	Imported int    `json:"imported"` // dry run 時為將會匯入的數量
+	Skipped  int    `json:"skipped"`  // 已存在 (相同 Hash) 或不需匯入
+	Failed   int    `json:"failed"`
+	Albums   int    `json:"albums,omitempty"` // Takeout 匯入新建立的相簿數
+	Current  string `json:"current"`
+}
+
+// ImportFailure 匯入失敗的檔案
+type ImportFailure struct {
+	Path  string `json:"path"`
+	Error string `json:"error"`
+}
+
+// ImportSummary 匯入結果
+type ImportSummary struct {
+	ImportProgress
+	Failures []ImportFailure `json:"failures"`
+}
+
+// record 累計單一檔案的結果 (status 為 UploadResult.Status)
+func (sum *ImportSummary) record(rel, status string, err error) {
+	switch {
+	case err != nil:
+		sum.Failed++
+		sum.Failures = append(sum.Failures, ImportFailure{Path: rel, Error: err.Error()})
+	case status == "created":
+		sum.Imported++
+	default:
+		sum.Skipped++
+	}
+	sum.Done++
+	sum.Current = rel
+}
+
+// importMediaTypes 伺服器端匯入支援的副檔名 (其他檔案略過)
+// 不依賴系統的 mime.types，容器映像中不一定存在
+var importMediaTypes = map[string]string{
+	".jpg":  "image/jpeg",
+	".jpeg": "image/jpeg",
+	".png":  "image/png",
+	".gif":  "image/gif",
+	".webp": "image/webp",
+	".heic": "image/heic",
+	".heif": "image/heif",
+	".avif": "image/avif",
+	".tif":  "image/tiff",
+	".tiff": "image/tiff",
+	".dng":  "image/x-adobe-dng",
+	".mp4":  "video/mp4",
+	".m4v":  "video/x-m4v",
+	".mov":  "video/quicktime",
+	".3gp":  "video/3gpp",
+	".avi":  "video/x-msvideo",
+	".mkv":  "video/x-matroska",
+	".webm": "video/webm",
+}
+
+// isImportCandidate 是否為要匯入的檔案 (略過隱藏檔，例如 macOS 的 ._IMG_0001.JPG)
+func isImportCandidate(name string) bool {
+	return !strings.HasPrefix(name, ".") && importMediaType(name) != ""
+}
+
+// importMediaType 依副檔名判斷媒體類型；不支援的檔案回傳空字串
+func importMediaType(path string) string {
+	return importMediaTypes[strings.ToLower(filepath.Ext(path))]
+}
+
+// importFile 將伺服器上的檔案送入上傳流程；已有相同 Hash 時回傳 conflict (除非 opts.Force)
+// 來源檔案不會被修改
+func (s *Service) importFile(ctx context.Context, userID, path, filename string, opts ingestOptions) (*UploadResult, error) {
+	mimeType := importMediaType(path)
+	if mimeType == "" {
+		return nil, fmt.Errorf("unsupported file type: %s", filepath.Ext(path))
+	}
+
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open file: %w", err)
+	}
+	defer f.Close()
+
+	info, err := f.Stat()
+	if err != nil {
+		return nil, fmt.Errorf("failed to stat file: %w", err)
+	}
+	if !info.Mode().IsRegular() {
+		return nil, fmt.Errorf("not a regular file: %s", path)
+	}
+	if filename == "" {
+		filename = filepath.Base(path)
+	}
+
+	return s.ingest(ctx, userID, f, filename, mimeType, info.Size(), opts)
+}
+
+// DefaultImportConcurrency 目錄匯入預設同時處理的檔案數
+const DefaultImportConcurrency = 4
+
+// DirImportOptions 目錄匯入選項
+type DirImportOptions struct {
+	Move        bool // 匯入成功 (或已存在於圖庫中) 後刪除來源檔案與 sidecar；預設保留 (複製)
+	Concurrency int  // 同時處理的檔案數，<= 0 時使用 DefaultImportConcurrency
+	DryRun      bool // 只計算 Hash 並檢查是否已存在，不寫入任何資料
+}
+
+// ImportDirectory 遞迴匯入伺服器上的目錄，每個檔案都經過與上傳相同的流程 (Hash、去重、Metadata、儲存路徑)
+// 新建立的項目會套用檔案旁的 XMP sidecar (評等、關鍵字、說明、位置)
+// 已存在的檔案略過，因此中斷後可直接重新執行；progress 在各 worker 間依序呼叫
+// Move 時 sidecar 等到共用它的檔案 (例如 IMG_0001.JPG 與 IMG_0001.DNG 共用 IMG_0001.xmp) 全部成功後才刪除
+func (s *Service) ImportDirectory(ctx context.Context, userID, dir string, opts DirImportOptions, progress func(ImportProgress)) (*ImportSummary, error) {
+	files, err := scanImportDir(dir)
+	if err != nil {
+		return nil, err
+	}
+	workers := opts.Concurrency
+	if workers <= 0 {
+		workers = DefaultImportConcurrency
+	}
+
+	sum := &ImportSummary{Failures: []ImportFailure{}}
+	sum.Total = len(files)
+	var mu sync.Mutex
+	gate := &hashGate{inflight: map[string]chan struct{}{}, seen: map[string]bool{}}
+	var sidecars *sidecarRefs
+	if opts.Move && !opts.DryRun {
+		sidecars = newSidecarRefs(files)
+	}
+
+	jobs := make(chan string)
+	var wg sync.WaitGroup
+	for i := 0; i < workers; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for path := range jobs {
+				status, err := s.importDirFile(ctx, userID, path, opts, gate)
+				if sidecars != nil {
+					if sidecar := sidecars.done(path, err == nil); sidecar != "" {
+						if rmErr := os.Remove(sidecar); rmErr != nil {
+							err = fmt.Errorf("imported but failed to remove sidecar: %w", rmErr)
+						}
+					}
+				}
+				rel, _ := filepath.Rel(dir, path)
+
+				mu.Lock()
+				sum.record(rel, status, err)
+				if progress != nil {
+					progress(sum.ImportProgress)
+				}
+				mu.Unlock()
+			}
+		}()
+	}
+
+	for _, path := range files {
+		if ctx.Err() != nil {
+			break
+		}
+		jobs <- path
+	}
+	close(jobs)
+	wg.Wait()
+
+	sum.Current = ""
+	return sum, ctx.Err()
+}
+
+// importDirFile 匯入單一檔案；同一 Hash 同時間只處理一個，避免並行時重複建立 (後到的會被判定為已存在)
+func (s *Service) importDirFile(ctx context.Context, userID, path string, opts DirImportOptions, gate *hashGate) (string, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return "", fmt.Errorf("failed to open file: %w", err)
+	}
+	fileHash, err := hashReader(f)
+	f.Close()
+	if err != nil {
+		return "", err
+	}
+
+	release := gate.acquire(fileHash)
+	defer release()
+
+	if opts.DryRun {
+		// 目錄中重複的檔案實際匯入時只會建立一次
+		if !gate.markSeen(fileHash) {
+			return "conflict", nil
+		}
+		existingID, err := s.checkExists(ctx, userID, fileHash)
+		if err != nil {
+			return "", err
+		}
+		if existingID != "" {
+			return "conflict", nil
+		}
+		return "created", nil
+	}
+
+	result, err := s.importFile(ctx, userID, path, "", ingestOptions{FileHash: fileHash})
+	if err != nil {
+		return "", err
+	}
+	// 新建立的項目套用旁邊的 XMP sidecar；已存在的項目只在尚未套用過時補套用 (前次匯入在套用前失敗或中斷)，
+	// 不覆寫使用者之後的修改。以 sidecar 來源的標籤判斷是否套用過，沒有關鍵字的 sidecar 無法判斷，會再次套用
+	switch result.Status {
+	case "created":
+		err = s.importSidecar(ctx, userID, result.Media.ID, path)
+	case "conflict":
+		if findXMPSidecar(path) == "" {
+			break
+		}
+		var applied bool
+		if applied, err = s.hasSidecarTags(ctx, result.ExistingID); err == nil && !applied {
+			err = s.importSidecar(ctx, userID, result.ExistingID, path)
+		}
+	}
+	if err != nil {
+		return result.Status, err
+	}
+	if opts.Move {
+		if err := os.Remove(path); err != nil {
+			return result.Status, fmt.Errorf("imported but failed to remove source: %w", err)
+		}
+	}
+	return result.Status, nil
+}
+
+// sidecarRefs 目錄匯入 (Move) 時記錄各 sidecar 尚未處理完的媒體檔案數
+// 任一共用的檔案失敗時保留 sidecar，重新執行時仍可套用
+type sidecarRefs struct {
+	mu      sync.Mutex
+	of      map[string]string // 媒體檔案 → sidecar
+	pending map[string]int
+	failed  map[string]bool
+}
+
+func newSidecarRefs(files []string) *sidecarRefs {
+	r := &sidecarRefs{of: map[string]string{}, pending: map[string]int{}, failed: map[string]bool{}}
+	for _, path := range files {
+		if sidecar := findXMPSidecar(path); sidecar != "" {
+			r.of[path] = sidecar
+			r.pending[sidecar]++
+		}
+	}
+	return r
+}
+
+// done 記錄檔案的處理結果，回傳可以刪除的 sidecar (沒有 sidecar、仍有檔案未處理或曾失敗時為空字串)
+func (r *sidecarRefs) done(path string, ok bool) string {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	sidecar := r.of[path]
+	if sidecar == "" {
+		return ""
+	}
+	if !ok {
+		r.failed[sidecar] = true
+	}
+	r.pending[sidecar]--
+	if r.pending[sidecar] > 0 || r.failed[sidecar] {
+		return ""
+	}
+	return sidecar
+}
+
+// scanImportDir 列出目錄下所有要匯入的檔案 (依路徑排序)
+func scanImportDir(dir string) ([]string, error) {
+	info, err := os.Stat(dir)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open import directory: %w", err)
+	}
+	if !info.IsDir() {
+		return nil, fmt.Errorf("not a directory: %s", dir)
+	}
+
+	var files []string
+	err = filepath.WalkDir(dir, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if d.IsDir() && path != dir && strings.HasPrefix(d.Name(), ".") {
+			return filepath.SkipDir
+		}
+		if d.Type().IsRegular() && isImportCandidate(d.Name()) {
+			files = append(files, path)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to scan import directory: %w", err)
+	}
+	sort.Strings(files)
+	return files, nil
+}
+
+// hashGate 以 Hash 為鍵的互斥鎖
+type hashGate struct {
+	mu       sync.Mutex
+	inflight map[string]chan struct{}
+	seen     map[string]bool // dry run 用：本次已處理過的 Hash
+}
+
+// markSeen 記錄 Hash，第一次出現時回傳 true
+func (g *hashGate) markSeen(hash string) bool {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	if g.seen[hash] {
+		return false
+	}
+	g.seen[hash] = true
+	return true
+}
+
+// acquire 等待同一 Hash 的處理結束後取得鎖，回傳釋放函式
+func (g *hashGate) acquire(hash string) func() {
+	for {
+		g.mu.Lock()
+		ch, busy := g.inflight[hash]
+		if !busy {
+			ch = make(chan struct{})
+			g.inflight[hash] = ch
+			g.mu.Unlock()
+			return func() {
+				g.mu.Lock()
+				delete(g.inflight, hash)
+				g.mu.Unlock()
+				close(ch)
+			}
+		}
+		g.mu.Unlock()
+		<-ch
+	}
+}
@@ -0,0 +1,122 @@
+package media
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	"github.com/DATA-DOG/go-sqlmock"
+)
+
+func importTestHash(s string) string {
+	sum := sha256.Sum256([]byte(s))
+	return hex.EncodeToString(sum[:])
+}
+
+func TestScanImportDir(t *testing.T) {
+	dir := t.TempDir()
+	for _, name := range []string{"b.JPG", "a/c.mov", "a/notes.txt", "._b.JPG", ".thumbnails/d.jpg", "e.heic"} {
+		writeTakeoutFile(t, filepath.Join(dir, name), name)
+	}
+
+	files, err := scanImportDir(dir)
+	if err != nil {
+		t.Fatalf("scanImportDir failed: %v", err)
+	}
+	want := []string{filepath.Join(dir, "a/c.mov"), filepath.Join(dir, "b.JPG"), filepath.Join(dir, "e.heic")}
+	if !reflect.DeepEqual(files, want) {
+		t.Errorf("expected %v, got %v", want, files)
+	}
+}
+
+// dry run 只查詢是否已存在；目錄中重複的檔案只計算一次
+func TestImportDirectoryDryRun(t *testing.T) {
+	dir := t.TempDir()
+	writeTakeoutFile(t, filepath.Join(dir, "a.jpg"), "new")
+	writeTakeoutFile(t, filepath.Join(dir, "copy/a.jpg"), "new")
+	writeTakeoutFile(t, filepath.Join(dir, "b.jpg"), "existing")
+
+	db, mock := newMockDB(t)
+	mock.MatchExpectationsInOrder(false)
+	s := &Service{DB: db, UploadDir: t.TempDir()}
+
+	mock.ExpectQuery(`SELECT id FROM media WHERE user_id = \$1 AND file_hash = \$2`).
+		WithArgs("user-1", importTestHash("new")).
+		WillReturnRows(sqlmock.NewRows([]string{"id"}))
+	mock.ExpectQuery(`SELECT id FROM media WHERE user_id = \$1 AND file_hash = \$2`).
+		WithArgs("user-1", importTestHash("existing")).
+		WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow("media-1"))
+
+	calls := 0
+	sum, err := s.ImportDirectory(context.Background(), "user-1", dir, DirImportOptions{DryRun: true, Concurrency: 2}, func(ImportProgress) {
+		calls++
+	})
+	if err != nil {
+		t.Fatalf("ImportDirectory failed: %v", err)
+	}
+	if sum.Total != 3 || sum.Imported != 1 || sum.Skipped != 2 || sum.Failed != 0 || calls != 3 {
+		t.Errorf("unexpected summary: %+v (progress calls %d)", sum.ImportProgress, calls)
+	}
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("unmet expectations: %v", err)
+	}
+}
+
+// Move 時共用的 sidecar 等到同名的檔案全部成功後才刪除；已存在且套用過 sidecar 的項目不再套用
+func TestImportDirectoryMoveKeepsSharedSidecar(t *testing.T) {
+	dir := t.TempDir()
+	jpg, dng, xmp := filepath.Join(dir, "IMG_0001.JPG"), filepath.Join(dir, "IMG_0001.DNG"), filepath.Join(dir, "IMG_0001.xmp")
+	writeTakeoutFile(t, jpg, "jpg")
+	writeTakeoutFile(t, dng, "dng")
+	writeTakeoutFile(t, xmp, "<x:xmpmeta/>")
+
+	db, mock := newMockDB(t)
+	mock.MatchExpectationsInOrder(false)
+	s := &Service{DB: db, UploadDir: t.TempDir()}
+	opts := DirImportOptions{Move: true, Concurrency: 2}
+	expectExisting := func(content, mediaID string) {
+		mock.ExpectQuery(`SELECT id FROM media WHERE user_id = \$1 AND file_hash = \$2`).
+			WithArgs("user-1", importTestHash(content)).
+			WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(mediaID))
+		mock.ExpectQuery(`SELECT EXISTS \(SELECT 1 FROM media_tags WHERE media_id = \$1 AND source = \$2\)`).
+			WithArgs(mediaID, TagSourceSidecar).
+			WillReturnRows(sqlmock.NewRows([]string{"exists"}).AddRow(true))
+	}
+
+	expectExisting("jpg", "media-1")
+	mock.ExpectQuery(`SELECT id FROM media WHERE user_id = \$1 AND file_hash = \$2`).
+		WithArgs("user-1", importTestHash("dng")).
+		WillReturnError(errors.New("connection reset"))
+	sum, err := s.ImportDirectory(context.Background(), "user-1", dir, opts, nil)
+	if err != nil {
+		t.Fatalf("ImportDirectory failed: %v", err)
+	}
+	if sum.Skipped != 1 || sum.Failed != 1 {
+		t.Errorf("unexpected summary: %+v", sum.ImportProgress)
+	}
+	if _, err := os.Stat(jpg); !os.IsNotExist(err) {
+		t.Error("imported source should be removed")
+	}
+	if _, err := os.Stat(xmp); err != nil {
+		t.Error("sidecar shared with a failed file should be kept")
+	}
+
+	// 重新執行：剩下的檔案成功後刪除 sidecar
+	expectExisting("dng", "media-2")
+	if _, err := s.ImportDirectory(context.Background(), "user-1", dir, opts, nil); err != nil {
+		t.Fatalf("ImportDirectory failed: %v", err)
+	}
+	for _, path := range []string{dng, xmp} {
+		if _, err := os.Stat(path); !os.IsNotExist(err) {
+			t.Errorf("%s should be removed", filepath.Base(path))
+		}
+	}
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("unmet expectations: %v", err)
+	}
+}
@@ -0,0 +1,342 @@
+package media
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+// 收件資料夾處理結果 (inbox_log.status)
+const (
+	InboxImported  = "imported"
+	InboxDuplicate = "duplicate" // 已存在相同 Hash 的項目，檔案仍移至 processed/
+	InboxFailed    = "failed"
+)
+
+// 處理後的檔案移入收件資料夾下的子資料夾
+const (
+	inboxProcessedDir = "processed"
+	inboxFailedDir    = "failed"
+)
+
+// 預設的監看參數
+const (
+	DefaultInboxStableFor    = 5 * time.Second  // 檔案大小與修改時間維持不變多久才視為寫入完成
+	DefaultInboxPollInterval = 10 * time.Second // 無法使用 inotify 時的輪詢間隔
+	inboxSafetyInterval      = time.Minute      // 使用 inotify 時仍定期掃描，補上遺漏的事件與資料夾設定變更
+)
+
+// ErrInboxNotConfigured 使用者沒有設定收件資料夾
+var ErrInboxNotConfigured = errors.New("inbox folder not configured")
+
+// InboxLogEntry 收件資料夾的處理紀錄
+type InboxLogEntry struct {
+	ID        string    `json:"id"`
+	Filename  string    `json:"filename"`
+	Status    string    `json:"status"`
+	MediaID   *string   `json:"media_id"` // 匯入或重複時對應的媒體
+	Error     string    `json:"error,omitempty"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// SetInboxFolder 設定使用者的收件資料夾 (伺服器端管理指令使用)；已設定時取代
+func (s *Service) SetInboxFolder(ctx context.Context, userID, dir string) error {
+	abs, err := filepath.Abs(dir)
+	if err != nil {
+		return fmt.Errorf("invalid inbox path: %w", err)
+	}
+	if info, err := os.Stat(abs); err != nil || !info.IsDir() {
+		return fmt.Errorf("inbox path is not a directory: %s", abs)
+	}
+	_, err = s.DB.ExecContext(ctx, `
+		INSERT INTO inbox_folders (user_id, path) VALUES ($1, $2)
+		ON CONFLICT (user_id) DO UPDATE SET path = EXCLUDED.path
+	`, userID, abs)
+	if err != nil {
+		return fmt.Errorf("failed to set inbox folder: %w", err)
+	}
+	return nil
+}
+
+// RemoveInboxFolder 停止監看使用者的收件資料夾 (檔案與處理紀錄保留)
+func (s *Service) RemoveInboxFolder(ctx context.Context, userID string) error {
+	res, err := s.DB.ExecContext(ctx, `DELETE FROM inbox_folders WHERE user_id = $1`, userID)
+	if err != nil {
+		return fmt.Errorf("failed to remove inbox folder: %w", err)
+	}
+	if n, err := res.RowsAffected(); err == nil && n == 0 {
+		return ErrInboxNotConfigured
+	}
+	return nil
+}
+
+// inboxFolders 讀取所有收件資料夾 (路徑 → 使用者 ID)
+func (s *Service) inboxFolders(ctx context.Context) (map[string]string, error) {
+	rows, err := s.DB.QueryContext(ctx, `SELECT user_id, path FROM inbox_folders`)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query inbox folders: %w", err)
+	}
+	defer rows.Close()
+
+	folders := map[string]string{}
+	for rows.Next() {
+		var userID, path string
+		if err := rows.Scan(&userID, &path); err != nil {
+			return nil, fmt.Errorf("failed to scan inbox folder: %w", err)
+		}
+		folders[path] = userID
+	}
+	return folders, rows.Err()
+}
+
+// ListInboxLog 取得使用者的收件資料夾處理紀錄 (新到舊)
+func (s *Service) ListInboxLog(ctx context.Context, userID string, limit, offset int) ([]*InboxLogEntry, error) {
+	rows, err := s.DB.QueryContext(ctx, `
+		SELECT id, filename, status, media_id, error, created_at
+		FROM inbox_log
+		WHERE user_id = $1
+		ORDER BY created_at DESC, id
+		LIMIT $2 OFFSET $3
+	`, userID, limit, offset)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query inbox log: %w", err)
+	}
+	defer rows.Close()
+
+	list := []*InboxLogEntry{}
+	for rows.Next() {
+		e := &InboxLogEntry{}
+		if err := rows.Scan(&e.ID, &e.Filename, &e.Status, &e.MediaID, &e.Error, &e.CreatedAt); err != nil {
+			return nil, fmt.Errorf("failed to scan inbox log: %w", err)
+		}
+		list = append(list, e)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to iterate inbox log: %w", err)
+	}
+	return list, nil
+}
+
+// inboxFile 觀察中的檔案
+type inboxFile struct {
+	size    int64
+	modTime time.Time
+	since   time.Time // 目前大小與修改時間第一次被觀察到的時間
+}
+
+// unmovedFile 已處理並寫入紀錄但無法移走的檔案
+type unmovedFile struct {
+	size    int64
+	modTime time.Time
+	target  string // 要移入的子資料夾
+}
+
+// InboxWatcher 監看收件資料夾 (只處理第一層的檔案)，檔案寫入完成後匯入並移至 processed/ 或 failed/
+//
+// Linux 上以 inotify 即時觸發掃描，其他平台或 inotify 無法使用時改為輪詢；
+// 兩種模式都以「大小與修改時間維持 StableFor 不變」判定寫入完成，不依賴事件本身
+type InboxWatcher struct {
+	s            *Service
+	StableFor    time.Duration
+	PollInterval time.Duration
+
+	now     func() time.Time
+	pending map[string]*inboxFile  // 路徑 → 觀察狀態
+	unmoved map[string]unmovedFile // 路徑 → 移動目標；之後的掃描只重試移動，不重新匯入或寫入紀錄
+}
+
+func NewInboxWatcher(s *Service) *InboxWatcher {
+	return &InboxWatcher{
+		s:            s,
+		StableFor:    DefaultInboxStableFor,
+		PollInterval: DefaultInboxPollInterval,
+		now:          time.Now,
+		pending:      map[string]*inboxFile{},
+		unmoved:      map[string]unmovedFile{},
+	}
+}
+
+// Start 在背景監看，ctx 結束時停止
+func (w *InboxWatcher) Start(ctx context.Context) {
+	go w.run(ctx)
+}
+
+func (w *InboxWatcher) run(ctx context.Context) {
+	n, err := newInboxNotifier()
+	if err != nil {
+		fmt.Printf("inbox: inotify unavailable, polling every %s: %v\n", w.PollInterval, err)
+	} else {
+		defer n.Close()
+	}
+
+	timer := time.NewTimer(0)
+	defer timer.Stop()
+	for {
+		var events <-chan struct{}
+		if n != nil {
+			events = n.Events()
+		}
+		select {
+		case <-ctx.Done():
+			return
+		case <-events:
+			if !timer.Stop() {
+				select {
+				case <-timer.C:
+				default:
+				}
+			}
+		case <-timer.C:
+		}
+
+		folders, err := w.s.inboxFolders(ctx)
+		if err != nil {
+			fmt.Printf("inbox: %v\n", err)
+		} else {
+			if n != nil {
+				n.Watch(folders)
+			}
+			w.scan(ctx, folders)
+		}
+
+		// 有尚未穩定的檔案時需在 StableFor 後重新檢查 (檔案不再變動時不會有新的事件)
+		interval := w.PollInterval
+		if n != nil {
+			interval = inboxSafetyInterval
+		}
+		if len(w.pending) > 0 {
+			interval = min(interval, w.StableFor)
+		}
+		timer.Reset(interval)
+	}
+}
+
+// scan 檢查所有收件資料夾，處理已穩定的檔案
+func (w *InboxWatcher) scan(ctx context.Context, folders map[string]string) {
+	seen := map[string]bool{}
+	for dir, userID := range folders {
+		entries, err := os.ReadDir(dir)
+		if err != nil {
+			fmt.Printf("inbox: failed to read %s: %v\n", dir, err)
+			continue
+		}
+		for _, entry := range entries {
+			if ctx.Err() != nil {
+				return
+			}
+			// 略過子資料夾 (包含 processed/ 與 failed/) 與隱藏檔 (多數程式寫入時使用的暫存檔)
+			if !entry.Type().IsRegular() || strings.HasPrefix(entry.Name(), ".") {
+				continue
+			}
+			path := filepath.Join(dir, entry.Name())
+			seen[path] = true
+			if w.retryMove(path) {
+				continue
+			}
+			if w.stable(path) {
+				delete(w.pending, path)
+				w.process(ctx, userID, dir, path)
+			}
+		}
+	}
+	// 已被移走或刪除的檔案不再追蹤
+	for path := range w.pending {
+		if !seen[path] {
+			delete(w.pending, path)
+		}
+	}
+	for path := range w.unmoved {
+		if !seen[path] {
+			delete(w.unmoved, path)
+		}
+	}
+}
+
+// retryMove 檔案已處理過但先前無法移走時重試移動並回傳 true
+// 檔案內容已改變 (大小或修改時間不同) 時視為新檔案，回傳 false 重新處理
+func (w *InboxWatcher) retryMove(path string) bool {
+	u, ok := w.unmoved[path]
+	if !ok {
+		return false
+	}
+	info, err := os.Stat(path)
+	if err != nil || info.Size() != u.size || !info.ModTime().Equal(u.modTime) {
+		delete(w.unmoved, path)
+		return false
+	}
+	if err := moveInboxFile(path, u.target); err == nil {
+		delete(w.unmoved, path)
+	}
+	return true
+}
+
+// stable 更新檔案的觀察狀態，大小與修改時間維持 StableFor 不變時回傳 true
+func (w *InboxWatcher) stable(path string) bool {
+	info, err := os.Stat(path)
+	if err != nil {
+		delete(w.pending, path)
+		return false
+	}
+	now := w.now()
+	f, ok := w.pending[path]
+	if !ok || f.size != info.Size() || !f.modTime.Equal(info.ModTime()) {
+		w.pending[path] = &inboxFile{size: info.Size(), modTime: info.ModTime(), since: now}
+		return false
+	}
+	return now.Sub(f.since) >= w.StableFor
+}
+
+// process 匯入單一檔案、移至 processed/ 或 failed/ 並寫入處理紀錄
+func (w *InboxWatcher) process(ctx context.Context, userID, dir, path string) {
+	name := filepath.Base(path)
+	status, target := InboxImported, inboxProcessedDir
+	var mediaID *string
+	var errMsg string
+
+	result, err := w.s.importFile(ctx, userID, path, "", ingestOptions{})
+	switch {
+	case err != nil:
+		status, target, errMsg = InboxFailed, inboxFailedDir, err.Error()
+	case result.Status == "conflict":
+		status, mediaID = InboxDuplicate, &result.ExistingID
+	default:
+		mediaID = &result.Media.ID
+	}
+
+	info, statErr := os.Stat(path)
+	if err := moveInboxFile(path, filepath.Join(dir, target)); err != nil {
+		// 無法移走時保留原地並記住，之後的掃描只重試移動 (不重複匯入與寫入紀錄)
+		fmt.Printf("inbox: %v\n", err)
+		if statErr == nil {
+			w.unmoved[path] = unmovedFile{size: info.Size(), modTime: info.ModTime(), target: filepath.Join(dir, target)}
+		}
+	}
+
+	_, err = w.s.DB.ExecContext(ctx, `
+		INSERT INTO inbox_log (user_id, filename, status, media_id, error) VALUES ($1, $2, $3, $4, $5)
+	`, userID, name, status, mediaID, errMsg)
+	if err != nil {
+		fmt.Printf("inbox: failed to record %s: %v\n", name, err)
+	}
+}
+
+// moveInboxFile 將檔案移入子資料夾；同名檔案已存在時加上時間戳記
+func moveInboxFile(path, targetDir string) error {
+	if err := os.MkdirAll(targetDir, 0755); err != nil {
+		return fmt.Errorf("failed to create %s: %w", targetDir, err)
+	}
+	name := filepath.Base(path)
+	target := filepath.Join(targetDir, name)
+	if _, err := os.Stat(target); err == nil {
+		ext := filepath.Ext(name)
+		target = filepath.Join(targetDir, fmt.Sprintf("%s_%d%s", strings.TrimSuffix(name, ext), time.Now().UnixNano(), ext))
+	}
+	if err := os.Rename(path, target); err != nil {
+		return fmt.Errorf("failed to move %s: %w", name, err)
+	}
+	return nil
+}
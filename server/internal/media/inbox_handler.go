@@ -0,0 +1,22 @@
+package media
+
+import (
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+)
+
+// ListInboxLogHandler 收件資料夾的處理紀錄 (GET /inbox/log?page=1&limit=20)
+// 收件資料夾由伺服器管理者以 gallery-admin inbox 設定
+func (h *Handler) ListInboxLogHandler(c *gin.Context) {
+	userID := c.MustGet("userID").(string)
+	limit, offset := parsePagination(c)
+
+	list, err := h.Service.ListInboxLog(c.Request.Context(), userID, limit, offset)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	jsonWithETag(c, http.StatusOK, list)
+}
@@ -0,0 +1,87 @@
+//go:build linux
+
+package media
+
+import (
+	"fmt"
+	"os"
+	"sync"
+	"syscall"
+)
+
+// inboxWatchMask 只需要知道「可能有新檔案」，寫入過程中的 IN_MODIFY 不監看 (由穩定判定處理)
+const inboxWatchMask = syscall.IN_CREATE | syscall.IN_CLOSE_WRITE | syscall.IN_MOVED_TO
+
+// inboxNotifier 以 inotify 監看收件資料夾
+type inboxNotifier struct {
+	fd     int // 直接使用原始 fd；呼叫 f.Fd() 會將 fd 改回阻塞模式
+	f      *os.File
+	events chan struct{}
+
+	mu      sync.Mutex
+	watches map[string]int // 資料夾 → watch descriptor
+}
+
+func newInboxNotifier() (*inboxNotifier, error) {
+	fd, err := syscall.InotifyInit1(syscall.IN_CLOEXEC | syscall.IN_NONBLOCK)
+	if err != nil {
+		return nil, fmt.Errorf("inotify_init1: %w", err)
+	}
+	// 非阻塞的 fd 交由 runtime poller 處理，Close 時 Read 會立即返回
+	n := &inboxNotifier{
+		fd:      fd,
+		f:       os.NewFile(uintptr(fd), "inotify"),
+		events:  make(chan struct{}, 1),
+		watches: map[string]int{},
+	}
+	go n.read()
+	return n, nil
+}
+
+// Events 有事件時送出訊號 (多個事件合併為一個)
+func (n *inboxNotifier) Events() <-chan struct{} {
+	return n.events
+}
+
+// Watch 同步監看的資料夾 (新增的加入監看，已移除的取消監看)
+func (n *inboxNotifier) Watch(folders map[string]string) {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+
+	for dir := range folders {
+		if _, ok := n.watches[dir]; ok {
+			continue
+		}
+		wd, err := syscall.InotifyAddWatch(n.fd, dir, inboxWatchMask)
+		if err != nil {
+			// 資料夾暫時不存在時由定期掃描補上
+			fmt.Printf("inbox: failed to watch %s: %v\n", dir, err)
+			continue
+		}
+		n.watches[dir] = wd
+	}
+	for dir, wd := range n.watches {
+		if _, ok := folders[dir]; !ok {
+			syscall.InotifyRmWatch(n.fd, uint32(wd))
+			delete(n.watches, dir)
+		}
+	}
+}
+
+// read 讀取事件；內容不需解析 (包含佇列溢位)，一律觸發重新掃描
+func (n *inboxNotifier) read() {
+	buf := make([]byte, 64*(syscall.SizeofInotifyEvent+syscall.NAME_MAX+1))
+	for {
+		if _, err := n.f.Read(buf); err != nil {
+			return
+		}
+		select {
+		case n.events <- struct{}{}:
+		default:
+		}
+	}
+}
+
+func (n *inboxNotifier) Close() error {
+	return n.f.Close()
+}
@@ -0,0 +1,16 @@
+//go:build !linux
+
+package media
+
+import "errors"
+
+// inboxNotifier 非 Linux 平台不支援 inotify，InboxWatcher 改為輪詢
+type inboxNotifier struct{}
+
+func newInboxNotifier() (*inboxNotifier, error) {
+	return nil, errors.New("inotify is only supported on linux")
+}
+
+func (n *inboxNotifier) Events() <-chan struct{}         { return nil }
+func (n *inboxNotifier) Watch(folders map[string]string) {}
+func (n *inboxNotifier) Close() error                    { return nil }
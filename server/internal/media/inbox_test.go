@@ -0,0 +1,159 @@
+package media
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"runtime"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+)
+
+// 檔案大小與修改時間需維持 StableFor 不變才會處理
+func TestInboxWatcherStable(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "scan.jpg")
+	writeTakeoutFile(t, path, "part")
+
+	now := time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC)
+	w := NewInboxWatcher(&Service{})
+	w.now = func() time.Time { return now }
+
+	if w.stable(path) {
+		t.Fatal("first observation must not be stable")
+	}
+	now = now.Add(w.StableFor / 2)
+	if w.stable(path) {
+		t.Fatal("file must not be stable before StableFor")
+	}
+
+	// 仍在寫入：大小改變時重新計時
+	writeTakeoutFile(t, path, "part and more")
+	now = now.Add(w.StableFor)
+	if w.stable(path) {
+		t.Fatal("growing file must not be stable")
+	}
+	now = now.Add(w.StableFor)
+	if !w.stable(path) {
+		t.Fatal("file should be stable after StableFor without changes")
+	}
+}
+
+// 重複的檔案移至 processed/，無法匯入的檔案移至 failed/，兩者都寫入處理紀錄
+func TestInboxWatcherProcess(t *testing.T) {
+	dir := t.TempDir()
+	writeTakeoutFile(t, filepath.Join(dir, "dup.jpg"), "existing")
+	writeTakeoutFile(t, filepath.Join(dir, "notes.pdf"), "not media")
+	writeTakeoutFile(t, filepath.Join(dir, ".dup.jpg.part"), "temp")
+	writeTakeoutFile(t, filepath.Join(dir, inboxProcessedDir, "dup.jpg"), "older")
+
+	db, mock := newMockDB(t)
+	s := &Service{DB: db, UploadDir: t.TempDir()}
+	now := time.Now()
+	w := NewInboxWatcher(s)
+	w.now = func() time.Time { return now }
+	folders := map[string]string{dir: "user-1"}
+
+	w.scan(context.Background(), folders)
+	if len(w.pending) != 2 {
+		t.Fatalf("expected 2 pending files, got %d", len(w.pending))
+	}
+
+	mock.ExpectQuery(`SELECT id FROM media WHERE user_id = \$1 AND file_hash = \$2`).
+		WithArgs("user-1", sqlmock.AnyArg()).
+		WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow("media-1"))
+	mock.ExpectExec(`INSERT INTO inbox_log`).
+		WithArgs("user-1", "dup.jpg", InboxDuplicate, "media-1", "").
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectExec(`INSERT INTO inbox_log`).
+		WithArgs("user-1", "notes.pdf", InboxFailed, nil, "unsupported file type: .pdf").
+		WillReturnResult(sqlmock.NewResult(0, 1))
+
+	now = now.Add(w.StableFor)
+	w.scan(context.Background(), folders)
+
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("unmet expectations: %v", err)
+	}
+	if len(w.pending) != 0 {
+		t.Errorf("expected no pending files, got %d", len(w.pending))
+	}
+	entries, _ := os.ReadDir(filepath.Join(dir, inboxProcessedDir))
+	if len(entries) != 2 {
+		t.Errorf("expected duplicate to be moved next to the older file, got %d entries", len(entries))
+	}
+	if _, err := os.Stat(filepath.Join(dir, inboxFailedDir, "notes.pdf")); err != nil {
+		t.Errorf("expected failed file to be moved: %v", err)
+	}
+	if _, err := os.Stat(filepath.Join(dir, ".dup.jpg.part")); err != nil {
+		t.Errorf("hidden temp file must be left alone: %v", err)
+	}
+}
+
+// 無法移走的檔案只處理一次：之後的掃描只重試移動，不重新匯入或重複寫入紀錄
+func TestInboxWatcherUnmovableFile(t *testing.T) {
+	dir := t.TempDir()
+	writeTakeoutFile(t, filepath.Join(dir, "notes.pdf"), "not media")
+	// failed/ 被指向不存在路徑的連結佔用，無法建立資料夾 (掃描時略過非一般檔案)
+	if err := os.Symlink(filepath.Join(dir, "missing"), filepath.Join(dir, inboxFailedDir)); err != nil {
+		t.Skipf("symlink unavailable: %v", err)
+	}
+
+	db, mock := newMockDB(t)
+	s := &Service{DB: db, UploadDir: t.TempDir()}
+	now := time.Now()
+	w := NewInboxWatcher(s)
+	w.now = func() time.Time { return now }
+	folders := map[string]string{dir: "user-1"}
+
+	mock.ExpectExec(`INSERT INTO inbox_log`).
+		WithArgs("user-1", "notes.pdf", InboxFailed, nil, "unsupported file type: .pdf").
+		WillReturnResult(sqlmock.NewResult(0, 1))
+
+	w.scan(context.Background(), folders)
+	for i := 0; i < 3; i++ {
+		now = now.Add(w.StableFor)
+		w.scan(context.Background(), folders)
+	}
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("unmet expectations: %v", err)
+	}
+	if _, ok := w.unmoved[filepath.Join(dir, "notes.pdf")]; !ok {
+		t.Fatal("expected file to be remembered as unmoved")
+	}
+
+	// 佔用解除後移入 failed/
+	if err := os.Remove(filepath.Join(dir, inboxFailedDir)); err != nil {
+		t.Fatal(err)
+	}
+	w.scan(context.Background(), folders)
+	if _, err := os.Stat(filepath.Join(dir, inboxFailedDir, "notes.pdf")); err != nil {
+		t.Errorf("expected file to be moved on retry: %v", err)
+	}
+	if len(w.unmoved) != 0 {
+		t.Errorf("expected no unmoved files, got %d", len(w.unmoved))
+	}
+}
+
+func TestInboxNotifier(t *testing.T) {
+	if runtime.GOOS != "linux" {
+		t.Skip("inotify is only supported on linux")
+	}
+	n, err := newInboxNotifier()
+	if err != nil {
+		t.Skipf("inotify unavailable: %v", err)
+	}
+	defer n.Close()
+
+	dir := t.TempDir()
+	n.Watch(map[string]string{dir: "user-1"})
+	writeTakeoutFile(t, filepath.Join(dir, "new.jpg"), "data")
+
+	select {
+	case <-n.Events():
+	case <-time.After(2 * time.Second):
+		t.Fatal("expected an inotify event")
+	}
+}
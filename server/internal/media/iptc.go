@@ -0,0 +1,115 @@
+package media
+
+import (
+	"bytes"
+	"encoding/binary"
+	"unicode/utf8"
+)
+
+// IPTC-IIM 常數
+const (
+	photoshopIRBHeader = "Photoshop 3.0\x00"
+	iptcResourceID     = 0x0404 // Photoshop IRB 中的 IPTC-NAA 資源
+	iptcTagMarker      = 0x1C
+	iptcRecordApp      = 2
+	iptcDatasetKeyword = 25 // 2:25 Keywords
+)
+
+// extractIPTCKeywords 從 JPEG 的 APP13 (Photoshop IRB) 中讀取 IPTC 關鍵字
+func extractIPTCKeywords(data []byte) []string {
+	if len(data) < 4 || data[0] != 0xFF || data[1] != 0xD8 {
+		return nil
+	}
+
+	var keywords []string
+	pos := 2
+	for pos+4 <= len(data) {
+		if data[pos] != 0xFF {
+			return keywords
+		}
+		marker := data[pos+1]
+		// SOS 之後是影像資料，不會再有 metadata
+		if marker == 0xDA || marker == 0xD9 {
+			return keywords
+		}
+		size := int(binary.BigEndian.Uint16(data[pos+2 : pos+4]))
+		if size < 2 || pos+2+size > len(data) {
+			return keywords
+		}
+		segment := data[pos+4 : pos+2+size]
+		if marker == 0xED && bytes.HasPrefix(segment, []byte(photoshopIRBHeader)) {
+			keywords = append(keywords, parsePhotoshopIRB(segment[len(photoshopIRBHeader):])...)
+		}
+		pos += 2 + size
+	}
+	return keywords
+}
+
+// parsePhotoshopIRB 逐一讀取 8BIM 資源區塊，找出 IPTC-NAA 資料
+func parsePhotoshopIRB(data []byte) []string {
+	var keywords []string
+	pos := 0
+	for pos+6 < len(data) && string(data[pos:pos+4]) == "8BIM" {
+		id := binary.BigEndian.Uint16(data[pos+4 : pos+6])
+		pos += 6
+
+		// Pascal 字串名稱，連同長度位元組補齊為偶數
+		if pos >= len(data) {
+			break
+		}
+		nameLen := int(data[pos]) + 1
+		if nameLen%2 != 0 {
+			nameLen++
+		}
+		pos += nameLen
+		if pos+4 > len(data) {
+			break
+		}
+
+		size := int(binary.BigEndian.Uint32(data[pos : pos+4]))
+		pos += 4
+		if size < 0 || pos+size > len(data) {
+			break
+		}
+		if id == iptcResourceID {
+			keywords = append(keywords, parseIPTCRecords(data[pos:pos+size])...)
+		}
+		pos += size
+		if size%2 != 0 {
+			pos++
+		}
+	}
+	return keywords
+}
+
+// parseIPTCRecords 讀取 IPTC 資料集中的 2:25 關鍵字
+func parseIPTCRecords(data []byte) []string {
+	var keywords []string
+	pos := 0
+	for pos+5 <= len(data) && data[pos] == iptcTagMarker {
+		record, dataset := data[pos+1], data[pos+2]
+		size := int(binary.BigEndian.Uint16(data[pos+3 : pos+5]))
+		pos += 5
+		// 擴充長度格式 (最高位元為 1) 不會用於關鍵字，直接停止
+		if size&0x8000 != 0 || pos+size > len(data) {
+			break
+		}
+		if record == iptcRecordApp && dataset == iptcDatasetKeyword {
+			keywords = append(keywords, decodeIPTCString(data[pos:pos+size]))
+		}
+		pos += size
+	}
+	return keywords
+}
+
+// decodeIPTCString 關鍵字多為 UTF-8；若不是合法 UTF-8 則視為 Latin-1
+func decodeIPTCString(b []byte) string {
+	if utf8.Valid(b) {
+		return string(b)
+	}
+	runes := make([]rune, len(b))
+	for i, c := range b {
+		runes[i] = rune(c)
+	}
+	return string(runes)
+}
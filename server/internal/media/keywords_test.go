@@ -0,0 +1,65 @@
+package media
+
+import (
+	"bytes"
+	"encoding/binary"
+	"reflect"
+	"testing"
+)
+
+const testXMP = `<?xpacket begin="" id="W5M0MpCehiHzreSzNTczkc9d"?>
+<x:xmpmeta xmlns:x="adobe:ns:meta/">
+ <rdf:RDF xmlns:rdf="http://www.w3.org/1999/02/22-rdf-syntax-ns#">
+  <rdf:Description rdf:about="" xmlns:dc="http://purl.org/dc/elements/1.1/">
+   <dc:title><rdf:Alt><rdf:li xml:lang="x-default">Not a keyword</rdf:li></rdf:Alt></dc:title>
+   <dc:subject><rdf:Bag><rdf:li>Receipts</rdf:li><rdf:li> 旅行 </rdf:li></rdf:Bag></dc:subject>
+  </rdf:Description>
+ </rdf:RDF>
+</x:xmpmeta>
+<?xpacket end="w"?>`
+
+// buildIPTCJPEG 組出只含 APP13 IPTC 關鍵字的最小 JPEG 檔頭
+func buildIPTCJPEG(keywords ...string) []byte {
+	var iptc bytes.Buffer
+	for _, kw := range keywords {
+		iptc.Write([]byte{iptcTagMarker, iptcRecordApp, iptcDatasetKeyword})
+		binary.Write(&iptc, binary.BigEndian, uint16(len(kw)))
+		iptc.WriteString(kw)
+	}
+
+	var irb bytes.Buffer
+	irb.WriteString(photoshopIRBHeader)
+	irb.WriteString("8BIM")
+	binary.Write(&irb, binary.BigEndian, uint16(iptcResourceID))
+	irb.Write([]byte{0, 0}) // 空名稱 (補齊為偶數)
+	binary.Write(&irb, binary.BigEndian, uint32(iptc.Len()))
+	irb.Write(iptc.Bytes())
+	if iptc.Len()%2 != 0 {
+		irb.WriteByte(0)
+	}
+
+	var jpeg bytes.Buffer
+	jpeg.Write([]byte{0xFF, 0xD8, 0xFF, 0xED})
+	binary.Write(&jpeg, binary.BigEndian, uint16(irb.Len()+2))
+	jpeg.Write(irb.Bytes())
+	jpeg.Write([]byte{0xFF, 0xDA, 0x00, 0x02})
+	return jpeg.Bytes()
+}
+
+func TestExtractEmbeddedKeywords(t *testing.T) {
+	head := append(buildIPTCJPEG("receipts", "Family"), []byte(testXMP)...)
+
+	got := extractEmbeddedKeywords(head)
+	want := []string{"receipts", "Family", "旅行"}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("expected %v, got %v", want, got)
+	}
+}
+
+func TestNormalizeTags(t *testing.T) {
+	got := NormalizeTags([]string{"  Beach  Day ", "beach day", "", "Cats"})
+	want := []string{"Beach Day", "Cats"}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("expected %v, got %v", want, got)
+	}
+}
@@ -0,0 +1,50 @@
+package media
+
+import (
+	"bytes"
+	"encoding/binary"
+	"strings"
+)
+
+// Apple MakerNote 常數 (iPhone 連拍以 BurstUUID 標記同一組照片)
+const (
+	appleMakerNoteHeader = "Apple iOS\x00"
+	appleIFDOffset       = 14 // 標頭 (10) + 版本 (2) + 位元組順序 "MM" (2)
+	appleTagBurstUUID    = 0x000b
+	tiffTypeASCII        = 2
+)
+
+// appleBurstUUID 從 Apple MakerNote 讀取 BurstUUID，非 Apple 格式或沒有該欄位時回傳空字串
+// Apple MakerNote 為大端序 IFD，位移量以 MakerNote 起點為基準
+func appleBurstUUID(note []byte) string {
+	if !bytes.HasPrefix(note, []byte(appleMakerNoteHeader)) || len(note) < appleIFDOffset+2 {
+		return ""
+	}
+	if string(note[12:14]) != "MM" {
+		return ""
+	}
+
+	count := int(binary.BigEndian.Uint16(note[appleIFDOffset:]))
+	pos := appleIFDOffset + 2
+	for i := 0; i < count && pos+12 <= len(note); i, pos = i+1, pos+12 {
+		tag := binary.BigEndian.Uint16(note[pos:])
+		typ := binary.BigEndian.Uint16(note[pos+2:])
+		size := int(binary.BigEndian.Uint32(note[pos+4:]))
+		if tag != appleTagBurstUUID || typ != tiffTypeASCII {
+			continue
+		}
+
+		var value []byte
+		if size <= 4 {
+			value = note[pos+8 : pos+8+size]
+		} else {
+			offset := int(binary.BigEndian.Uint32(note[pos+8:]))
+			if offset < 0 || size < 0 || offset+size > len(note) {
+				return ""
+			}
+			value = note[offset : offset+size]
+		}
+		return strings.TrimSpace(strings.TrimRight(string(value), "\x00"))
+	}
+	return ""
+}
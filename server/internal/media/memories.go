@@ -0,0 +1,90 @@
+package media
+
+import (
+	"context"
+	"fmt"
+	"time"
+)
+
+// DefaultMemoriesPerYear 每一年預設回傳的代表照片數量
+const DefaultMemoriesPerYear = 6
+
+// MemoryGroup 為「那年今日」中單一年份的分組
+type MemoryGroup struct {
+	Year     int      `json:"year"`
+	YearsAgo int      `json:"years_ago"`
+	Total    int      `json:"total"` // 該年當日的媒體總數 (Items 僅為挑選過的代表)
+	Items    []*Media `json:"items"`
+}
+
+// Memories 取得「那年今日」：以使用者時區比較月/日，回傳往年同一天拍攝的媒體 (依年份分組)
+//
+// 2/29 拍攝的媒體在非閏年併入 2/28 (同一個月，不會跑到三月)，不會連續三年都看不到
+//
+// 封存項目不列入；代表照片排序：我的最愛 → 照片優先於影片 → 有相機資訊 (排除截圖) → 有 GPS → 解析度較高 → 時間較早
+func (s *Service) Memories(ctx context.Context, userID string, day time.Time, perYear int) ([]*MemoryGroup, error) {
+	loc := day.Location()
+	// 只取今年以前的資料；以時間範圍過濾可使用 idx_media_taken_at
+	startOfYear := time.Date(day.Year(), time.January, 1, 0, 0, 0, 0, loc)
+
+	query := `
+		SELECT ` + mediaColumns + `, m.year, m.total
+		FROM (
+			SELECT media.*,
+			       EXTRACT(YEAR FROM media.taken_at AT TIME ZONE $2)::int AS year,
+			       COUNT(*) OVER w AS total,
+			       ROW_NUMBER() OVER (w ORDER BY
+			           media.is_favorite DESC,
+			           (media.mime_type LIKE 'image/%') DESC,
+			           (COALESCE(media.camera_make, '') <> '') DESC,
+			           (media.latitude IS NOT NULL) DESC,
+			           COALESCE(media.width, 0)::bigint * COALESCE(media.height, 0) DESC,
+			           media.taken_at
+			       ) AS rank
+			FROM media
+			WHERE media.user_id = $1
+			  AND media.deleted_at IS NULL
+			  AND NOT media.is_archived
+			  AND media.taken_at < $3
+			  AND EXTRACT(MONTH FROM media.taken_at AT TIME ZONE $2) = $4
+			  AND (EXTRACT(DAY FROM media.taken_at AT TIME ZONE $2) = $5
+			       OR ($7 AND EXTRACT(DAY FROM media.taken_at AT TIME ZONE $2) = 29))
+			WINDOW w AS (PARTITION BY EXTRACT(YEAR FROM media.taken_at AT TIME ZONE $2))
+		) m
+		WHERE m.rank <= $6
+		ORDER BY m.year DESC, m.rank
+	`
+	rows, err := s.DB.QueryContext(ctx, query, userID, loc.String(), startOfYear, int(day.Month()), day.Day(), perYear, includesLeapDay(day))
+	if err != nil {
+		return nil, fmt.Errorf("failed to query memories: %w", err)
+	}
+	defer rows.Close()
+
+	groups := []*MemoryGroup{}
+	var current *MemoryGroup
+	for rows.Next() {
+		m := &Media{}
+		var year, total int
+		if err := scanMedia(rows, m, &year, &total); err != nil {
+			return nil, fmt.Errorf("failed to scan media: %w", err)
+		}
+		if current == nil || current.Year != year {
+			current = &MemoryGroup{Year: year, YearsAgo: day.Year() - year, Total: total}
+			groups = append(groups, current)
+		}
+		current.Items = append(current.Items, m)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to iterate memories: %w", err)
+	}
+	return groups, nil
+}
+
+// includesLeapDay day 是否為非閏年的 2/28 (同時顯示往年 2/29 的媒體)
+func includesLeapDay(day time.Time) bool {
+	if day.Month() != time.February || day.Day() != 28 {
+		return false
+	}
+	// 閏年的 3/1 前一天是 2/29
+	return time.Date(day.Year(), time.March, 0, 0, 0, 0, 0, time.UTC).Day() == 28
+}
@@ -0,0 +1,53 @@
+package media
+
+import (
+	"net/http"
+	"strconv"
+	"time"
+	_ "time/tzdata" // Alpine 映像檔沒有系統時區資料，內嵌以支援 IANA 時區
+
+	"github.com/gin-gonic/gin"
+)
+
+// MemoriesHandler 取得「那年今日」
+//
+// GET /media/memories?tz=Asia/Taipei&date=2006-01-02&per_year=6
+//   - tz: 使用者的 IANA 時區 (預設 UTC)，用於判斷「今天」與比較拍攝日期；
+//     不接受 Local (伺服器的時區，資料庫無法辨識)
+//   - date: 選填，覆寫「今天」(以 tz 解讀)
+func (h *Handler) MemoriesHandler(c *gin.Context) {
+	userID := c.MustGet("userID").(string)
+
+	// 時區名稱會傳給 PostgreSQL 的 AT TIME ZONE，必須是 IANA 名稱；
+	// LoadLocation("Local") 回傳伺服器時區，名稱 "Local" 不是有效的時區
+	loc, err := time.LoadLocation(c.DefaultQuery("tz", "UTC"))
+	if err != nil || loc == time.Local || loc.String() == "Local" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid tz"})
+		return
+	}
+
+	day := time.Now().In(loc)
+	if d := c.Query("date"); d != "" {
+		day, err = time.ParseInLocation("2006-01-02", d, loc)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "invalid date, expected YYYY-MM-DD"})
+			return
+		}
+	}
+
+	perYear, _ := strconv.Atoi(c.DefaultQuery("per_year", strconv.Itoa(DefaultMemoriesPerYear)))
+	if perYear < 1 || perYear > 50 {
+		perYear = DefaultMemoriesPerYear
+	}
+
+	groups, err := h.Service.Memories(c.Request.Context(), userID, day, perYear)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	for _, g := range groups {
+		h.attachURLs(userID, g.Items...)
+	}
+	jsonWithETag(c, http.StatusOK, groups)
+}
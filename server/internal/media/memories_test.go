@@ -0,0 +1,83 @@
+package media
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/gin-gonic/gin"
+)
+
+func TestMemories(t *testing.T) {
+	db, mock := newMockDB(t)
+
+	loc, _ := time.LoadLocation("Asia/Taipei")
+	day := time.Date(2026, time.October, 19, 9, 0, 0, 0, loc)
+
+	cols := append(append([]string{}, mediaTestColumns...), "year", "total")
+	rows := sqlmock.NewRows(cols).
+		AddRow(append(mediaTestRow("a", time.Date(2024, 10, 19, 8, 0, 0, 0, loc)), 2024, 3)...).
+		AddRow(append(mediaTestRow("b", time.Date(2024, 10, 19, 9, 0, 0, 0, loc)), 2024, 3)...).
+		AddRow(append(mediaTestRow("c", time.Date(2020, 10, 19, 9, 0, 0, 0, loc)), 2020, 1)...)
+
+	mock.ExpectQuery("SELECT (.+) FROM media").
+		WithArgs("user-1", "Asia/Taipei", time.Date(2026, time.January, 1, 0, 0, 0, 0, loc), 10, 19, 2, false).
+		WillReturnRows(rows)
+
+	s := NewService(db, t.TempDir())
+	groups, err := s.Memories(context.Background(), "user-1", day, 2)
+	if err != nil {
+		t.Fatalf("Memories failed: %v", err)
+	}
+
+	if len(groups) != 2 {
+		t.Fatalf("expected 2 groups, got %d", len(groups))
+	}
+	if groups[0].Year != 2024 || groups[0].YearsAgo != 2 || groups[0].Total != 3 || len(groups[0].Items) != 2 {
+		t.Errorf("unexpected first group: %+v", groups[0])
+	}
+	if groups[1].Year != 2020 || groups[1].YearsAgo != 6 || len(groups[1].Items) != 1 {
+		t.Errorf("unexpected second group: %+v", groups[1])
+	}
+
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("there were unfulfilled expectations: %s", err)
+	}
+}
+
+// 非閏年的 2/28 同時包含往年 2/29 的媒體
+func TestIncludesLeapDay(t *testing.T) {
+	cases := []struct {
+		day  time.Time
+		want bool
+	}{
+		{time.Date(2026, time.February, 28, 0, 0, 0, 0, time.UTC), true},
+		{time.Date(2028, time.February, 28, 0, 0, 0, 0, time.UTC), false},
+		{time.Date(2028, time.February, 29, 0, 0, 0, 0, time.UTC), false},
+		{time.Date(2026, time.March, 1, 0, 0, 0, 0, time.UTC), false},
+	}
+	for _, tc := range cases {
+		if got := includesLeapDay(tc.day); got != tc.want {
+			t.Errorf("%s: expected %v, got %v", tc.day.Format("2006-01-02"), tc.want, got)
+		}
+	}
+}
+
+func TestMemoriesHandlerRejectsLocalTZ(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	h := NewHandler(&Service{})
+
+	for _, tz := range []string{"Local", "Mars/Olympus"} {
+		w := httptest.NewRecorder()
+		c, _ := gin.CreateTestContext(w)
+		c.Set("userID", "user-1")
+		c.Request = httptest.NewRequest(http.MethodGet, "/media/memories?tz="+tz, nil)
+		h.MemoriesHandler(c)
+		if w.Code != http.StatusBadRequest {
+			t.Errorf("tz=%s: expected 400, got %d", tz, w.Code)
+		}
+	}
+}
@@ -7,6 +7,7 @@ import (
 	"image"
 	_ "image/jpeg" // Register decoders
 	_ "image/png"
+	"io"
 	"os"
 	"os/exec"
 	"strconv"
@@ -40,7 +41,21 @@ func extractMetadata(filePath string, mimeType string) (*Media, error) {
 	return m, nil
 }
 
-// extractImageMetadata 解析圖片資訊 (寬高, EXIF)
+// embeddedMetadataScanBytes 搜尋 IPTC/XMP 時讀取的檔案前段大小
+const embeddedMetadataScanBytes = 2 << 20
+
+// extractEmbeddedKeywords 合併 IPTC 2:25 與 XMP dc:subject 關鍵字
+func extractEmbeddedKeywords(head []byte) []string {
+	keywords := extractIPTCKeywords(head)
+	if packet := findXMPPacket(head); packet != nil {
+		// XML 格式錯誤時仍保留已解析到的部分
+		x, _ := parseXMP(packet)
+		keywords = append(keywords, x.Keywords...)
+	}
+	return NormalizeTags(keywords)
+}
+
+// extractImageMetadata 解析圖片資訊 (寬高, EXIF, 關鍵字)
 func extractImageMetadata(filePath string, m *Media) error {
 	f, err := os.Open(filePath)
 	if err != nil {
@@ -56,7 +71,14 @@ func extractImageMetadata(filePath string, m *Media) error {
 		m.Height = cfg.Height
 	}
 
-	// 2. 解析 EXIF
+	// 2. 解析內嵌的 IPTC / XMP 關鍵字 (metadata 位於檔案前段)
+	if _, err := f.Seek(0, 0); err != nil {
+		return err
+	}
+	head, _ := io.ReadAll(io.LimitReader(f, embeddedMetadataScanBytes))
+	m.Tags = TagList(extractEmbeddedKeywords(head))
+
+	// 3. 解析 EXIF
 	// 重新定位到檔案開頭
 	if _, err := f.Seek(0, 0); err != nil {
 		return err
@@ -86,6 +108,9 @@ func extractImageMetadata(filePath string, m *Media) error {
 	if camModel, err := x.Get(exif.Model); err == nil {
 		m.CameraModel, _ = camModel.StringVal()
 	}
+	if note, err := x.Get(exif.MakerNote); err == nil {
+		m.BurstID = appleBurstUUID(note.Val)
+	}
 
 	// 曝光參數
 	if fnum, err := x.Get(exif.FNumber); err == nil {
@@ -1,6 +1,8 @@
 package media
 
 import (
+	"encoding/json"
+	"fmt"
 	"time"
 )
 
@@ -28,4 +30,120 @@ type Media struct {
 	DominantColor    string     `json:"dominant_color"`
 	UploadedAt       time.Time  `json:"uploaded_at"`
 	DeletedAt        *time.Time `json:"deleted_at,omitempty"`
+	StreamStatus     string     `json:"stream_status,omitempty"` // 影片 HLS 轉檔狀態
+	IsFavorite       bool       `json:"is_favorite"`
+	IsArchived       bool       `json:"is_archived"` // 封存：不顯示於主時間軸
+	Tags             TagList    `json:"tags"`
+
+	// 使用者覆寫 (PATCH /media/:id)；TakenAt / Latitude / Longitude 為生效值
+	Caption            string `json:"caption"`
+	TakenAtOverridden  bool   `json:"taken_at_overridden"`
+	LocationOverridden bool   `json:"location_overridden"`
+	Rating             *int   `json:"rating"` // 1~5 星，-1 代表排除 (同 XMP xmp:Rating)；nil 為未評等
+
+	// 連拍堆疊；時間軸只回傳封面，StackSize 為堆疊中 (不含垃圾桶) 的項目數
+	StackID   *string `json:"stack_id,omitempty"`
+	StackSize int     `json:"stack_size,omitempty"`
+	BurstID   string  `json:"-"` // 上傳時解析的 BurstUUID
+
+	PHash          *int64   `json:"-"`                         // 感知雜湊 (dHash)
+	NearDuplicates []string `json:"near_duplicates,omitempty"` // 上傳時選擇性回傳的相似媒體
+
+	FromPartner bool `json:"from_partner,omitempty"` // 伴侶分享的媒體 (擁有者為 UserID)
+
+	URLs *MediaURLs `json:"urls,omitempty"` // 簽章網址，由 handler 依請求者產生
+}
+
+// TagList 標籤名稱列表，可直接掃描查詢中以 json_agg 產生的 JSON 陣列
+type TagList []string
+
+// Scan 實作 sql.Scanner
+func (t *TagList) Scan(src any) error {
+	var data []byte
+	switch v := src.(type) {
+	case nil:
+		*t = TagList{}
+		return nil
+	case []byte:
+		data = v
+	case string:
+		data = []byte(v)
+	default:
+		return fmt.Errorf("unsupported tag list type: %T", src)
+	}
+	list := TagList{}
+	if err := json.Unmarshal(data, &list); err != nil {
+		return fmt.Errorf("failed to decode tag list: %w", err)
+	}
+	*t = list
+	return nil
+}
+
+// Album 代表 albums 資料表的結構
+type Album struct {
+	ID           string    `json:"id"`
+	UserID       string    `json:"user_id"`
+	Title        string    `json:"title"`
+	Description  string    `json:"description"`
+	CoverMediaID *string   `json:"cover_media_id"` // 實際顯示的封面 (未指定或已移至垃圾桶時為第一個項目)
+	MediaCount   int       `json:"media_count"`    // 不含垃圾桶中的項目
+	CreatedAt    time.Time `json:"created_at"`
+	UpdatedAt    time.Time `json:"updated_at"`
+	Role         string    `json:"role,omitempty"` // 目前使用者的角色：owner、contributor 或 viewer
+}
+
+// AlbumMember 共享相簿的成員 (列表中第一筆為擁有者)
+type AlbumMember struct {
+	UserID    string    `json:"user_id"`
+	Email     string    `json:"email"`
+	Role      string    `json:"role"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// Partner 伴侶分享的另一方
+type Partner struct {
+	UserID     string     `json:"user_id"`
+	Email      string     `json:"email"`
+	StartDate  *time.Time `json:"start_date"`  // 只分享此時間之後拍攝的項目，null 代表全部
+	InTimeline bool       `json:"in_timeline"` // 受分享者是否將其合併至自己的時間軸
+	CreatedAt  time.Time  `json:"created_at"`
+}
+
+// Stack 連拍堆疊 (展開後的內容)
+type Stack struct {
+	ID           string   `json:"id"`
+	CoverMediaID string   `json:"cover_media_id"` // 實際顯示的封面
+	Media        []*Media `json:"media"`          // 依拍攝時間排序，不含垃圾桶中的項目
+}
+
+// Event 自動分群的事件 (唯讀的虛擬相簿)
+type Event struct {
+	ID           string    `json:"id"`
+	StartAt      time.Time `json:"start_at"`
+	EndAt        time.Time `json:"end_at"`
+	Latitude     *float64  `json:"latitude"` // 有 GPS 成員的中心點
+	Longitude    *float64  `json:"longitude"`
+	MediaCount   int       `json:"media_count"`    // 不含垃圾桶與封存中的項目
+	CoverMediaID *string   `json:"cover_media_id"` // 我的最愛與照片優先
+}
+
+// ShareLink 公開分享連結 (擁有者檢視)
+type ShareLink struct {
+	ID            string     `json:"id"`
+	Token         string     `json:"token"`
+	AlbumID       *string    `json:"album_id"` // NULL 代表分享選取的項目
+	Title         string     `json:"title"`
+	AllowDownload bool       `json:"allow_download"`
+	ExpiresAt     *time.Time `json:"expires_at"`
+	ViewCount     int64      `json:"view_count"`
+	LastViewedAt  *time.Time `json:"last_viewed_at"`
+	MediaCount    int        `json:"media_count"` // 不含垃圾桶中的項目
+	CreatedAt     time.Time  `json:"created_at"`
+}
+
+// Tag 代表 tags 資料表的結構
+type Tag struct {
+	ID         string `json:"id"`
+	Name       string `json:"name"`
+	MediaCount int    `json:"media_count"` // 不含垃圾桶中的項目
 }
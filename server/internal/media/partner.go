@@ -0,0 +1,164 @@
+package media
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"time"
+)
+
+var ErrPartnerNotFound = errors.New("partner share not found")
+
+// partnerVisible 媒體 (別名 m) 由其擁有者以伴侶分享授權給使用者 (userParam)，且符合起始日期
+// 封存的項目不分享給伴侶 (列表與以 ID 直接存取檔案、HLS、XMP 皆同)
+// timelineOnly 時只包含使用者選擇合併至時間軸的授權
+// 每次查詢都重新檢查授權，撤銷後立即生效
+func partnerVisible(userParam string, timelineOnly bool) string {
+	cond := `(NOT m.is_archived AND EXISTS (SELECT 1 FROM partner_shares ps
+		WHERE ps.owner_id = m.user_id AND ps.partner_id = ` + userParam + `
+		  AND (ps.start_date IS NULL OR COALESCE(m.taken_at, m.uploaded_at) >= ps.start_date)`
+	if timelineOnly {
+		cond += ` AND ps.in_timeline`
+	}
+	return cond + `))`
+}
+
+// timelineOwners 時間軸包含的擁有者：自己與選擇合併的伴侶 (讓 user_id 索引可用於 OR 條件)
+func timelineOwners(userParam string) string {
+	return `m.user_id = ANY(ARRAY(
+		SELECT ` + userParam + `::uuid
+		UNION ALL
+		SELECT owner_id FROM partner_shares WHERE partner_id = ` + userParam + ` AND in_timeline))
+		AND (m.user_id = ` + userParam + ` OR ` + partnerVisible(userParam, true) + `)`
+}
+
+// markPartnerMedia 標記不屬於使用者本人的媒體
+func markPartnerMedia(list []*Media, userID string) {
+	for _, m := range list {
+		m.FromPartner = m.UserID != userID
+	}
+}
+
+// PartnerOverview 伴侶分享的雙向列表
+type PartnerOverview struct {
+	SharingWith  []*Partner `json:"sharing_with"`   // 我授權的對象
+	SharedWithMe []*Partner `json:"shared_with_me"` // 授權給我的對象
+}
+
+// GrantPartner 授權另一位使用者 (以 Email 指定) 讀取自己的整個媒體庫；已授權時更新起始日期
+// startDate 為 nil 代表分享全部，否則只分享拍攝 (無拍攝時間者以上傳時間) 於該時間之後的項目
+func (s *Service) GrantPartner(ctx context.Context, ownerID, email string, startDate *time.Time) (*Partner, error) {
+	p := &Partner{StartDate: startDate}
+	var err error
+	if p.UserID, p.Email, err = s.findInvitee(ctx, ownerID, email); err != nil {
+		return nil, err
+	}
+
+	err = s.DB.QueryRowContext(ctx, `
+		INSERT INTO partner_shares (owner_id, partner_id, start_date) VALUES ($1, $2, $3)
+		ON CONFLICT (owner_id, partner_id) DO UPDATE SET start_date = EXCLUDED.start_date
+		RETURNING in_timeline, created_at
+	`, ownerID, p.UserID, startDate).Scan(&p.InTimeline, &p.CreatedAt)
+	if err != nil {
+		return nil, fmt.Errorf("failed to grant partner share: %w", err)
+	}
+	return p, nil
+}
+
+// ListPartners 列出我授權的與授權給我的伴侶分享
+func (s *Service) ListPartners(ctx context.Context, userID string) (*PartnerOverview, error) {
+	overview := &PartnerOverview{SharingWith: []*Partner{}, SharedWithMe: []*Partner{}}
+
+	query := `
+		SELECT ps.owner_id = $1, u.id, u.email, ps.start_date, ps.in_timeline, ps.created_at
+		FROM partner_shares ps
+		JOIN users u ON u.id = CASE WHEN ps.owner_id = $1 THEN ps.partner_id ELSE ps.owner_id END
+		WHERE ps.owner_id = $1 OR ps.partner_id = $1
+		ORDER BY ps.created_at, u.id
+	`
+	rows, err := s.DB.QueryContext(ctx, query, userID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query partners: %w", err)
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		p := &Partner{}
+		var granted bool
+		if err := rows.Scan(&granted, &p.UserID, &p.Email, &p.StartDate, &p.InTimeline, &p.CreatedAt); err != nil {
+			return nil, fmt.Errorf("failed to scan partner: %w", err)
+		}
+		if granted {
+			overview.SharingWith = append(overview.SharingWith, p)
+		} else {
+			overview.SharedWithMe = append(overview.SharedWithMe, p)
+		}
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to iterate partners: %w", err)
+	}
+	return overview, nil
+}
+
+// RevokePartner 撤銷授權 (由擁有者操作)；對方之後的所有請求立即無法讀取
+func (s *Service) RevokePartner(ctx context.Context, ownerID, partnerID string) error {
+	res, err := s.DB.ExecContext(ctx, `DELETE FROM partner_shares WHERE owner_id = $1 AND partner_id = $2`, ownerID, partnerID)
+	if err != nil {
+		return fmt.Errorf("failed to revoke partner share: %w", err)
+	}
+	if n, _ := res.RowsAffected(); n == 0 {
+		return ErrPartnerNotFound
+	}
+	return nil
+}
+
+// SetPartnerTimeline 設定是否將伴侶 (ownerID) 的媒體合併至自己的時間軸
+func (s *Service) SetPartnerTimeline(ctx context.Context, userID, ownerID string, inTimeline bool) error {
+	res, err := s.DB.ExecContext(ctx, `
+		UPDATE partner_shares SET in_timeline = $3 WHERE owner_id = $1 AND partner_id = $2
+	`, ownerID, userID, inTimeline)
+	if err != nil {
+		return fmt.Errorf("failed to update partner share: %w", err)
+	}
+	if n, _ := res.RowsAffected(); n == 0 {
+		return ErrPartnerNotFound
+	}
+	return nil
+}
+
+// ListPartnerMedia 瀏覽伴侶授權的媒體庫 (排序與時間軸相同，不含垃圾桶與封存項目)
+func (s *Service) ListPartnerMedia(ctx context.Context, userID, ownerID string, limit, offset int) ([]*Media, error) {
+	var exists bool
+	err := s.DB.QueryRowContext(ctx, `
+		SELECT TRUE FROM partner_shares WHERE owner_id = $1 AND partner_id = $2
+	`, ownerID, userID).Scan(&exists)
+	if err == sql.ErrNoRows {
+		return nil, ErrPartnerNotFound
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to query partner share: %w", err)
+	}
+
+	query := `
+		SELECT ` + mediaColumns + `
+		FROM media m
+		WHERE m.user_id = $1 AND m.deleted_at IS NULL
+		  AND ` + partnerVisible("$2", false) + `
+		  AND ` + stackCollapsedCondition + `
+		ORDER BY m.taken_at DESC NULLS LAST, m.uploaded_at DESC
+		LIMIT $3 OFFSET $4
+	`
+	rows, err := s.DB.QueryContext(ctx, query, ownerID, userID, limit, offset)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query partner media: %w", err)
+	}
+	defer rows.Close()
+
+	list, err := collectMedia(rows)
+	if err != nil {
+		return nil, err
+	}
+	markPartnerMedia(list, userID)
+	return list, nil
+}
@@ -0,0 +1,138 @@
+package media
+
+import (
+	"errors"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+type partnerRequest struct {
+	Email     string     `json:"email"`
+	StartDate *time.Time `json:"start_date"` // 選填，只分享此時間之後拍攝的項目
+}
+
+type partnerTimelineRequest struct {
+	InTimeline *bool `json:"in_timeline"`
+}
+
+// respondPartnerError 將伴侶分享錯誤轉換為 HTTP 狀態碼
+func respondPartnerError(c *gin.Context, err error) {
+	switch {
+	case errors.Is(err, ErrPartnerNotFound), errors.Is(err, ErrInviteeNotFound):
+		c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+	case errors.Is(err, ErrInviteSelf):
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+	default:
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+	}
+}
+
+// partnerParam 取得並驗證路徑中的伴侶使用者 ID
+func partnerParam(c *gin.Context) (string, bool) {
+	partnerID := strings.ToLower(c.Param("user_id"))
+	if !uuidPattern.MatchString(partnerID) {
+		c.JSON(http.StatusNotFound, gin.H{"error": ErrPartnerNotFound.Error()})
+		return "", false
+	}
+	return partnerID, true
+}
+
+// ListPartnersHandler 列出我授權的與授權給我的伴侶分享 (GET /partners)
+func (h *Handler) ListPartnersHandler(c *gin.Context) {
+	userID := c.MustGet("userID").(string)
+
+	overview, err := h.Service.ListPartners(c.Request.Context(), userID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	jsonWithETag(c, http.StatusOK, overview)
+}
+
+// GrantPartnerHandler 授權另一位使用者讀取整個媒體庫 (POST /partners)
+// body: {"email": "...", "start_date": "2020-01-01T00:00:00Z"}；已授權時更新起始日期
+func (h *Handler) GrantPartnerHandler(c *gin.Context) {
+	userID := c.MustGet("userID").(string)
+
+	var req partnerRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid request body"})
+		return
+	}
+	req.Email = strings.TrimSpace(req.Email)
+	if req.Email == "" || len(req.Email) > 255 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "email is required"})
+		return
+	}
+
+	partner, err := h.Service.GrantPartner(c.Request.Context(), userID, req.Email, req.StartDate)
+	if err != nil {
+		respondPartnerError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusCreated, partner)
+}
+
+// RevokePartnerHandler 撤銷我授權給對方的伴侶分享，立即生效 (DELETE /partners/:user_id)
+func (h *Handler) RevokePartnerHandler(c *gin.Context) {
+	userID := c.MustGet("userID").(string)
+	partnerID, ok := partnerParam(c)
+	if !ok {
+		return
+	}
+
+	if err := h.Service.RevokePartner(c.Request.Context(), userID, partnerID); err != nil {
+		respondPartnerError(c, err)
+		return
+	}
+
+	c.Status(http.StatusNoContent)
+}
+
+// SetPartnerTimelineHandler 設定是否將伴侶的媒體合併至我的時間軸 (PUT /partners/:user_id/timeline)
+// body: {"in_timeline": true}
+func (h *Handler) SetPartnerTimelineHandler(c *gin.Context) {
+	userID := c.MustGet("userID").(string)
+	ownerID, ok := partnerParam(c)
+	if !ok {
+		return
+	}
+
+	var req partnerTimelineRequest
+	if err := c.ShouldBindJSON(&req); err != nil || req.InTimeline == nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "in_timeline is required"})
+		return
+	}
+
+	if err := h.Service.SetPartnerTimeline(c.Request.Context(), userID, ownerID, *req.InTimeline); err != nil {
+		respondPartnerError(c, err)
+		return
+	}
+
+	c.Status(http.StatusNoContent)
+}
+
+// ListPartnerMediaHandler 瀏覽伴侶授權給我的媒體庫 (GET /partners/:user_id/media?page=1&limit=20)
+// 檔案與串流透過一般的 /media/:id/file、/media/:id/hls 取得
+func (h *Handler) ListPartnerMediaHandler(c *gin.Context) {
+	userID := c.MustGet("userID").(string)
+	ownerID, ok := partnerParam(c)
+	if !ok {
+		return
+	}
+	limit, offset := parsePagination(c)
+
+	list, err := h.Service.ListPartnerMedia(c.Request.Context(), userID, ownerID, limit, offset)
+	if err != nil {
+		respondPartnerError(c, err)
+		return
+	}
+
+	h.attachURLs(userID, list...)
+	jsonWithETag(c, http.StatusOK, list)
+}
@@ -0,0 +1,67 @@
+package media
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+)
+
+func TestListMergesPartnerTimeline(t *testing.T) {
+	db, mock := newMockDB(t)
+	s := &Service{DB: db}
+
+	now := time.Now()
+	own := mediaTestRow(batchActiveID, now)
+	partner := mediaTestRow(batchTrashedID, now.Add(-time.Hour))
+	partner[1] = "user-2"
+
+	mock.ExpectQuery(`SELECT owner_id FROM partner_shares WHERE partner_id = \$1 AND in_timeline`).
+		WithArgs("user-1", 20, 0).
+		WillReturnRows(sqlmock.NewRows(mediaTestColumns).AddRow(own...).AddRow(partner...))
+
+	list, err := s.List(context.Background(), "user-1", ListOptions{IncludePartners: true}, 20, 0)
+	if err != nil {
+		t.Fatalf("List failed: %v", err)
+	}
+	if len(list) != 2 || list[0].FromPartner || !list[1].FromPartner {
+		t.Errorf("unexpected partner marks: %+v", list)
+	}
+}
+
+func TestListPartnerMediaRequiresGrant(t *testing.T) {
+	db, mock := newMockDB(t)
+	s := &Service{DB: db}
+
+	mock.ExpectQuery(`SELECT TRUE FROM partner_shares`).
+		WithArgs("user-2", "user-1").
+		WillReturnRows(sqlmock.NewRows([]string{"bool"}))
+
+	_, err := s.ListPartnerMedia(context.Background(), "user-1", "user-2", 20, 0)
+	if !errors.Is(err, ErrPartnerNotFound) {
+		t.Errorf("expected ErrPartnerNotFound, got %v", err)
+	}
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("unmet expectations: %v", err)
+	}
+}
+
+// 以 ID 直接存取 (檔案、HLS、XMP) 與伴侶列表套用相同規則：封存的項目不分享給伴侶
+func TestGetAccessibleByIDHidesArchivedFromPartners(t *testing.T) {
+	db, mock := newMockDB(t)
+	s := &Service{DB: db}
+
+	mock.ExpectQuery(`WHERE m.id = \$1 AND \(m.user_id = \$2 OR \(m.deleted_at IS NULL AND \(.+`+
+		`\(NOT m.is_archived AND EXISTS \(SELECT 1 FROM partner_shares ps`).
+		WithArgs(batchActiveID, "user-2").
+		WillReturnRows(sqlmock.NewRows(append(mediaTestColumns, "storage_path")))
+
+	if _, err := s.GetAccessibleByID(context.Background(), "user-2", batchActiveID); !errors.Is(err, ErrMediaNotFound) {
+		t.Errorf("expected ErrMediaNotFound, got %v", err)
+	}
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("unmet expectations: %v", err)
+	}
+}
@@ -0,0 +1,446 @@
+package media
+
+import (
+	"context"
+	"fmt"
+	"image"
+	"math/bits"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+)
+
+// 相似度設定：相似度 = 1 - 漢明距離 / 64
+const (
+	DefaultSimilarity = 0.9  // 約 6 個位元以內
+	MinSimilarity     = 0.75 // 再低的門檻誤判過多
+
+	// maxNearDuplicateWarnings 上傳時回傳的相似項目上限
+	maxNearDuplicateWarnings = 10
+	// phashSampleSize 以 libvips 縮小後再計算的邊長
+	phashSampleSize = 256
+	// phashBackfillBatch 補算時每批讀取的筆數
+	phashBackfillBatch = 200
+)
+
+// SimilarGroup 一組視覺上相似的媒體 (依拍攝時間新到舊)
+type SimilarGroup struct {
+	Media []*Media `json:"media"`
+}
+
+// SimilarityToDistance 將相似度轉換為可接受的最大漢明距離
+func SimilarityToDistance(similarity float64) int {
+	return int((1 - similarity) * 64)
+}
+
+// hammingDistance 兩個雜湊不同的位元數
+func hammingDistance(a, b uint64) int {
+	return bits.OnesCount64(a ^ b)
+}
+
+// computeDHash 計算 64 位元 dHash：縮為 9x8 灰階後比較每列相鄰像素的亮度
+// 對縮放、重新壓縮與輕微調色不敏感
+func computeDHash(img image.Image) uint64 {
+	b := img.Bounds()
+	w, h := b.Dx(), b.Dy()
+
+	var grid [8][9]float64
+	for gy := 0; gy < 8; gy++ {
+		y0, y1 := b.Min.Y+gy*h/8, b.Min.Y+(gy+1)*h/8
+		for gx := 0; gx < 9; gx++ {
+			x0, x1 := b.Min.X+gx*w/9, b.Min.X+(gx+1)*w/9
+			grid[gy][gx] = averageLuma(img, x0, y0, x1, y1)
+		}
+	}
+
+	var hash uint64
+	for y := 0; y < 8; y++ {
+		for x := 0; x < 8; x++ {
+			hash <<= 1
+			if grid[y][x] < grid[y][x+1] {
+				hash |= 1
+			}
+		}
+	}
+	return hash
+}
+
+// averageLuma 計算區塊的平均亮度；大區塊以固定步距取樣，避免逐一讀取數千萬像素
+func averageLuma(img image.Image, x0, y0, x1, y1 int) float64 {
+	if x1 <= x0 {
+		x1 = x0 + 1
+	}
+	if y1 <= y0 {
+		y1 = y0 + 1
+	}
+	stepX, stepY := max(1, (x1-x0)/16), max(1, (y1-y0)/16)
+
+	var sum float64
+	var n int
+	for y := y0; y < y1; y += stepY {
+		for x := x0; x < x1; x += stepX {
+			r, g, b, _ := img.At(x, y).RGBA()
+			sum += 0.299*float64(r) + 0.587*float64(g) + 0.114*float64(b)
+			n++
+		}
+	}
+	return sum / float64(n)
+}
+
+// imageDHash 計算圖片檔案的 dHash
+// 優先以 libvips 縮小 (支援 HEIC 並依 EXIF Orientation 轉正)，無法使用時以標準庫解碼 JPEG/PNG
+func imageDHash(ctx context.Context, path, mimeType string) (uint64, error) {
+	tmp, err := os.CreateTemp("", "phash-*")
+	if err != nil {
+		return 0, fmt.Errorf("failed to create temp file: %w", err)
+	}
+	tmp.Close()
+	defer os.Remove(tmp.Name())
+
+	src := tmp.Name()
+	opts := VariantOptions{Width: phashSampleSize, Height: phashSampleSize, Fit: FitContain, Format: FormatJPEG}
+	if err := runVipsThumbnail(ctx, path, src, opts); err != nil {
+		if mimeType != "image/jpeg" && mimeType != "image/png" {
+			return 0, err
+		}
+		src = path
+	}
+
+	f, err := os.Open(src)
+	if err != nil {
+		return 0, err
+	}
+	defer f.Close()
+
+	img, _, err := image.Decode(f)
+	if err != nil {
+		return 0, fmt.Errorf("failed to decode image: %w", err)
+	}
+	return computeDHash(img), nil
+}
+
+// 多重索引雜湊：64 位元切成 phashChunks 段，各段為 media 的 phash_0..phash_3 欄位 (有索引)
+const (
+	phashChunks    = 4
+	phashChunkBits = 64 / phashChunks
+)
+
+// phashChunk 雜湊的第 i 段 (由高位元開始)，與 phash_i 欄位相同
+func phashChunk(hash uint64, i int) int32 {
+	return int32(hash >> (64 - phashChunkBits*(i+1)) & (1<<phashChunkBits - 1))
+}
+
+// chunkNeighbors 列出與 v 的漢明距離在 radius 以內的所有段值 (含 v 本身)
+func chunkNeighbors(v int32, radius int) []int32 {
+	out := []int32{v}
+	var flip func(val int32, from, left int)
+	flip = func(val int32, from, left int) {
+		for b := from; b < phashChunkBits; b++ {
+			n := val ^ 1<<b
+			out = append(out, n)
+			if left > 1 {
+				flip(n, b+1, left-1)
+			}
+		}
+	}
+	if radius > 0 {
+		flip(v, 0, radius)
+	}
+	return out
+}
+
+// phashCandidateCondition 產生以段索引取得候選的條件：距離 <= maxDistance 時至少一段的距離 <= maxDistance/phashChunks
+// 參數編號由 next 開始；候選仍需以 bit_count 精確過濾
+func phashCandidateCondition(hash uint64, maxDistance, next int) (string, []any) {
+	radius := maxDistance / phashChunks
+	conds := make([]string, phashChunks)
+	args := make([]any, phashChunks)
+	for i := range conds {
+		conds[i] = fmt.Sprintf("phash_%d = ANY($%d)", i, next+i)
+		args[i] = chunkNeighbors(phashChunk(hash, i), radius)
+	}
+	return "(" + strings.Join(conds, " OR ") + ")", args
+}
+
+// groupSimilarPairs 將相似配對連結成組 (傳遞關係)；items 為排序後的項目索引 0..n-1
+// 回傳兩個以上項目的組，組內與組之間皆依索引順序
+func groupSimilarPairs(n int, pairs [][2]int) [][]int {
+	parent := make([]int, n)
+	for i := range parent {
+		parent[i] = i
+	}
+	var find func(int) int
+	find = func(i int) int {
+		if parent[i] != i {
+			parent[i] = find(parent[i])
+		}
+		return parent[i]
+	}
+	for _, p := range pairs {
+		ra, rb := find(p[0]), find(p[1])
+		if ra < rb {
+			parent[rb] = ra
+		} else if rb < ra {
+			parent[ra] = rb
+		}
+	}
+
+	byRoot := map[int][]int{}
+	var order []int
+	for i := 0; i < n; i++ {
+		r := find(i)
+		if _, ok := byRoot[r]; !ok {
+			order = append(order, r)
+		}
+		byRoot[r] = append(byRoot[r], i)
+	}
+	var groups [][]int
+	for _, r := range order {
+		if len(byRoot[r]) >= 2 {
+			groups = append(groups, byRoot[r])
+		}
+	}
+	return groups
+}
+
+// indexSimilar 寫入媒體與其他媒體 (含垃圾桶) 的相似配對，距離上限為 MinSimilarity
+// 上傳與補算時呼叫；失敗時不寫入 media_similar_indexed，之後由補算流程重試
+func (s *Service) indexSimilar(ctx context.Context, userID, mediaID string, hash int64) error {
+	cond, args := phashCandidateCondition(uint64(hash), SimilarityToDistance(MinSimilarity), 5)
+	query := `
+		INSERT INTO media_similar (media_id, similar_id, user_id, distance)
+		SELECT LEAST($2::uuid, id), GREATEST($2::uuid, id), $1, bit_count((phash # $3)::bit(64))
+		FROM media
+		WHERE user_id = $1 AND id <> $2 AND phash IS NOT NULL AND ` + cond + `
+		  AND bit_count((phash # $3)::bit(64)) <= $4
+		ON CONFLICT DO NOTHING
+	`
+	args = append([]any{userID, mediaID, hash, SimilarityToDistance(MinSimilarity)}, args...)
+	if _, err := s.DB.ExecContext(ctx, query, args...); err != nil {
+		return fmt.Errorf("failed to store similar media: %w", err)
+	}
+	if _, err := s.DB.ExecContext(ctx, `INSERT INTO media_similar_indexed (media_id) VALUES ($1) ON CONFLICT DO NOTHING`, mediaID); err != nil {
+		return fmt.Errorf("failed to mark similar media indexed: %w", err)
+	}
+	return nil
+}
+
+// SimilarGroups 列出視覺上相似的媒體群組 (不含垃圾桶)，分頁以群組為單位
+// 群組由上傳時寫入的相似配對組成，組內與組之間依拍攝時間新到舊
+func (s *Service) SimilarGroups(ctx context.Context, userID string, similarity float64, limit, offset int) ([]*SimilarGroup, error) {
+	query := `
+		SELECT s.media_id, a.taken_at, s.similar_id, b.taken_at
+		FROM media_similar s
+		JOIN media a ON a.id = s.media_id
+		JOIN media b ON b.id = s.similar_id
+		WHERE s.user_id = $1 AND s.distance <= $2 AND a.deleted_at IS NULL AND b.deleted_at IS NULL
+	`
+	rows, err := s.DB.QueryContext(ctx, query, userID, SimilarityToDistance(similarity))
+	if err != nil {
+		return nil, fmt.Errorf("failed to query similar media: %w", err)
+	}
+	takenAt := map[string]*time.Time{}
+	var edges [][2]string
+	for rows.Next() {
+		var a, b string
+		var ta, tb *time.Time
+		if err := rows.Scan(&a, &ta, &b, &tb); err != nil {
+			rows.Close()
+			return nil, fmt.Errorf("failed to scan similar media: %w", err)
+		}
+		takenAt[a], takenAt[b] = ta, tb
+		edges = append(edges, [2]string{a, b})
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to iterate similar media: %w", err)
+	}
+
+	// 依拍攝時間新到舊 (沒有時間的排最後)，相同時依 id
+	ids := make([]string, 0, len(takenAt))
+	for id := range takenAt {
+		ids = append(ids, id)
+	}
+	sort.Slice(ids, func(i, j int) bool {
+		ti, tj := takenAt[ids[i]], takenAt[ids[j]]
+		switch {
+		case ti == nil || tj == nil:
+			if (ti == nil) != (tj == nil) {
+				return tj == nil
+			}
+		case !ti.Equal(*tj):
+			return ti.After(*tj)
+		}
+		return ids[i] < ids[j]
+	})
+	index := make(map[string]int, len(ids))
+	for i, id := range ids {
+		index[id] = i
+	}
+	pairs := make([][2]int, len(edges))
+	for i, e := range edges {
+		pairs[i] = [2]int{index[e[0]], index[e[1]]}
+	}
+
+	groups := groupSimilarPairs(len(ids), pairs)
+	if offset >= len(groups) {
+		return []*SimilarGroup{}, nil
+	}
+	groups = groups[offset:min(len(groups), offset+limit)]
+
+	var pageIDs []string
+	for _, g := range groups {
+		for _, i := range g {
+			pageIDs = append(pageIDs, ids[i])
+		}
+	}
+	byID, err := s.getManyByID(ctx, userID, pageIDs)
+	if err != nil {
+		return nil, err
+	}
+
+	result := make([]*SimilarGroup, 0, len(groups))
+	for _, g := range groups {
+		sg := &SimilarGroup{Media: make([]*Media, 0, len(g))}
+		for _, i := range g {
+			if m, ok := byID[ids[i]]; ok {
+				sg.Media = append(sg.Media, m)
+			}
+		}
+		result = append(result, sg)
+	}
+	return result, nil
+}
+
+// FindNearDuplicates 找出與指定雜湊相似的其他媒體 (最相似者優先)
+func (s *Service) FindNearDuplicates(ctx context.Context, userID, excludeID string, hash int64, similarity float64) ([]string, error) {
+	cond, args := phashCandidateCondition(uint64(hash), SimilarityToDistance(similarity), 6)
+	query := `
+		SELECT id FROM media
+		WHERE user_id = $1 AND id <> $2 AND deleted_at IS NULL AND phash IS NOT NULL AND ` + cond + `
+		  AND bit_count((phash # $3)::bit(64)) <= $4
+		ORDER BY bit_count((phash # $3)::bit(64)), uploaded_at DESC
+		LIMIT $5
+	`
+	args = append([]any{userID, excludeID, hash, SimilarityToDistance(similarity), maxNearDuplicateWarnings}, args...)
+	rows, err := s.DB.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query near duplicates: %w", err)
+	}
+	defer rows.Close()
+
+	ids := []string{}
+	for rows.Next() {
+		var id string
+		if err := rows.Scan(&id); err != nil {
+			return nil, fmt.Errorf("failed to scan near duplicate: %w", err)
+		}
+		ids = append(ids, id)
+	}
+	return ids, rows.Err()
+}
+
+// BackfillPerceptualHashes 為尚未計算雜湊的圖片補算 (依 id 分批走訪一次)，並為尚未建立相似配對的媒體建立配對
+// 無法解碼的檔案維持 NULL，只計入 failed，不會被當成相似項目
+// 只更新 phash 時觸發器不記錄異動 (見 migration 000014)，補算不會讓客戶端重新同步
+func (s *Service) BackfillPerceptualHashes(ctx context.Context, userID string) (hashed, failed int, err error) {
+	query := `
+		SELECT id, storage_path, mime_type FROM media
+		WHERE user_id = $1 AND deleted_at IS NULL AND phash IS NULL AND mime_type LIKE 'image/%'
+		  AND id > $2
+		ORDER BY id
+		LIMIT $3
+	`
+	type pending struct{ id, path, mimeType string }
+	last := "00000000-0000-0000-0000-000000000000"
+	for {
+		rows, err := s.DB.QueryContext(ctx, query, userID, last, phashBackfillBatch)
+		if err != nil {
+			return hashed, failed, fmt.Errorf("failed to query media without hash: %w", err)
+		}
+		var batch []pending
+		for rows.Next() {
+			var p pending
+			if err := rows.Scan(&p.id, &p.path, &p.mimeType); err != nil {
+				rows.Close()
+				return hashed, failed, fmt.Errorf("failed to scan media: %w", err)
+			}
+			batch = append(batch, p)
+		}
+		rows.Close()
+		if err := rows.Err(); err != nil {
+			return hashed, failed, fmt.Errorf("failed to iterate media: %w", err)
+		}
+		if len(batch) == 0 {
+			break
+		}
+
+		for _, p := range batch {
+			if err := ctx.Err(); err != nil {
+				return hashed, failed, err
+			}
+			h, err := imageDHash(ctx, filepath.Join(s.UploadDir, p.path), p.mimeType)
+			if err != nil {
+				fmt.Printf("failed to compute phash for %s: %v\n", p.id, err)
+				failed++
+				continue
+			}
+			if _, err := s.DB.ExecContext(ctx, `UPDATE media SET phash = $2 WHERE id = $1`, p.id, int64(h)); err != nil {
+				return hashed, failed, fmt.Errorf("failed to store phash: %w", err)
+			}
+			hashed++
+		}
+		last = batch[len(batch)-1].id
+	}
+
+	return hashed, failed, s.backfillSimilar(ctx, userID)
+}
+
+// backfillSimilar 為有雜湊但尚未建立相似配對的媒體建立配對 (含剛補算的媒體與先前寫入失敗的媒體)
+func (s *Service) backfillSimilar(ctx context.Context, userID string) error {
+	query := `
+		SELECT m.id, m.phash FROM media m
+		WHERE m.user_id = $1 AND m.phash IS NOT NULL
+		  AND NOT EXISTS (SELECT 1 FROM media_similar_indexed i WHERE i.media_id = m.id)
+		ORDER BY m.id
+		LIMIT $2
+	`
+	type pending struct {
+		id   string
+		hash int64
+	}
+	for {
+		rows, err := s.DB.QueryContext(ctx, query, userID, phashBackfillBatch)
+		if err != nil {
+			return fmt.Errorf("failed to query unindexed media: %w", err)
+		}
+		var batch []pending
+		for rows.Next() {
+			var p pending
+			if err := rows.Scan(&p.id, &p.hash); err != nil {
+				rows.Close()
+				return fmt.Errorf("failed to scan media: %w", err)
+			}
+			batch = append(batch, p)
+		}
+		rows.Close()
+		if err := rows.Err(); err != nil {
+			return fmt.Errorf("failed to iterate media: %w", err)
+		}
+		if len(batch) == 0 {
+			return nil
+		}
+
+		for _, p := range batch {
+			if err := ctx.Err(); err != nil {
+				return err
+			}
+			// 寫入後即標記完成，下一批查詢不會再取到
+			if err := s.indexSimilar(ctx, userID, p.id, p.hash); err != nil {
+				return err
+			}
+		}
+	}
+}
@@ -0,0 +1,171 @@
+package media
+
+import (
+	"context"
+	"image"
+	"image/color"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+)
+
+// gradientImage 產生水平漸層 (可選擇反向)，用於測試 dHash 對縮放不敏感
+func gradientImage(w, h int, reverse bool) image.Image {
+	img := image.NewGray(image.Rect(0, 0, w, h))
+	for y := 0; y < h; y++ {
+		for x := 0; x < w; x++ {
+			v := uint8(x * 255 / w)
+			if reverse {
+				v = 255 - v
+			}
+			img.SetGray(x, y, color.Gray{Y: v})
+		}
+	}
+	return img
+}
+
+func TestComputeDHash(t *testing.T) {
+	large := computeDHash(gradientImage(1200, 800, false))
+	small := computeDHash(gradientImage(300, 200, false))
+	if d := hammingDistance(large, small); d > 2 {
+		t.Errorf("resized copy should be near-identical, distance %d", d)
+	}
+
+	reversed := computeDHash(gradientImage(1200, 800, true))
+	if d := hammingDistance(large, reversed); d < 32 {
+		t.Errorf("different image should be far apart, distance %d", d)
+	}
+}
+
+func TestGroupSimilarPairs(t *testing.T) {
+	// 0-2、2-3 (傳遞)、1-4；5 沒有配對
+	groups := groupSimilarPairs(6, [][2]int{{2, 3}, {4, 1}, {0, 2}})
+	if !reflect.DeepEqual(groups, [][]int{{0, 2, 3}, {1, 4}}) {
+		t.Errorf("unexpected groups: %v", groups)
+	}
+	if groups := groupSimilarPairs(0, nil); len(groups) != 0 {
+		t.Errorf("expected no groups, got %v", groups)
+	}
+}
+
+func TestChunkNeighbors(t *testing.T) {
+	// 16 位元中翻轉 0..r 個位元的組合數
+	for radius, want := range []int{1, 17, 137, 697, 2517} {
+		got := chunkNeighbors(0x1234, radius)
+		if len(got) != want {
+			t.Errorf("radius %d: expected %d neighbors, got %d", radius, want, len(got))
+		}
+		seen := map[int32]bool{}
+		for _, v := range got {
+			if seen[v] || v < 0 || v > 0xFFFF || hammingDistance(uint64(v), 0x1234) > radius {
+				t.Fatalf("radius %d: invalid neighbor %#x", radius, v)
+			}
+			seen[v] = true
+		}
+	}
+}
+
+func TestPhashCandidateCondition(t *testing.T) {
+	hash := uint64(0xAAAA_BBBB_CCCC_DDDD)
+	cond, args := phashCandidateCondition(hash, 6, 5)
+	if cond != "(phash_0 = ANY($5) OR phash_1 = ANY($6) OR phash_2 = ANY($7) OR phash_3 = ANY($8))" {
+		t.Errorf("unexpected condition: %s", cond)
+	}
+	for i, want := range []int32{0xAAAA, 0xBBBB, 0xCCCC, 0xDDDD} {
+		if phashChunk(hash, i) != want {
+			t.Errorf("chunk %d: expected %#x, got %#x", i, want, phashChunk(hash, i))
+		}
+		if n := args[i].([]int32); len(n) != 17 || n[0] != want {
+			t.Errorf("chunk %d: unexpected neighbors %v", i, n[:min(len(n), 3)])
+		}
+	}
+
+	// 任何距離 <= 6 的雜湊至少有一段落在候選中
+	near := hash ^ 0x0003_0003_0001_0001 // 差 6 位元，每段 1~2 位元
+	found := false
+	for i := 0; i < phashChunks; i++ {
+		for _, v := range args[i].([]int32) {
+			if v == phashChunk(near, i) {
+				found = true
+			}
+		}
+	}
+	if !found {
+		t.Errorf("hash within distance 6 is not a candidate")
+	}
+}
+
+func TestSimilarGroups(t *testing.T) {
+	db, mock := newMockDB(t)
+	s := &Service{DB: db}
+	const (
+		a = "00000000-0000-0000-0000-00000000000a"
+		b = "00000000-0000-0000-0000-00000000000b"
+		c = "00000000-0000-0000-0000-00000000000c"
+	)
+	older := time.Date(2020, 1, 1, 0, 0, 0, 0, time.UTC)
+	newer := older.Add(time.Hour)
+
+	mock.ExpectQuery(`SELECT s.media_id, a.taken_at, s.similar_id, b.taken_at\s+FROM media_similar`).
+		WithArgs("user-1", SimilarityToDistance(DefaultSimilarity)).
+		WillReturnRows(sqlmock.NewRows([]string{"media_id", "a_taken_at", "similar_id", "b_taken_at"}).
+			AddRow(a, nil, b, older).
+			AddRow(b, older, c, newer))
+	rows := sqlmock.NewRows(mediaTestColumns)
+	for _, id := range []string{c, b, a} {
+		rows.AddRow(mediaTestRow(id, newer)...)
+	}
+	mock.ExpectQuery(`SELECT .+ FROM media m`).WillReturnRows(rows)
+
+	groups, err := s.SimilarGroups(context.Background(), "user-1", DefaultSimilarity, 20, 0)
+	if err != nil {
+		t.Fatalf("SimilarGroups failed: %v", err)
+	}
+	if len(groups) != 1 || len(groups[0].Media) != 3 {
+		t.Fatalf("expected one group of 3, got %+v", groups)
+	}
+	var ids []string
+	for _, m := range groups[0].Media {
+		ids = append(ids, m.ID)
+	}
+	// 依拍攝時間新到舊，沒有時間的排最後
+	if !reflect.DeepEqual(ids, []string{c, b, a}) {
+		t.Errorf("unexpected order: %v", ids)
+	}
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Error(err)
+	}
+}
+
+func TestFindNearDuplicatesUsesChunkIndex(t *testing.T) {
+	db, mock := newMockDB(t)
+	s := &Service{DB: db}
+	const hash = int64(0x0102030405060708)
+
+	mock.ExpectQuery(`phash_0 = ANY\(\$6\) OR phash_1 = ANY\(\$7\) OR phash_2 = ANY\(\$8\) OR phash_3 = ANY\(\$9\)`).
+		WithArgs("user-1", "m1", hash, 6, maxNearDuplicateWarnings,
+			chunkNeighbors(0x0102, 1), chunkNeighbors(0x0304, 1), chunkNeighbors(0x0506, 1), chunkNeighbors(0x0708, 1)).
+		WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow("m2"))
+
+	ids, err := s.FindNearDuplicates(context.Background(), "user-1", "m1", hash, DefaultSimilarity)
+	if err != nil {
+		t.Fatalf("FindNearDuplicates failed: %v", err)
+	}
+	if !reflect.DeepEqual(ids, []string{"m2"}) {
+		t.Errorf("unexpected ids: %v", ids)
+	}
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Error(err)
+	}
+}
+
+func TestSimilarityToDistance(t *testing.T) {
+	if d := SimilarityToDistance(1); d != 0 {
+		t.Errorf("expected 0, got %d", d)
+	}
+	if d := SimilarityToDistance(DefaultSimilarity); d != 6 {
+		t.Errorf("expected 6, got %d", d)
+	}
+}
@@ -5,17 +5,90 @@ import (
 	"crypto/sha256"
 	"database/sql"
 	"encoding/hex"
+	"errors"
 	"fmt"
 	"io"
 	"mime/multipart"
 	"os"
 	"path/filepath"
+	"strings"
 	"time"
 )
 
+// mediaColumns 為新查詢共用的欄位清單 (不含 storage_path)，搭配 scanMedia 使用
+// 查詢時資料表別名必須為 m
+const mediaColumns = `m.id, m.user_id, m.original_filename, m.file_hash, m.size_bytes, m.mime_type,
+	m.width, m.height, m.duration, m.taken_at, m.latitude, m.longitude,
+	m.camera_make, m.camera_model, m.exposure_time, m.aperture, m.iso,
+	m.blur_hash, m.dominant_color, m.uploaded_at, m.deleted_at,
+	COALESCE(m.stream_status, ''), m.is_favorite, m.is_archived,
+	(SELECT COALESCE(json_agg(t.name ORDER BY lower(t.name)), '[]')
+	 FROM media_tags mt JOIN tags t ON t.id = mt.tag_id WHERE mt.media_id = m.id) AS tags,
+	m.caption,
+	m.taken_at IS DISTINCT FROM m.extracted_taken_at,
+	(m.latitude, m.longitude) IS DISTINCT FROM (m.extracted_latitude, m.extracted_longitude),
+	m.stack_id,
+	(SELECT COUNT(*) FROM media sm WHERE sm.stack_id = m.stack_id AND sm.deleted_at IS NULL) AS stack_size,
+	m.rating`
+
+// rowScanner 抽象 *sql.Row 與 *sql.Rows 的 Scan
+type rowScanner interface {
+	Scan(dest ...any) error
+}
+
+// execer 抽象 *sql.DB 與 *sql.Tx，讓同一段邏輯可在交易內外重用
+type execer interface {
+	ExecContext(ctx context.Context, query string, args ...any) (sql.Result, error)
+	QueryContext(ctx context.Context, query string, args ...any) (*sql.Rows, error)
+	QueryRowContext(ctx context.Context, query string, args ...any) *sql.Row
+}
+
+// scanMedia 依 mediaColumns 的順序掃描一筆資料，extra 為查詢額外附加的欄位
+func scanMedia(row rowScanner, m *Media, extra ...any) error {
+	dest := []any{
+		&m.ID, &m.UserID, &m.OriginalFilename, &m.FileHash, &m.SizeBytes, &m.MimeType,
+		&m.Width, &m.Height, &m.Duration, &m.TakenAt, &m.Latitude, &m.Longitude,
+		&m.CameraMake, &m.CameraModel, &m.ExposureTime, &m.Aperture, &m.ISO,
+		&m.BlurHash, &m.DominantColor, &m.UploadedAt, &m.DeletedAt,
+		&m.StreamStatus, &m.IsFavorite, &m.IsArchived, &m.Tags,
+		&m.Caption, &m.TakenAtOverridden, &m.LocationOverridden,
+		&m.StackID, &m.StackSize,
+		&m.Rating,
+	}
+	return row.Scan(append(dest, extra...)...)
+}
+
+// collectMedia 讀取所有資料列 (欄位需為 mediaColumns)
+func collectMedia(rows *sql.Rows) ([]*Media, error) {
+	list := []*Media{} // Initialize as empty slice to ensure JSON [] instead of null
+	for rows.Next() {
+		m := &Media{}
+		if err := scanMedia(rows, m); err != nil {
+			return nil, fmt.Errorf("failed to scan media: %w", err)
+		}
+		list = append(list, m)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to iterate media: %w", err)
+	}
+	return list, nil
+}
+
+// ErrMediaNotFound 媒體不存在或不屬於該使用者
+var ErrMediaNotFound = errors.New("media not found")
+
 type Service struct {
 	DB        *sql.DB
 	UploadDir string
+
+	// Transcoder 選填；設定後影片上傳完成會排入 HLS 轉檔
+	Transcoder *HLSTranscoder
+	// Variants 選填；縮圖與轉檔的磁碟快取
+	Variants *VariantCache
+	// AccountExports 選填；設定後帳號匯出會排入背景處理 (未設定時保持 pending)
+	AccountExports *AccountExporter
+	// StackWindow 連拍判定間隔，0 時使用 DefaultStackWindow
+	StackWindow time.Duration
 }
 
 func NewService(db *sql.DB, uploadDir string) *Service {
@@ -32,6 +105,25 @@ type UploadResult struct {
 	ExistingID string
 }
 
+// ingestOptions 上傳流程的選項；TakenAt / Location 為檔案中解析不到時的回退值
+type ingestOptions struct {
+	Force       bool // 已有相同 Hash 時仍建立新項目 (Keep Both)
+	SkipTrashed bool // 垃圾桶中已有相同 Hash 時回傳 skipped，不重新建立使用者刪除的項目 (重複匯入用)
+	TakenAt     *time.Time
+	Location    *Location
+	Caption     string
+	FileHash    string // 已計算的 SHA-256；空白時由 ingest 計算
+}
+
+// hashReader 計算 SHA-256 (hex)
+func hashReader(r io.Reader) (string, error) {
+	hash := sha256.New()
+	if _, err := io.Copy(hash, r); err != nil {
+		return "", fmt.Errorf("failed to calculate hash: %w", err)
+	}
+	return hex.EncodeToString(hash.Sum(nil)), nil
+}
+
 // Upload 處理檔案上傳
 func (s *Service) Upload(ctx context.Context, userID string, fileHeader *multipart.FileHeader, force bool, takenAt *time.Time) (*UploadResult, error) {
 	src, err := fileHeader.Open()
@@ -40,16 +132,23 @@ func (s *Service) Upload(ctx context.Context, userID string, fileHeader *multipa
 	}
 	defer src.Close()
 
-	// 1. 計算 Hash
-	hash := sha256.New()
-	if _, err := io.Copy(hash, src); err != nil {
-		return nil, fmt.Errorf("failed to calculate hash: %w", err)
-	}
-	fileHash := hex.EncodeToString(hash.Sum(nil))
+	return s.ingest(ctx, userID, src, fileHeader.Filename, fileHeader.Header.Get("Content-Type"), fileHeader.Size,
+		ingestOptions{Force: force, TakenAt: takenAt})
+}
 
-	// 重置讀取位置
-	if _, err := src.Seek(0, 0); err != nil {
-		return nil, fmt.Errorf("failed to seek file: %w", err)
+// ingest 上傳流程本體 (Hash、去重、儲存、解析 Metadata、寫入資料庫)，供 HTTP 上傳與伺服器端匯入共用
+func (s *Service) ingest(ctx context.Context, userID string, src io.ReadSeeker, filename, mimeType string, size int64, opts ingestOptions) (*UploadResult, error) {
+	// 1. 計算 Hash (伺服器端匯入可能已事先計算)
+	fileHash := opts.FileHash
+	if fileHash == "" {
+		var err error
+		if fileHash, err = hashReader(src); err != nil {
+			return nil, err
+		}
+		// 重置讀取位置
+		if _, err := src.Seek(0, 0); err != nil {
+			return nil, fmt.Errorf("failed to seek file: %w", err)
+		}
 	}
 
 	// 2. 檢查去重 (Deduplication)
@@ -58,10 +157,19 @@ func (s *Service) Upload(ctx context.Context, userID string, fileHeader *multipa
 		return nil, err
 	}
 
-	if !force {
+	if !opts.Force {
 		if existingID != "" {
 			return &UploadResult{Status: "conflict", ExistingID: existingID}, nil
 		}
+		if opts.SkipTrashed {
+			trashedID, err := s.checkTrashed(ctx, userID, fileHash)
+			if err != nil {
+				return nil, err
+			}
+			if trashedID != "" {
+				return &UploadResult{Status: "skipped", ExistingID: trashedID}, nil
+			}
+		}
 	}
 	// If force is true, we proceed to create a duplicate (Keep Both)
 
@@ -69,7 +177,7 @@ func (s *Service) Upload(ctx context.Context, userID string, fileHeader *multipa
 	// 路徑規則: uploads/uid/year/month/hash_timestamp.ext
 	// 加入 timestamp 以確保檔名唯一，避免覆蓋舊檔案 (因為我們允許重複)
 	now := time.Now()
-	ext := filepath.Ext(fileHeader.Filename)
+	ext := filepath.Ext(filename)
 	uniqueSuffix := fmt.Sprintf("_%d", now.UnixNano())
 	relPath := filepath.Join(userID, now.Format("2006"), now.Format("01"), fileHash+uniqueSuffix+ext)
 	absPath := filepath.Join(s.UploadDir, relPath)
@@ -90,24 +198,28 @@ func (s *Service) Upload(ctx context.Context, userID string, fileHeader *multipa
 
 	// 4. 解析 Metadata
 	// 即使解析失敗，我們仍然允許上傳，只是 Metadata 會是空的
-	meta, _ := extractMetadata(absPath, fileHeader.Header.Get("Content-Type"))
+	meta, _ := extractMetadata(absPath, mimeType)
 	if meta == nil {
 		meta = &Media{}
 	}
 
 	// 如果 EXIF 解析不到時間且客戶端有提供，則作為回退
-	if meta.TakenAt == nil && takenAt != nil {
-		meta.TakenAt = takenAt
+	if meta.TakenAt == nil && opts.TakenAt != nil {
+		meta.TakenAt = opts.TakenAt
+	}
+	if meta.Latitude == nil && opts.Location != nil {
+		meta.Latitude, meta.Longitude = &opts.Location.Latitude, &opts.Location.Longitude
 	}
 
 	// 5. 寫入資料庫
 	media := &Media{
 		UserID:           userID,
-		OriginalFilename: fileHeader.Filename,
+		OriginalFilename: filename,
 		StoragePath:      relPath,
 		FileHash:         fileHash,
-		SizeBytes:        fileHeader.Size,
-		MimeType:         fileHeader.Header.Get("Content-Type"),
+		SizeBytes:        size,
+		MimeType:         mimeType,
+		Caption:          opts.Caption,
 
 		// Metadata
 		Width:        meta.Width,
@@ -121,6 +233,19 @@ func (s *Service) Upload(ctx context.Context, userID string, fileHeader *multipa
 		ExposureTime: meta.ExposureTime,
 		Aperture:     meta.Aperture,
 		ISO:          meta.ISO,
+		BurstID:      meta.BurstID,
+	}
+	if strings.HasPrefix(media.MimeType, "video/") {
+		media.StreamStatus = StreamPending
+	}
+	media.Tags = TagList(NormalizeTags(meta.Tags))
+	if strings.HasPrefix(media.MimeType, "image/") {
+		if h, err := imageDHash(ctx, absPath, media.MimeType); err == nil {
+			phash := int64(h)
+			media.PHash = &phash
+		} else {
+			fmt.Printf("failed to compute phash for %s: %v\n", filename, err)
+		}
 	}
 
 	if err := s.insertMedia(ctx, media); err != nil {
@@ -128,6 +253,38 @@ func (s *Service) Upload(ctx context.Context, userID string, fileHeader *multipa
 		os.Remove(absPath)
 		return nil, err
 	}
+	if media.PHash != nil {
+		// 相似配對寫入失敗不影響上傳，之後由補算流程重試
+		if err := s.indexSimilar(ctx, userID, media.ID, *media.PHash); err != nil {
+			fmt.Printf("failed to index similar media for %s: %v\n", media.ID, err)
+		}
+	}
+
+	// 6. 匯入檔案內嵌的 IPTC/XMP 關鍵字作為標籤 (失敗不影響上傳)
+	if len(media.Tags) > 0 {
+		if _, err := s.addTags(ctx, s.DB, userID, []string{media.ID}, media.Tags, TagSourceEmbedded); err != nil {
+			fmt.Printf("failed to import embedded keywords for %s: %v\n", media.ID, err)
+		}
+	}
+
+	// 7. 連拍堆疊偵測 (失敗不影響上傳)
+	if strings.HasPrefix(media.MimeType, "image/") && media.TakenAt != nil {
+		if err := s.detectStacksNear(ctx, userID, *media.TakenAt); err != nil {
+			fmt.Printf("failed to detect stack for %s: %v\n", media.ID, err)
+		}
+	}
+
+	// 8. 更新拍攝時間附近的事件分群 (失敗不影響上傳，可透過重建修正)
+	if media.TakenAt != nil {
+		if err := s.refreshEventsAround(ctx, userID, *media.TakenAt); err != nil {
+			fmt.Printf("failed to refresh events for %s: %v\n", media.ID, err)
+		}
+	}
+
+	// 9. 影片非同步轉檔為 HLS
+	if media.StreamStatus == StreamPending && s.Transcoder != nil {
+		s.Transcoder.Enqueue(media.ID)
+	}
 
 	return &UploadResult{Media: media, Status: "created"}, nil
 }
@@ -145,24 +302,30 @@ func (s *Service) checkExists(ctx context.Context, userID, fileHash string) (str
 	return id, nil
 }
 
+// checkTrashed 垃圾桶中是否有相同 Hash 的項目，回傳其 ID
+func (s *Service) checkTrashed(ctx context.Context, userID, fileHash string) (string, error) {
+	var id string
+	query := `SELECT id FROM media WHERE user_id = $1 AND file_hash = $2 AND deleted_at IS NOT NULL LIMIT 1`
+	err := s.DB.QueryRowContext(ctx, query, userID, fileHash).Scan(&id)
+	if err == sql.ErrNoRows {
+		return "", nil
+	}
+	if err != nil {
+		return "", fmt.Errorf("failed to check trash: %w", err)
+	}
+	return id, nil
+}
+
 // CheckExistsByHash 公開檢查 Hash 邏輯
 func (s *Service) CheckExistsByHash(ctx context.Context, userID, hash string) (*Media, error) {
 	query := `
-		SELECT id, user_id, original_filename, storage_path, file_hash, size_bytes, mime_type,
-		       width, height, duration, taken_at, latitude, longitude,
-		       camera_make, camera_model, exposure_time, aperture, iso,
-		       blur_hash, dominant_color, uploaded_at
-		FROM media
-		WHERE user_id = $1 AND file_hash = $2 AND deleted_at IS NULL
+		SELECT ` + mediaColumns + `, m.storage_path
+		FROM media m
+		WHERE m.user_id = $1 AND m.file_hash = $2 AND m.deleted_at IS NULL
 		LIMIT 1
 	`
 	m := &Media{}
-	err := s.DB.QueryRowContext(ctx, query, userID, hash).Scan(
-		&m.ID, &m.UserID, &m.OriginalFilename, &m.StoragePath, &m.FileHash, &m.SizeBytes, &m.MimeType,
-		&m.Width, &m.Height, &m.Duration, &m.TakenAt, &m.Latitude, &m.Longitude,
-		&m.CameraMake, &m.CameraModel, &m.ExposureTime, &m.Aperture, &m.ISO,
-		&m.BlurHash, &m.DominantColor, &m.UploadedAt,
-	)
+	err := scanMedia(s.DB.QueryRowContext(ctx, query, userID, hash), m, &m.StoragePath)
 	if err != nil {
 		if err == sql.ErrNoRows {
 			return nil, nil
@@ -178,53 +341,85 @@ func (s *Service) insertMedia(ctx context.Context, m *Media) error {
 			user_id, original_filename, storage_path, file_hash, size_bytes, mime_type,
 			width, height, duration, taken_at, latitude, longitude,
 			camera_make, camera_model, exposure_time, aperture, iso,
-			blur_hash, dominant_color
+			blur_hash, dominant_color, stream_status,
+			extracted_taken_at, extracted_latitude, extracted_longitude,
+			burst_id, phash, caption
 		) VALUES (
 			$1, $2, $3, $4, $5, $6,
 			$7, $8, $9, $10, $11, $12,
 			$13, $14, $15, $16, $17,
-			$18, $19
+			$18, $19, NULLIF($20, ''),
+			$10, $11, $12,
+			NULLIF($21, ''), $22, $23
 		) RETURNING id, uploaded_at
 	`
 	return s.DB.QueryRowContext(ctx, query,
 		m.UserID, m.OriginalFilename, m.StoragePath, m.FileHash, m.SizeBytes, m.MimeType,
 		m.Width, m.Height, m.Duration, m.TakenAt, m.Latitude, m.Longitude,
 		m.CameraMake, m.CameraModel, m.ExposureTime, m.Aperture, m.ISO,
-		m.BlurHash, m.DominantColor,
+		m.BlurHash, m.DominantColor, m.StreamStatus,
+		m.BurstID, m.PHash, m.Caption,
 	).Scan(&m.ID, &m.UploadedAt)
 }
 
+// ListOptions 時間軸列表的篩選條件
+type ListOptions struct {
+	FavoritesOnly bool     // 只列出我的最愛
+	ArchivedOnly  bool     // 只列出封存項目；預設排除封存項目
+	Tags          []string // 依標籤篩選 (不分大小寫)
+	MatchAllTags  bool     // true: 需包含所有標籤 (AND)；false: 任一標籤 (OR)
+
+	IncludePartners bool // 合併選擇加入時間軸的伴侶媒體 (標記 from_partner)
+}
+
 // List 取得使用者的媒體列表
-func (s *Service) List(ctx context.Context, userID string, limit, offset int) ([]*Media, error) {
+func (s *Service) List(ctx context.Context, userID string, opts ListOptions, limit, offset int) ([]*Media, error) {
+	args := []any{userID}
+	arg := func(v any) string {
+		args = append(args, v)
+		return fmt.Sprintf("$%d", len(args))
+	}
+
+	conds := []string{"m.user_id = $1", "m.deleted_at IS NULL"}
+	if opts.IncludePartners {
+		conds[0] = timelineOwners("$1")
+	}
+	if opts.ArchivedOnly {
+		conds = append(conds, "m.is_archived")
+	} else {
+		conds = append(conds, "NOT m.is_archived")
+	}
+	if opts.FavoritesOnly {
+		conds = append(conds, "m.is_favorite")
+	}
+	tags := NormalizeTags(opts.Tags)
+	if len(tags) > 0 {
+		conds = append(conds, tagFilterCondition(arg, tags, opts.MatchAllTags))
+	}
+	// 只有一般時間軸收合堆疊：篩選我的最愛或標籤時，符合的可能不是封面，需逐一列出
+	if !opts.FavoritesOnly && len(tags) == 0 {
+		conds = append(conds, stackCollapsedCondition)
+	}
+
 	query := `
-		SELECT id, user_id, original_filename, file_hash, size_bytes, mime_type,
-		       width, height, duration, taken_at, latitude, longitude,
-		       camera_make, camera_model, exposure_time, aperture, iso,
-		       blur_hash, dominant_color, uploaded_at
-		FROM media
-		WHERE user_id = $1 AND deleted_at IS NULL
-		ORDER BY taken_at DESC NULLS LAST, uploaded_at DESC
-		LIMIT $2 OFFSET $3
-	`
-	rows, err := s.DB.QueryContext(ctx, query, userID, limit, offset)
+		SELECT ` + mediaColumns + `
+		FROM media m
+		WHERE ` + strings.Join(conds, " AND ") + `
+		ORDER BY m.taken_at DESC NULLS LAST, m.uploaded_at DESC
+		LIMIT ` + arg(limit) + ` OFFSET ` + arg(offset)
+
+	rows, err := s.DB.QueryContext(ctx, query, args...)
 	if err != nil {
 		return nil, fmt.Errorf("failed to query media: %w", err)
 	}
 	defer rows.Close()
 
-	list := []*Media{} // Initialize as empty slice to ensure JSON [] instead of null
-	for rows.Next() {
-		m := &Media{}
-		err := rows.Scan(
-			&m.ID, &m.UserID, &m.OriginalFilename, &m.FileHash, &m.SizeBytes, &m.MimeType,
-			&m.Width, &m.Height, &m.Duration, &m.TakenAt, &m.Latitude, &m.Longitude,
-			&m.CameraMake, &m.CameraModel, &m.ExposureTime, &m.Aperture, &m.ISO,
-			&m.BlurHash, &m.DominantColor, &m.UploadedAt,
-		)
-		if err != nil {
-			return nil, fmt.Errorf("failed to scan media: %w", err)
-		}
-		list = append(list, m)
+	list, err := collectMedia(rows)
+	if err != nil {
+		return nil, err
+	}
+	if opts.IncludePartners {
+		markPartnerMedia(list, userID)
 	}
 	return list, nil
 }
@@ -232,20 +427,12 @@ func (s *Service) List(ctx context.Context, userID string, limit, offset int) ([
 // GetByID 取得單一媒體（包含 storage_path）
 func (s *Service) GetByID(ctx context.Context, userID string, mediaID string) (*Media, error) {
 	query := `
-		SELECT id, user_id, original_filename, storage_path, file_hash, size_bytes, mime_type,
-		       width, height, duration, taken_at, latitude, longitude,
-		       camera_make, camera_model, exposure_time, aperture, iso,
-		       blur_hash, dominant_color, uploaded_at, deleted_at
-		FROM media
-		WHERE id = $1 AND user_id = $2
+		SELECT ` + mediaColumns + `, m.storage_path
+		FROM media m
+		WHERE m.id = $1 AND m.user_id = $2
 	`
 	m := &Media{}
-	err := s.DB.QueryRowContext(ctx, query, mediaID, userID).Scan(
-		&m.ID, &m.UserID, &m.OriginalFilename, &m.StoragePath, &m.FileHash, &m.SizeBytes, &m.MimeType,
-		&m.Width, &m.Height, &m.Duration, &m.TakenAt, &m.Latitude, &m.Longitude,
-		&m.CameraMake, &m.CameraModel, &m.ExposureTime, &m.Aperture, &m.ISO,
-		&m.BlurHash, &m.DominantColor, &m.UploadedAt, &m.DeletedAt,
-	)
+	err := scanMedia(s.DB.QueryRowContext(ctx, query, mediaID, userID), m, &m.StoragePath)
 	if err != nil {
 		if err == sql.ErrNoRows {
 			return nil, fmt.Errorf("media not found")
@@ -255,16 +442,42 @@ func (s *Service) GetByID(ctx context.Context, userID string, mediaID string) (*
 	return m, nil
 }
 
+// mediaSharedWith 媒體 (別名 m) 位於使用者 (userParam) 擁有或受邀加入的相簿中
+func mediaSharedWith(userParam string) string {
+	return `EXISTS (
+		SELECT 1 FROM album_media sam
+		JOIN albums a ON a.id = sam.album_id
+		` + albumMemberJoin + userParam + `
+		WHERE sam.media_id = m.id AND ` + albumAccessible(userParam) + `)`
+}
+
+// GetAccessibleByID 取得可讀取的單一媒體：自己的媒體 (含垃圾桶)，
+// 或位於自己擁有、受邀加入之相簿中、伴侶分享授權範圍內的他人媒體 (不含垃圾桶)；不會揭露擁有者的其他媒體
+func (s *Service) GetAccessibleByID(ctx context.Context, userID string, mediaID string) (*Media, error) {
+	query := `
+		SELECT ` + mediaColumns + `, m.storage_path
+		FROM media m
+		WHERE m.id = $1 AND (m.user_id = $2 OR (m.deleted_at IS NULL AND (
+			` + mediaSharedWith("$2") + ` OR ` + partnerVisible("$2", false) + `)))
+	`
+	m := &Media{}
+	err := scanMedia(s.DB.QueryRowContext(ctx, query, mediaID, userID), m, &m.StoragePath)
+	if err == sql.ErrNoRows {
+		return nil, ErrMediaNotFound
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to query media: %w", err)
+	}
+	return m, nil
+}
+
 // ListTrash 取得垃圾桶中的媒體列表
 func (s *Service) ListTrash(ctx context.Context, userID string, limit, offset int) ([]*Media, error) {
 	query := `
-		SELECT id, user_id, original_filename, file_hash, size_bytes, mime_type,
-		       width, height, duration, taken_at, latitude, longitude,
-		       camera_make, camera_model, exposure_time, aperture, iso,
-		       blur_hash, dominant_color, uploaded_at, deleted_at
-		FROM media
-		WHERE user_id = $1 AND deleted_at IS NOT NULL
-		ORDER BY deleted_at DESC
+		SELECT ` + mediaColumns + `
+		FROM media m
+		WHERE m.user_id = $1 AND m.deleted_at IS NOT NULL
+		ORDER BY m.deleted_at DESC
 		LIMIT $2 OFFSET $3
 	`
 	rows, err := s.DB.QueryContext(ctx, query, userID, limit, offset)
@@ -273,21 +486,7 @@ func (s *Service) ListTrash(ctx context.Context, userID string, limit, offset in
 	}
 	defer rows.Close()
 
-	list := []*Media{}
-	for rows.Next() {
-		m := &Media{}
-		err := rows.Scan(
-			&m.ID, &m.UserID, &m.OriginalFilename, &m.FileHash, &m.SizeBytes, &m.MimeType,
-			&m.Width, &m.Height, &m.Duration, &m.TakenAt, &m.Latitude, &m.Longitude,
-			&m.CameraMake, &m.CameraModel, &m.ExposureTime, &m.Aperture, &m.ISO,
-			&m.BlurHash, &m.DominantColor, &m.UploadedAt, &m.DeletedAt,
-		)
-		if err != nil {
-			return nil, fmt.Errorf("failed to scan media: %w", err)
-		}
-		list = append(list, m)
-	}
-	return list, nil
+	return collectMedia(rows)
 }
 
 // Restore 還原媒體
@@ -340,12 +539,21 @@ func (s *Service) DeletePermanent(ctx context.Context, userID string, mediaID st
 	}
 
 	// 3. 刪除實體檔案
+	s.removeMediaFiles(mediaID, storagePath)
+
+	return nil
+}
+
+// removeMediaFiles 刪除原始檔與 HLS 輸出 (資料庫記錄刪除後呼叫，失敗僅記錄)
+// 衍生縮圖以 file_hash 為鍵且可能被其他媒體共用，交由快取的 LRU 淘汰
+func (s *Service) removeMediaFiles(mediaID, storagePath string) {
 	absPath := filepath.Join(s.UploadDir, storagePath)
 	if err := os.Remove(absPath); err != nil {
 		fmt.Printf("failed to delete file %s: %v\n", absPath, err)
 	}
-
-	return nil
+	if err := os.RemoveAll(s.hlsDir(mediaID)); err != nil {
+		fmt.Printf("failed to delete hls directory for %s: %v\n", mediaID, err)
+	}
 }
 
 // Delete 刪除媒體 (軟刪除)
@@ -0,0 +1,326 @@
+package media
+
+import (
+	"context"
+	"crypto/rand"
+	"database/sql"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"regexp"
+	"time"
+)
+
+var ErrShareNotFound = errors.New("share link not found")
+
+// shareTokenBytes 分享 token 的隨機位元組數 (256 bits，無法猜測)
+const shareTokenBytes = 32
+
+// shareTokenPattern 用於驗證路徑中的 token (base64url，無 padding)
+var shareTokenPattern = regexp.MustCompile(`^[A-Za-z0-9_-]{43}$`)
+
+// shareLinkColumns 分享連結查詢共用欄位 (資料表別名須為 sl)
+// 標題未指定時使用相簿標題；數量不含垃圾桶中的媒體
+const shareLinkColumns = `sl.id, sl.token, sl.album_id,
+	COALESCE(NULLIF(sl.title, ''), (SELECT a.title FROM albums a WHERE a.id = sl.album_id), ''),
+	sl.allow_download, sl.expires_at, sl.view_count, sl.last_viewed_at, sl.created_at,
+	(SELECT COUNT(*) FROM ` + shareMembers + ` WHERE m.deleted_at IS NULL) AS media_count`
+
+// shareMembers 分享連結 (別名 sl) 的成員媒體 (別名 m)，排序欄位為 sm.position 與 sm.added_at
+// 由 album_media / share_link_media 出發，不掃描擁有者的整個圖庫；只包含連結擁有者的媒體
+// 與 share_links 同層時需加 CROSS JOIN LATERAL
+const shareMembers = `(
+		SELECT am.media_id, am.position, am.added_at FROM album_media am WHERE am.album_id = sl.album_id
+		UNION ALL
+		SELECT slm.media_id, slm.position, NULL FROM share_link_media slm WHERE slm.share_id = sl.id
+	) sm
+	JOIN media m ON m.id = sm.media_id AND m.user_id = sl.user_id`
+
+// shareLinkActive 未撤銷且未過期
+const shareLinkActive = `sl.revoked_at IS NULL AND (sl.expires_at IS NULL OR sl.expires_at > NOW())`
+
+func scanShareLink(row rowScanner, l *ShareLink) error {
+	return row.Scan(&l.ID, &l.Token, &l.AlbumID, &l.Title, &l.AllowDownload, &l.ExpiresAt,
+		&l.ViewCount, &l.LastViewedAt, &l.CreatedAt, &l.MediaCount)
+}
+
+// ShareLinkRequest 建立分享連結的參數；AlbumID 與 MediaIDs 必須擇一
+type ShareLinkRequest struct {
+	AlbumID       string     `json:"album_id"`
+	MediaIDs      []string   `json:"media_ids"` // 依此順序顯示
+	Title         string     `json:"title"`
+	AllowDownload bool       `json:"allow_download"`
+	ExpiresAt     *time.Time `json:"expires_at"` // 省略代表永不過期
+}
+
+// Validate 檢查參數
+func (r *ShareLinkRequest) Validate(now time.Time) error {
+	switch {
+	case r.AlbumID == "" && len(r.MediaIDs) == 0:
+		return fmt.Errorf("album_id or media_ids is required")
+	case r.AlbumID != "" && len(r.MediaIDs) > 0:
+		return fmt.Errorf("album_id and media_ids are mutually exclusive")
+	case r.AlbumID != "" && !uuidPattern.MatchString(r.AlbumID):
+		return fmt.Errorf("invalid album_id")
+	}
+	if len(r.MediaIDs) > 0 {
+		if err := validateIDs(r.MediaIDs); err != nil {
+			return err
+		}
+	}
+	if len(r.Title) > 255 {
+		return fmt.Errorf("title is too long (max 255 characters)")
+	}
+	if r.ExpiresAt != nil && !r.ExpiresAt.After(now) {
+		return fmt.Errorf("expires_at must be in the future")
+	}
+	return nil
+}
+
+// SharedMedia 分享頁面中的媒體：只包含顯示所需的欄位，不公開擁有者、GPS、檔案 Hash 與標籤
+type SharedMedia struct {
+	ID               string     `json:"id"`
+	OriginalFilename string     `json:"original_filename"`
+	Width            int        `json:"width"`
+	Height           int        `json:"height"`
+	Duration         float64    `json:"duration"`
+	MimeType         string     `json:"mime_type"`
+	TakenAt          *time.Time `json:"taken_at"`
+	BlurHash         string     `json:"blur_hash"`
+	DominantColor    string     `json:"dominant_color"`
+	Caption          string     `json:"caption"`
+	HasStream        bool       `json:"has_stream"` // 可播放 /s/:token/media/:id/hls/master.m3u8
+}
+
+func newSharedMedia(m *Media) *SharedMedia {
+	return &SharedMedia{
+		ID:               m.ID,
+		OriginalFilename: m.OriginalFilename,
+		Width:            m.Width,
+		Height:           m.Height,
+		Duration:         m.Duration,
+		MimeType:         m.MimeType,
+		TakenAt:          m.TakenAt,
+		BlurHash:         m.BlurHash,
+		DominantColor:    m.DominantColor,
+		Caption:          m.Caption,
+		HasStream:        m.StreamStatus == StreamReady,
+	}
+}
+
+// SharedView 分享頁面的摘要 (不含連結 ID 與瀏覽次數)
+type SharedView struct {
+	Title         string     `json:"title"`
+	AllowDownload bool       `json:"allow_download"`
+	ExpiresAt     *time.Time `json:"expires_at"`
+	MediaCount    int        `json:"media_count"`
+}
+
+// newShareToken 產生 URL 安全的隨機 token
+func newShareToken() (string, error) {
+	b := make([]byte, shareTokenBytes)
+	if _, err := rand.Read(b); err != nil {
+		return "", fmt.Errorf("failed to generate token: %w", err)
+	}
+	return base64.RawURLEncoding.EncodeToString(b), nil
+}
+
+// CreateShareLink 建立分享連結；選取的媒體只能是自己的且不在垃圾桶中，不存在的項目略過
+func (s *Service) CreateShareLink(ctx context.Context, userID string, req ShareLinkRequest) (*ShareLink, error) {
+	if req.AlbumID != "" {
+		if err := s.ensureAlbum(ctx, userID, req.AlbumID); err != nil {
+			return nil, err
+		}
+	}
+	token, err := newShareToken()
+	if err != nil {
+		return nil, err
+	}
+
+	tx, err := s.DB.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	var shareID string
+	err = tx.QueryRowContext(ctx, `
+		INSERT INTO share_links (user_id, token, album_id, title, allow_download, expires_at)
+		VALUES ($1, $2, NULLIF($3, '')::uuid, $4, $5, $6)
+		RETURNING id
+	`, userID, token, req.AlbumID, req.Title, req.AllowDownload, req.ExpiresAt).Scan(&shareID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to insert share link: %w", err)
+	}
+
+	if len(req.MediaIDs) > 0 {
+		res, err := tx.ExecContext(ctx, `
+			INSERT INTO share_link_media (share_id, media_id, position)
+			SELECT $1, m.id, MIN(t.ord)
+			FROM unnest($2::uuid[]) WITH ORDINALITY AS t(id, ord)
+			JOIN media m ON m.id = t.id
+			WHERE m.user_id = $3 AND m.deleted_at IS NULL
+			GROUP BY m.id
+		`, shareID, req.MediaIDs, userID)
+		if err != nil {
+			return nil, fmt.Errorf("failed to insert share media: %w", err)
+		}
+		if n, _ := res.RowsAffected(); n == 0 {
+			return nil, ErrMediaNotFound
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, fmt.Errorf("failed to commit: %w", err)
+	}
+	return s.getShareLink(ctx, userID, shareID)
+}
+
+// ListShareLinks 列出有效 (未撤銷且未過期) 的分享連結與瀏覽次數，最新建立者優先
+func (s *Service) ListShareLinks(ctx context.Context, userID string) ([]*ShareLink, error) {
+	query := `
+		SELECT ` + shareLinkColumns + `
+		FROM share_links sl
+		WHERE sl.user_id = $1 AND ` + shareLinkActive + `
+		ORDER BY sl.created_at DESC, sl.id
+	`
+	rows, err := s.DB.QueryContext(ctx, query, userID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query share links: %w", err)
+	}
+	defer rows.Close()
+
+	links := []*ShareLink{}
+	for rows.Next() {
+		l := &ShareLink{}
+		if err := scanShareLink(rows, l); err != nil {
+			return nil, fmt.Errorf("failed to scan share link: %w", err)
+		}
+		links = append(links, l)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to iterate share links: %w", err)
+	}
+	return links, nil
+}
+
+// getShareLink 取得單一分享連結 (包含已撤銷或過期者)
+func (s *Service) getShareLink(ctx context.Context, userID, shareID string) (*ShareLink, error) {
+	query := `
+		SELECT ` + shareLinkColumns + `
+		FROM share_links sl
+		WHERE sl.id = $1 AND sl.user_id = $2
+	`
+	l := &ShareLink{}
+	err := scanShareLink(s.DB.QueryRowContext(ctx, query, shareID, userID), l)
+	if err == sql.ErrNoRows {
+		return nil, ErrShareNotFound
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to query share link: %w", err)
+	}
+	return l, nil
+}
+
+// RevokeShareLink 撤銷分享連結，立即失效
+func (s *Service) RevokeShareLink(ctx context.Context, userID, shareID string) error {
+	res, err := s.DB.ExecContext(ctx, `
+		UPDATE share_links SET revoked_at = NOW()
+		WHERE id = $1 AND user_id = $2 AND revoked_at IS NULL
+	`, shareID, userID)
+	if err != nil {
+		return fmt.Errorf("failed to revoke share link: %w", err)
+	}
+	if n, _ := res.RowsAffected(); n == 0 {
+		return ErrShareNotFound
+	}
+	return nil
+}
+
+// OpenShareLink 以 token 開啟分享頁面並累計瀏覽次數；撤銷、過期或不存在時回傳 ErrShareNotFound
+func (s *Service) OpenShareLink(ctx context.Context, token string) (*ShareLink, error) {
+	query := `
+		UPDATE share_links sl SET view_count = sl.view_count + 1, last_viewed_at = NOW()
+		WHERE sl.token = $1 AND ` + shareLinkActive + `
+		RETURNING ` + shareLinkColumns
+	l := &ShareLink{}
+	err := scanShareLink(s.DB.QueryRowContext(ctx, query, token), l)
+	if err == sql.ErrNoRows {
+		return nil, ErrShareNotFound
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to open share link: %w", err)
+	}
+	return l, nil
+}
+
+// resolveShareToken 取得 token 對應的有效分享連結 ID 與設定 (不累計瀏覽次數)
+func (s *Service) resolveShareToken(ctx context.Context, token string) (shareID string, allowDownload bool, err error) {
+	err = s.DB.QueryRowContext(ctx, `
+		SELECT sl.id, sl.allow_download FROM share_links sl
+		WHERE sl.token = $1 AND `+shareLinkActive,
+		token).Scan(&shareID, &allowDownload)
+	if err == sql.ErrNoRows {
+		return "", false, ErrShareNotFound
+	}
+	if err != nil {
+		return "", false, fmt.Errorf("failed to query share link: %w", err)
+	}
+	return shareID, allowDownload, nil
+}
+
+// ListSharedMedia 列出分享連結中的媒體 (相簿依手動排序，選取項目依建立時的順序)
+func (s *Service) ListSharedMedia(ctx context.Context, token string, limit, offset int) ([]*SharedMedia, error) {
+	shareID, _, err := s.resolveShareToken(ctx, token)
+	if err != nil {
+		return nil, err
+	}
+
+	query := `
+		SELECT ` + mediaColumns + `
+		FROM share_links sl
+		CROSS JOIN LATERAL ` + shareMembers + `
+		WHERE sl.id = $1 AND m.deleted_at IS NULL
+		ORDER BY sm.position, sm.added_at, m.id
+		LIMIT $2 OFFSET $3
+	`
+	rows, err := s.DB.QueryContext(ctx, query, shareID, limit, offset)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query shared media: %w", err)
+	}
+	defer rows.Close()
+
+	list, err := collectMedia(rows)
+	if err != nil {
+		return nil, err
+	}
+	shared := make([]*SharedMedia, 0, len(list))
+	for _, m := range list {
+		shared = append(shared, newSharedMedia(m))
+	}
+	return shared, nil
+}
+
+// GetSharedMedia 取得分享連結中的單一媒體 (含儲存路徑) 與是否允許下載原始檔
+func (s *Service) GetSharedMedia(ctx context.Context, token, mediaID string) (*Media, bool, error) {
+	shareID, allowDownload, err := s.resolveShareToken(ctx, token)
+	if err != nil {
+		return nil, false, err
+	}
+
+	query := `
+		SELECT ` + mediaColumns + `, m.storage_path
+		FROM share_links sl
+		CROSS JOIN LATERAL ` + shareMembers + `
+		WHERE sl.id = $1 AND m.id = $2 AND m.deleted_at IS NULL
+	`
+	m := &Media{}
+	err = scanMedia(s.DB.QueryRowContext(ctx, query, shareID, mediaID), m, &m.StoragePath)
+	if err == sql.ErrNoRows {
+		return nil, false, ErrMediaNotFound
+	}
+	if err != nil {
+		return nil, false, fmt.Errorf("failed to query shared media: %w", err)
+	}
+	return m, allowDownload, nil
+}
@@ -0,0 +1,201 @@
+package media
+
+import (
+	"errors"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// respondShareError 將分享連結錯誤轉換為 HTTP 狀態碼
+// 撤銷、過期與不存在的連結一律回應 404，不透露連結曾經存在
+func respondShareError(c *gin.Context, err error) {
+	switch {
+	case errors.Is(err, ErrShareNotFound), errors.Is(err, ErrAlbumNotFound), errors.Is(err, ErrMediaNotFound):
+		c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+	default:
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+	}
+}
+
+// shareTokenParam 取得並驗證路徑中的分享 token
+func shareTokenParam(c *gin.Context) (string, bool) {
+	token := c.Param("token")
+	if !shareTokenPattern.MatchString(token) {
+		c.JSON(http.StatusNotFound, gin.H{"error": ErrShareNotFound.Error()})
+		return "", false
+	}
+	return token, true
+}
+
+// CreateShareLinkHandler 建立公開分享連結 (POST /shares)
+// body: {"album_id": "..."} 或 {"media_ids": [...]}，可選 title、allow_download、expires_at
+func (h *Handler) CreateShareLinkHandler(c *gin.Context) {
+	userID := c.MustGet("userID").(string)
+
+	var req ShareLinkRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid request body"})
+		return
+	}
+	if err := req.Validate(time.Now()); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	req.AlbumID = strings.ToLower(req.AlbumID)
+
+	link, err := h.Service.CreateShareLink(c.Request.Context(), userID, req)
+	if err != nil {
+		respondShareError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusCreated, link)
+}
+
+// ListShareLinksHandler 列出有效的分享連結與瀏覽次數 (GET /shares)
+func (h *Handler) ListShareLinksHandler(c *gin.Context) {
+	userID := c.MustGet("userID").(string)
+
+	links, err := h.Service.ListShareLinks(c.Request.Context(), userID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	jsonWithETag(c, http.StatusOK, links)
+}
+
+// RevokeShareLinkHandler 撤銷分享連結 (DELETE /shares/:id)
+func (h *Handler) RevokeShareLinkHandler(c *gin.Context) {
+	userID := c.MustGet("userID").(string)
+	shareID := c.Param("id")
+	if !uuidPattern.MatchString(shareID) {
+		c.JSON(http.StatusNotFound, gin.H{"error": ErrShareNotFound.Error()})
+		return
+	}
+
+	if err := h.Service.RevokeShareLink(c.Request.Context(), userID, shareID); err != nil {
+		respondShareError(c, err)
+		return
+	}
+
+	c.Status(http.StatusNoContent)
+}
+
+// 以下為公開路由 (群組 /s，不經過 AuthMiddleware)，只能存取 token 對應的分享內容
+// 回應不可被共用快取儲存，撤銷後每次重新驗證即失效
+
+// RegisterShareRoutes 掛載公開分享路由；r 須在 AuthMiddleware 之外 (訪客沒有 Authorization 標頭)
+func (h *Handler) RegisterShareRoutes(r gin.IRouter) {
+	shared := r.Group("/s/:token")
+	shared.GET("", h.GetSharedViewHandler)
+	shared.GET("/media", h.ListSharedMediaHandler)
+	shared.GET("/media/:id/file", h.GetSharedFileHandler)
+	shared.GET("/media/:id/hls/*file", h.SharedStreamHandler)
+}
+
+// GetSharedViewHandler 開啟分享頁面並累計瀏覽次數 (GET /s/:token)
+func (h *Handler) GetSharedViewHandler(c *gin.Context) {
+	token, ok := shareTokenParam(c)
+	if !ok {
+		return
+	}
+
+	link, err := h.Service.OpenShareLink(c.Request.Context(), token)
+	if err != nil {
+		respondShareError(c, err)
+		return
+	}
+
+	c.Header("Cache-Control", "no-store")
+	c.JSON(http.StatusOK, SharedView{
+		Title:         link.Title,
+		AllowDownload: link.AllowDownload,
+		ExpiresAt:     link.ExpiresAt,
+		MediaCount:    link.MediaCount,
+	})
+}
+
+// ListSharedMediaHandler 列出分享的媒體 (GET /s/:token/media?page=1&limit=20)
+func (h *Handler) ListSharedMediaHandler(c *gin.Context) {
+	token, ok := shareTokenParam(c)
+	if !ok {
+		return
+	}
+	limit, offset := parsePagination(c)
+
+	list, err := h.Service.ListSharedMedia(c.Request.Context(), token, limit, offset)
+	if err != nil {
+		respondShareError(c, err)
+		return
+	}
+
+	jsonWithETag(c, http.StatusOK, list)
+}
+
+// sharedMediaParam 驗證 token 與媒體 ID 並取得分享連結中的媒體；失敗時已回應
+func (h *Handler) sharedMediaParam(c *gin.Context) (*Media, bool, bool) {
+	token, ok := shareTokenParam(c)
+	if !ok {
+		return nil, false, false
+	}
+	mediaID := c.Param("id")
+	if !uuidPattern.MatchString(mediaID) {
+		c.JSON(http.StatusNotFound, gin.H{"error": ErrMediaNotFound.Error()})
+		return nil, false, false
+	}
+
+	media, allowDownload, err := h.Service.GetSharedMedia(c.Request.Context(), token, mediaID)
+	if err != nil {
+		respondShareError(c, err)
+		return nil, false, false
+	}
+	return media, allowDownload, true
+}
+
+// GetSharedFileHandler 提供分享媒體的檔案 (GET /s/:token/media/:id/file)
+// 參數與 GetFileHandler 相同，預設一律為顯示用的衍生檔案 (已移除 EXIF 等中繼資料)；
+// format=original 取得原始檔，未允許下載時回應 403
+func (h *Handler) GetSharedFileHandler(c *gin.Context) {
+	media, allowDownload, ok := h.sharedMediaParam(c)
+	if !ok {
+		return
+	}
+
+	opts, original, err := parseVariantQuery(c)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if original && !allowDownload {
+		c.JSON(http.StatusForbidden, gin.H{"error": "downloading originals is not allowed for this link"})
+		return
+	}
+
+	h.serveMedia(c, media, sharedETagBase(media), opts, original, listCacheControl)
+}
+
+// SharedStreamHandler 提供分享影片的 HLS 串流 (GET /s/:token/media/:id/hls/*file)
+// playlist 內為相對路徑，片段沿用同一 token；每個請求都重新驗證連結，撤銷後立即失效
+func (h *Handler) SharedStreamHandler(c *gin.Context) {
+	file := strings.TrimPrefix(c.Param("file"), "/")
+	if !hlsFilePattern.MatchString(file) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid stream file"})
+		return
+	}
+	media, _, ok := h.sharedMediaParam(c)
+	if !ok {
+		return
+	}
+
+	h.serveStream(c, media, sharedETagBase(media), file, listCacheControl)
+}
+
+// sharedETagBase 公開路由的 ETag 基底：匿名訪客不得從 ETag 得知原始檔的 SHA-256
+// (可用來比對是否持有特定檔案)；媒體的檔案建立後不會被取代，以媒體 ID 區分即可
+func sharedETagBase(media *Media) string {
+	return media.ID
+}
@@ -0,0 +1,159 @@
+package media
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/gin-gonic/gin"
+)
+
+func TestNewShareToken(t *testing.T) {
+	a, err := newShareToken()
+	if err != nil {
+		t.Fatalf("newShareToken failed: %v", err)
+	}
+	b, _ := newShareToken()
+	if !shareTokenPattern.MatchString(a) || a == b {
+		t.Errorf("unexpected tokens: %q, %q", a, b)
+	}
+}
+
+func TestShareLinkRequestValidate(t *testing.T) {
+	now := time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC)
+	past := now.Add(-time.Hour)
+	invalid := []ShareLinkRequest{
+		{},
+		{AlbumID: batchActiveID, MediaIDs: []string{batchActiveID}},
+		{AlbumID: "not-a-uuid"},
+		{MediaIDs: []string{"not-a-uuid"}},
+		{AlbumID: batchActiveID, ExpiresAt: &past},
+	}
+	for _, req := range invalid {
+		if err := req.Validate(now); err == nil {
+			t.Errorf("expected error for %+v", req)
+		}
+	}
+	future := now.Add(24 * time.Hour)
+	valid := ShareLinkRequest{MediaIDs: []string{batchActiveID}, ExpiresAt: &future}
+	if err := valid.Validate(now); err != nil {
+		t.Errorf("unexpected error: %v", err)
+	}
+}
+
+func TestSharedFileRequiresDownloadPermission(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	db, mock := newMockDB(t)
+	h := NewHandler(&Service{DB: db})
+
+	token, _ := newShareToken()
+	mock.ExpectQuery(`SELECT sl.id, sl.allow_download FROM share_links sl`).
+		WithArgs(token).
+		WillReturnRows(sqlmock.NewRows([]string{"id", "allow_download"}).AddRow("share-1", false))
+	row := append(mediaTestRow(batchActiveID, time.Now()), "a.jpg")
+	mock.ExpectQuery(`FROM share_links sl`).
+		WithArgs("share-1", batchActiveID).
+		WillReturnRows(sqlmock.NewRows(append(mediaTestColumns, "storage_path")).AddRow(row...))
+
+	w := httptest.NewRecorder()
+	c, _ := gin.CreateTestContext(w)
+	c.Request = httptest.NewRequest(http.MethodGet, "/s/"+token+"/media/"+batchActiveID+"/file?format=original", nil)
+	c.Params = gin.Params{{Key: "token", Value: token}, {Key: "id", Value: batchActiveID}}
+	h.GetSharedFileHandler(c)
+
+	if w.Code != http.StatusForbidden {
+		t.Errorf("expected 403, got %d", w.Code)
+	}
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("unmet expectations: %v", err)
+	}
+}
+
+// 公開路由不需 Authorization 標頭；影片的 playlist 與片段以同一 token 存取，撤銷後失效
+func TestShareRoutesServeStreamWithoutAuth(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	db, mock := newMockDB(t)
+	s := &Service{DB: db, UploadDir: t.TempDir()}
+	h := NewHandler(s)
+	r := gin.New()
+	h.RegisterShareRoutes(r)
+
+	writeTakeoutFile(t, filepath.Join(s.hlsDir(batchActiveID), "master.m3u8"), "#EXTM3U\n720p/index.m3u8\n")
+	writeTakeoutFile(t, filepath.Join(s.hlsDir(batchActiveID), "720p", "seg_000.ts"), "segment")
+
+	token, _ := newShareToken()
+	video := mediaTestRow(batchActiveID, time.Now())
+	video[5], video[21] = "video/mp4", StreamReady // mime_type, stream_status
+	expectShared := func() {
+		mock.ExpectQuery(`SELECT sl.id, sl.allow_download FROM share_links sl`).
+			WithArgs(token).
+			WillReturnRows(sqlmock.NewRows([]string{"id", "allow_download"}).AddRow("share-1", false))
+		mock.ExpectQuery(`FROM share_links sl`).
+			WithArgs("share-1", batchActiveID).
+			WillReturnRows(sqlmock.NewRows(append(mediaTestColumns, "storage_path")).AddRow(append(video, "a.mp4")...))
+	}
+	get := func(path string) *httptest.ResponseRecorder {
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/s/"+token+"/media/"+batchActiveID+"/hls/"+path, nil))
+		return w
+	}
+
+	expectShared()
+	if w := get("master.m3u8"); w.Code != http.StatusOK || w.Header().Get("Content-Type") != "application/vnd.apple.mpegurl" {
+		t.Errorf("expected playlist, got %d %s", w.Code, w.Header().Get("Content-Type"))
+	}
+	expectShared()
+	if w := get("720p/seg_000.ts"); w.Code != http.StatusOK || w.Body.String() != "segment" || w.Header().Get("Cache-Control") != listCacheControl {
+		t.Errorf("expected revalidated segment, got %d %q %s", w.Code, w.Body.String(), w.Header().Get("Cache-Control"))
+	} else if etag := w.Header().Get("ETag"); etag == "" || strings.Contains(etag, video[3].(string)) {
+		t.Errorf("ETag must not reveal the file hash: %s", etag) // file_hash
+	}
+	if w := get("../../secret"); w.Code != http.StatusBadRequest && w.Code != http.StatusNotFound {
+		t.Errorf("expected path traversal to be rejected, got %d", w.Code)
+	}
+
+	// 撤銷後 resolveShareToken 找不到連結
+	mock.ExpectQuery(`SELECT sl.id, sl.allow_download FROM share_links sl`).
+		WithArgs(token).
+		WillReturnRows(sqlmock.NewRows([]string{"id", "allow_download"}))
+	if w := get("720p/seg_000.ts"); w.Code != http.StatusNotFound {
+		t.Errorf("expected revoked link to be 404, got %d", w.Code)
+	}
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("unmet expectations: %v", err)
+	}
+}
+
+// 成員由 album_media / share_link_media 出發，不從擁有者的整個圖庫過濾
+func TestListSharedMediaDrivenByMembers(t *testing.T) {
+	db, mock := newMockDB(t)
+	s := &Service{DB: db}
+
+	token, _ := newShareToken()
+	mock.ExpectQuery(`SELECT sl.id, sl.allow_download FROM share_links sl`).
+		WithArgs(token).
+		WillReturnRows(sqlmock.NewRows([]string{"id", "allow_download"}).AddRow("share-1", false))
+	mock.ExpectQuery(`FROM share_links sl\s+CROSS JOIN LATERAL \(\s+`+
+		`SELECT am.media_id, am.position, am.added_at FROM album_media am WHERE am.album_id = sl.album_id\s+UNION ALL\s+`+
+		`SELECT slm.media_id, slm.position, NULL FROM share_link_media slm WHERE slm.share_id = sl.id\s+\) sm\s+`+
+		`JOIN media m ON m.id = sm.media_id AND m.user_id = sl.user_id\s+`+
+		`WHERE sl.id = \$1 AND m.deleted_at IS NULL\s+ORDER BY sm.position, sm.added_at, m.id`).
+		WithArgs("share-1", 50, 0).
+		WillReturnRows(sqlmock.NewRows(mediaTestColumns).AddRow(mediaTestRow(batchActiveID, time.Now())...))
+
+	list, err := s.ListSharedMedia(context.Background(), token, 50, 0)
+	if err != nil {
+		t.Fatalf("ListSharedMedia failed: %v", err)
+	}
+	if len(list) != 1 || list[0].ID != batchActiveID {
+		t.Errorf("unexpected shared media: %+v", list)
+	}
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("unmet expectations: %v", err)
+	}
+}
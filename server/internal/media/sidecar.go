@@ -0,0 +1,133 @@
+package media
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+// MaxSidecarSize XMP sidecar 的大小上限 (一般只有數 KB)
+const MaxSidecarSize = 1 << 20
+
+// xmpSidecarExt sidecar 的副檔名；匯出時使用 darktable 的命名 (IMG_0001.JPG.xmp)，避免同名不同格式的檔案互相覆蓋
+const xmpSidecarExt = ".xmp"
+
+// ErrInvalidSidecar 內容不是 XMP 或格式錯誤
+var ErrInvalidSidecar = errors.New("invalid XMP sidecar")
+
+// readSidecar 讀取並解析 XMP sidecar
+func readSidecar(r io.Reader) (*xmpData, error) {
+	data, err := io.ReadAll(io.LimitReader(r, MaxSidecarSize+1))
+	if err != nil {
+		return nil, fmt.Errorf("failed to read sidecar: %w", err)
+	}
+	if len(data) > MaxSidecarSize {
+		return nil, fmt.Errorf("%w: exceeds %d bytes", ErrInvalidSidecar, MaxSidecarSize)
+	}
+	packet := findXMPPacket(data)
+	if packet == nil {
+		return nil, ErrInvalidSidecar
+	}
+	x, err := parseXMP(packet)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidSidecar, err)
+	}
+	return x, nil
+}
+
+// sidecarPatch 將 sidecar 中有值的欄位轉為覆寫 (桌面軟體中的修改視同使用者修改)，沒有的欄位保持不變
+// 說明優先使用 dc:description，沒有時使用 dc:title；不合理的值直接略過，不讓整份 sidecar 失敗
+func sidecarPatch(x *xmpData) MediaPatch {
+	var p MediaPatch
+	if x.Rating != nil {
+		p.SetRating = true
+		if r := *x.Rating; r != 0 {
+			p.Rating = &r
+		}
+	}
+	if x.TakenAt != nil && !x.TakenAt.After(time.Now().Add(24*time.Hour)) {
+		p.SetTakenAt, p.TakenAt = true, x.TakenAt
+	}
+	if x.Location != nil {
+		p.SetLocation, p.Location = true, x.Location
+	}
+	caption := strings.TrimSpace(x.Description)
+	if caption == "" {
+		caption = strings.TrimSpace(x.Title)
+	}
+	if caption != "" {
+		caption = truncateRunes(caption, MaxCaptionLength)
+		p.Caption = &caption
+	}
+	return p
+}
+
+// AttachSidecar 讀取 XMP sidecar 並套用至媒體：評等、說明、拍攝時間與位置寫入為覆寫 (可還原為檔案中的原始值)，
+// 關鍵字加入為標籤 (來源為 sidecar)；回傳更新後的媒體
+func (s *Service) AttachSidecar(ctx context.Context, userID, mediaID string, r io.Reader) (*Media, error) {
+	x, err := readSidecar(r)
+	if err != nil {
+		return nil, err
+	}
+	return s.applySidecar(ctx, userID, mediaID, x)
+}
+
+func (s *Service) applySidecar(ctx context.Context, userID, mediaID string, x *xmpData) (*Media, error) {
+	m, err := s.Update(ctx, userID, mediaID, sidecarPatch(x))
+	if err != nil {
+		return nil, err
+	}
+	n, err := s.addTags(ctx, s.DB, userID, []string{mediaID}, x.Keywords, TagSourceSidecar)
+	if err != nil {
+		return nil, err
+	}
+	if n == 0 {
+		return m, nil
+	}
+	return s.GetByID(ctx, userID, mediaID)
+}
+
+// findXMPSidecar 尋找媒體檔案旁的 sidecar (IMG_0001.JPG.xmp 或 IMG_0001.xmp，副檔名不分大小寫)；找不到時回傳空字串
+func findXMPSidecar(path string) string {
+	base := strings.TrimSuffix(path, filepath.Ext(path))
+	for _, stem := range []string{path, base} {
+		for _, ext := range []string{xmpSidecarExt, strings.ToUpper(xmpSidecarExt)} {
+			if info, err := os.Stat(stem + ext); err == nil && info.Mode().IsRegular() {
+				return stem + ext
+			}
+		}
+	}
+	return ""
+}
+
+// importSidecar 套用媒體檔案旁的 sidecar (目錄匯入用)，沒有 sidecar 時不做任何事
+func (s *Service) importSidecar(ctx context.Context, userID, mediaID, path string) error {
+	sidecar := findXMPSidecar(path)
+	if sidecar == "" {
+		return nil
+	}
+	f, err := os.Open(sidecar)
+	if err != nil {
+		return fmt.Errorf("failed to open sidecar: %w", err)
+	}
+	defer f.Close()
+	if _, err := s.AttachSidecar(ctx, userID, mediaID, f); err != nil {
+		return fmt.Errorf("failed to apply sidecar %s: %w", filepath.Base(sidecar), err)
+	}
+	return nil
+}
+
+// hasSidecarTags 媒體是否已有來自 sidecar 的標籤，用來判斷目錄匯入是否已套用過 sidecar
+func (s *Service) hasSidecarTags(ctx context.Context, mediaID string) (bool, error) {
+	var exists bool
+	query := `SELECT EXISTS (SELECT 1 FROM media_tags WHERE media_id = $1 AND source = $2)`
+	if err := s.DB.QueryRowContext(ctx, query, mediaID, TagSourceSidecar).Scan(&exists); err != nil {
+		return false, fmt.Errorf("failed to query sidecar tags: %w", err)
+	}
+	return exists, nil
+}
@@ -0,0 +1,75 @@
+package media
+
+import (
+	"errors"
+	"mime"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+)
+
+// AttachSidecarHandler 將 XMP sidecar 套用至既有媒體 (POST /media/:id/sidecar，multipart 欄位 file)
+// 評等、說明、拍攝時間與位置寫入為覆寫，關鍵字加入為標籤
+func (h *Handler) AttachSidecarHandler(c *gin.Context) {
+	userID := c.MustGet("userID").(string)
+	mediaID := c.Param("id")
+
+	if !uuidPattern.MatchString(mediaID) {
+		c.JSON(http.StatusNotFound, gin.H{"error": ErrMediaNotFound.Error()})
+		return
+	}
+
+	fileHeader, err := c.FormFile("file")
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "missing file"})
+		return
+	}
+	f, err := fileHeader.Open()
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "failed to read file"})
+		return
+	}
+	defer f.Close()
+
+	media, err := h.Service.AttachSidecar(c.Request.Context(), userID, mediaID, f)
+	if err != nil {
+		switch {
+		case errors.Is(err, ErrInvalidSidecar):
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		case errors.Is(err, ErrMediaNotFound):
+			c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+		default:
+			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		}
+		return
+	}
+
+	h.attachURLs(userID, media)
+	c.JSON(http.StatusOK, media)
+}
+
+// XMPSidecarHandler 下載媒體的 XMP sidecar (GET /media/:id/xmp)
+// 內容為 GoGallery 中的生效值 (含覆寫)，檔名為 原始檔名.xmp，可直接放在桌面軟體的原始檔旁
+func (h *Handler) XMPSidecarHandler(c *gin.Context) {
+	userID := c.MustGet("userID").(string)
+	mediaID := c.Param("id")
+
+	if !uuidPattern.MatchString(mediaID) {
+		c.JSON(http.StatusNotFound, gin.H{"error": ErrMediaNotFound.Error()})
+		return
+	}
+
+	media, err := h.Service.GetAccessibleByID(c.Request.Context(), userID, mediaID)
+	if err != nil {
+		if errors.Is(err, ErrMediaNotFound) {
+			c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.Header("Content-Disposition", mime.FormatMediaType("attachment", map[string]string{"filename": sanitizeZipName(media) + xmpSidecarExt}))
+	c.Header("Cache-Control", listCacheControl)
+	c.Data(http.StatusOK, "application/rdf+xml", buildXMPSidecar(media))
+}
@@ -0,0 +1,248 @@
+package media
+
+import (
+	"context"
+	"errors"
+	"math"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+)
+
+// Lightroom 將簡單屬性寫成 rdf:Description 的屬性
+const lightroomXMP = `<x:xmpmeta xmlns:x="adobe:ns:meta/" x:xmptk="Adobe XMP Core 7.0">
+ <rdf:RDF xmlns:rdf="http://www.w3.org/1999/02/22-rdf-syntax-ns#">
+  <rdf:Description rdf:about=""
+    xmlns:xmp="http://ns.adobe.com/xap/1.0/"
+    xmlns:exif="http://ns.adobe.com/exif/1.0/"
+    xmlns:dc="http://purl.org/dc/elements/1.1/"
+   xmp:Rating="4"
+   exif:DateTimeOriginal="2019-07-14T18:02:11+08:00"
+   exif:GPSLatitude="25,1.98S"
+   exif:GPSLongitude="121,33,36W">
+   <dc:title><rdf:Alt><rdf:li xml:lang="zh-TW">日落</rdf:li><rdf:li xml:lang="x-default">Sunset</rdf:li></rdf:Alt></dc:title>
+   <dc:subject><rdf:Bag><rdf:li>Beach</rdf:li><rdf:li>Family</rdf:li></rdf:Bag></dc:subject>
+  </rdf:Description>
+ </rdf:RDF>
+</x:xmpmeta>`
+
+// darktable 將簡單屬性寫成子元素
+const darktableXMP = `<?xml version="1.0" encoding="UTF-8"?>
+<x:xmpmeta xmlns:x="adobe:ns:meta/">
+ <rdf:RDF xmlns:rdf="http://www.w3.org/1999/02/22-rdf-syntax-ns#">
+  <rdf:Description rdf:about="" xmlns:xmp="http://ns.adobe.com/xap/1.0/" xmlns:dc="http://purl.org/dc/elements/1.1/">
+   <xmp:Rating>-1</xmp:Rating>
+   <dc:description><rdf:Alt><rdf:li xml:lang="x-default">Blurry &amp; dark</rdf:li></rdf:Alt></dc:description>
+  </rdf:Description>
+ </rdf:RDF>
+</x:xmpmeta>`
+
+func TestParseXMPSidecarAttributes(t *testing.T) {
+	x, err := parseXMP(findXMPPacket([]byte(lightroomXMP)))
+	if err != nil {
+		t.Fatalf("parseXMP failed: %v", err)
+	}
+	if x.Rating == nil || *x.Rating != 4 {
+		t.Errorf("unexpected rating: %v", x.Rating)
+	}
+	if x.Title != "Sunset" {
+		t.Errorf("expected x-default title, got %q", x.Title)
+	}
+	if !reflect.DeepEqual(x.Keywords, []string{"Beach", "Family"}) {
+		t.Errorf("unexpected keywords: %v", x.Keywords)
+	}
+	if x.TakenAt == nil || !x.TakenAt.Equal(time.Date(2019, 7, 14, 10, 2, 11, 0, time.UTC)) {
+		t.Errorf("unexpected taken_at: %v", x.TakenAt)
+	}
+	if x.Location == nil || math.Abs(x.Location.Latitude+25.033) > 1e-9 || math.Abs(x.Location.Longitude+121.56) > 1e-9 {
+		t.Errorf("unexpected location: %+v", x.Location)
+	}
+}
+
+func TestParseXMPSidecarElements(t *testing.T) {
+	x, err := parseXMP(findXMPPacket([]byte(darktableXMP)))
+	if err != nil {
+		t.Fatalf("parseXMP failed: %v", err)
+	}
+	if x.Rating == nil || *x.Rating != -1 {
+		t.Errorf("unexpected rating: %v", x.Rating)
+	}
+	if x.Description != "Blurry & dark" || x.Title != "" {
+		t.Errorf("unexpected title/description: %q / %q", x.Title, x.Description)
+	}
+	if x.Location != nil || x.TakenAt != nil {
+		t.Errorf("expected no location or date: %+v", x)
+	}
+}
+
+func TestParseXMPCoordinate(t *testing.T) {
+	cases := []struct {
+		in   string
+		ok   bool
+		want float64
+	}{
+		{"25,1.98N", true, 25.033},
+		{"25,1,58.8S", true, -25.033},
+		{"-45.5", true, -45.5},
+		{"25,61.0N", false, 0},
+		{"91,0.0N", false, 0},
+		{"north", false, 0},
+		{"", false, 0},
+	}
+	for _, c := range cases {
+		got, ok := parseXMPCoordinate(c.in, 'N', 'S')
+		if ok != c.ok || (ok && math.Abs(got-c.want) > 1e-9) {
+			t.Errorf("parseXMPCoordinate(%q) = %v, %v; want %v, %v", c.in, got, ok, c.want, c.ok)
+		}
+	}
+}
+
+func TestBuildXMPSidecarRoundTrip(t *testing.T) {
+	rating := 5
+	takenAt := time.Date(2021, 3, 4, 5, 6, 7, 0, time.UTC)
+	lat, lng := -33.856784, 151.215297
+	m := &Media{
+		Rating:    &rating,
+		TakenAt:   &takenAt,
+		Latitude:  &lat,
+		Longitude: &lng,
+		Caption:   `Opera <House> & "harbour"`,
+		Tags:      TagList{"Sydney", "旅行"},
+	}
+
+	x, err := readSidecar(strings.NewReader(string(buildXMPSidecar(m))))
+	if err != nil {
+		t.Fatalf("generated sidecar is not readable: %v", err)
+	}
+	if x.Rating == nil || *x.Rating != rating {
+		t.Errorf("rating mismatch: %v", x.Rating)
+	}
+	if x.TakenAt == nil || !x.TakenAt.Equal(takenAt) {
+		t.Errorf("taken_at mismatch: %v", x.TakenAt)
+	}
+	if x.Location == nil || math.Abs(x.Location.Latitude-lat) > 1e-6 || math.Abs(x.Location.Longitude-lng) > 1e-6 {
+		t.Errorf("location mismatch: %+v", x.Location)
+	}
+	if x.Description != m.Caption || x.Title != m.Caption {
+		t.Errorf("caption mismatch: %q / %q", x.Description, x.Title)
+	}
+	if !reflect.DeepEqual(x.Keywords, []string(m.Tags)) {
+		t.Errorf("keywords mismatch: %v", x.Keywords)
+	}
+
+	// 讀回後套用的值與原本相同
+	p := sidecarPatch(x)
+	if p.Caption == nil || *p.Caption != m.Caption || p.Rating == nil || *p.Rating != rating || !p.TakenAt.Equal(takenAt) {
+		t.Errorf("patch does not match media: %+v", p)
+	}
+
+	// 沒有任何值時仍是有效的 sidecar
+	empty, err := readSidecar(strings.NewReader(string(buildXMPSidecar(&Media{}))))
+	if err != nil || empty.Rating != nil || empty.Location != nil || len(empty.Keywords) != 0 || empty.Title != "" {
+		t.Errorf("unexpected empty sidecar: %+v, %v", empty, err)
+	}
+}
+
+func TestReadSidecarInvalid(t *testing.T) {
+	for _, body := range []string{"not xml", "<x:xmpmeta><rdf:RDF>", strings.Repeat(" ", MaxSidecarSize+1)} {
+		if _, err := readSidecar(strings.NewReader(body)); !errors.Is(err, ErrInvalidSidecar) {
+			t.Errorf("expected ErrInvalidSidecar for %.20q, got %v", body, err)
+		}
+	}
+}
+
+func TestSidecarPatch(t *testing.T) {
+	zero := 0
+	p := sidecarPatch(&xmpData{Rating: &zero, Title: " Sunset ", Description: ""})
+	if !p.SetRating || p.Rating != nil {
+		t.Errorf("expected rating 0 to clear: %+v", p)
+	}
+	if p.Caption == nil || *p.Caption != "Sunset" {
+		t.Errorf("expected title as caption fallback: %v", p.Caption)
+	}
+	if p.SetTakenAt || p.SetLocation {
+		t.Errorf("absent fields must not be changed: %+v", p)
+	}
+
+	future := time.Now().Add(72 * time.Hour)
+	p = sidecarPatch(&xmpData{TakenAt: &future})
+	if p.SetTakenAt || p.Caption != nil || p.SetRating {
+		t.Errorf("expected invalid date to be skipped: %+v", p)
+	}
+}
+
+func TestAttachSidecar(t *testing.T) {
+	db, mock := newMockDB(t)
+	s := &Service{DB: db}
+	const mediaID = "11111111-1111-1111-1111-111111111111"
+
+	mock.ExpectExec(`UPDATE media SET`).
+		WithArgs(mediaID, "user-1", false, true, sqlmock.AnyArg(), false, true, sqlmock.AnyArg(), sqlmock.AnyArg(), "Sunset", true, sqlmock.AnyArg()).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectQuery(`SELECT .+ FROM media m`).
+		WillReturnRows(sqlmock.NewRows(append(mediaTestColumns, "storage_path")).AddRow(append(mediaTestRow(mediaID, time.Now()), "a.jpg")...))
+	mock.ExpectExec(`INSERT INTO tags`).WithArgs("user-1", []string{"Beach", "Family"}).WillReturnResult(sqlmock.NewResult(0, 2))
+	mock.ExpectExec(`INSERT INTO media_tags`).
+		WithArgs("user-1", []string{mediaID}, []string{"Beach", "Family"}, TagSourceSidecar).
+		WillReturnResult(sqlmock.NewResult(0, 2))
+	mock.ExpectQuery(`SELECT .+ FROM media m`).
+		WillReturnRows(sqlmock.NewRows(append(mediaTestColumns, "storage_path")).AddRow(append(mediaTestRow(mediaID, time.Now()), "a.jpg")...))
+
+	if _, err := s.AttachSidecar(context.Background(), "user-1", mediaID, strings.NewReader(lightroomXMP)); err != nil {
+		t.Fatalf("AttachSidecar failed: %v", err)
+	}
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Error(err)
+	}
+}
+
+func TestFindXMPSidecar(t *testing.T) {
+	dir := t.TempDir()
+	photo := filepath.Join(dir, "IMG_0001.CR2")
+	writeTakeoutFile(t, photo, "raw")
+	if got := findXMPSidecar(photo); got != "" {
+		t.Errorf("expected no sidecar, got %s", got)
+	}
+
+	writeTakeoutFile(t, filepath.Join(dir, "IMG_0001.xmp"), darktableXMP)
+	if got := findXMPSidecar(photo); got != filepath.Join(dir, "IMG_0001.xmp") {
+		t.Errorf("expected Lightroom-style sidecar, got %s", got)
+	}
+
+	// darktable 的命名優先 (不會與同名不同格式的檔案混淆)
+	writeTakeoutFile(t, photo+".xmp", darktableXMP)
+	if got := findXMPSidecar(photo); got != photo+".xmp" {
+		t.Errorf("expected darktable-style sidecar, got %s", got)
+	}
+}
+
+func TestBuildZipArchiveWithXMP(t *testing.T) {
+	db, mock := newMockDB(t)
+	dir := t.TempDir()
+	s := &Service{DB: db, UploadDir: dir}
+	if err := os.WriteFile(filepath.Join(dir, "a.jpg"), []byte("image"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	m := &Media{ID: "m1", OriginalFilename: "IMG_0001.JPG", StoragePath: "a.jpg", FileHash: "h1", Caption: "Hello"}
+
+	mock.ExpectQuery(`SELECT file_hash, crc32 FROM file_checksums`).WillReturnRows(sqlmock.NewRows([]string{"file_hash", "crc32"}))
+	a, err := s.buildZipArchive(context.Background(), "test", []*Media{m}, nil, true)
+	if err != nil {
+		t.Fatalf("buildZipArchive failed: %v", err)
+	}
+	var names []string
+	for _, e := range a.entries {
+		names = append(names, e.name)
+	}
+	if !reflect.DeepEqual(names, []string{"IMG_0001.JPG", "IMG_0001.JPG.xmp"}) {
+		t.Errorf("unexpected entries: %v", names)
+	}
+	if !strings.Contains(string(a.entries[1].data), "Hello") {
+		t.Errorf("sidecar does not contain caption")
+	}
+}
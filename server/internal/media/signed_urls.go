@@ -0,0 +1,68 @@
+package media
+
+import (
+	"net/url"
+	"time"
+
+	"github.com/gin-gonic/gin"
+
+	"gogallery/internal/auth"
+)
+
+// 簽章網址使用的衍生參數：縮圖與原始檔 (未指定時 GetFileHandler 回傳顯示用的衍生檔)
+var (
+	thumbnailQuery = url.Values{"w": {"512"}, "h": {"512"}, "fit": {FitCover}, "format": {FormatAuto}}
+	originalQuery  = url.Values{"format": {FormatOriginal}}
+)
+
+// MediaURLs 不需 Authorization 標頭即可使用的簽章網址 (供 <img>、影片播放器等)
+type MediaURLs struct {
+	Original  string    `json:"original"`
+	Thumbnail string    `json:"thumbnail"`
+	Stream    string    `json:"stream,omitempty"` // HLS master playlist，轉檔完成後才提供
+	ExpiresAt time.Time `json:"expires_at"`
+}
+
+// attachURLs 為媒體加上以 userID 簽章的網址 (未設定 Signer 時不處理)
+func (h *Handler) attachURLs(userID string, list ...*Media) {
+	if h.Signer == nil {
+		return
+	}
+	exp := h.Signer.Expiry(time.Now())
+	for _, m := range list {
+		if m == nil {
+			continue
+		}
+		m.URLs = &MediaURLs{
+			Original:  h.Signer.FileURL(userID, m.ID, originalQuery, exp),
+			Thumbnail: h.Signer.FileURL(userID, m.ID, thumbnailQuery, exp),
+			ExpiresAt: exp,
+		}
+		if m.StreamStatus == StreamReady {
+			m.URLs.Stream = h.Signer.StreamURL(userID, m.ID, exp)
+		}
+	}
+}
+
+// NewHandlerFromEnv 建立 Handler 並以環境變數設定簽章網址 (見 auth.NewURLSignerFromEnv)
+// 未設定金鑰時回傳錯誤，API 應拒絕啟動
+func NewHandlerFromEnv(s *Service) (*Handler, error) {
+	signer, err := auth.NewURLSignerFromEnv()
+	if err != nil {
+		return nil, err
+	}
+	h := NewHandler(s)
+	h.Signer = signer
+	return h, nil
+}
+
+// RegisterSignedRoutes 掛載簽章網址 (不經過 AuthMiddleware，由簽章驗證並注入 userID，媒體存取權仍由 handler 檢查)
+// r 須為 Signer.Prefix 對應的路由群組；未設定 Signer 時不掛載
+func (h *Handler) RegisterSignedRoutes(r gin.IRouter) {
+	if h.Signer == nil {
+		return
+	}
+	signed := r.Group("/signed/:uid/:exp/:sig", auth.SignedURLMiddleware(h.Signer))
+	signed.GET("/media/:id/file", h.GetFileHandler)
+	signed.GET("/media/:id/hls/*file", h.StreamHandler)
+}
@@ -0,0 +1,63 @@
+package media
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/gin-gonic/gin"
+)
+
+func TestNewHandlerFromEnvRequiresSecret(t *testing.T) {
+	t.Setenv("SIGNED_URL_SECRET", "")
+	if _, err := NewHandlerFromEnv(&Service{}); err == nil {
+		t.Fatal("expected error without SIGNED_URL_SECRET")
+	}
+
+	t.Setenv("SIGNED_URL_SECRET", strings.Repeat("k", 32))
+	h, err := NewHandlerFromEnv(&Service{})
+	if err != nil || h.Signer == nil {
+		t.Fatalf("expected signer to be configured: %v", err)
+	}
+}
+
+func TestRegisterSignedRoutes(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	t.Setenv("SIGNED_URL_SECRET", strings.Repeat("k", 32))
+	t.Setenv("SIGNED_URL_PREFIX", "/api")
+	db, mock := newMockDB(t)
+	h, err := NewHandlerFromEnv(&Service{DB: db})
+	if err != nil {
+		t.Fatal(err)
+	}
+	r := gin.New()
+	h.RegisterSignedRoutes(r.Group("/api"))
+
+	m := &Media{ID: batchActiveID}
+	h.attachURLs("user-1", m)
+	if !strings.Contains(m.URLs.Original, "format=original") {
+		t.Errorf("original url must request the original bytes: %s", m.URLs.Original)
+	}
+
+	do := func(target string) int {
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, httptest.NewRequest(http.MethodGet, target, nil))
+		return w.Code
+	}
+
+	// 竄改的網址不查詢資料庫
+	if code := do(strings.Replace(m.URLs.Original, "format=original", "format=jpeg", 1)); code != http.StatusForbidden {
+		t.Errorf("expected tampered url to be rejected, got %d", code)
+	}
+
+	// 簽章有效時進入 handler，仍檢查媒體存取權
+	mock.ExpectQuery(`FROM media m`).WillReturnRows(sqlmock.NewRows(append(mediaTestColumns, "storage_path")))
+	if code := do(m.URLs.Original); code != http.StatusNotFound {
+		t.Errorf("expected inaccessible media to be 404, got %d", code)
+	}
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Error(err)
+	}
+}
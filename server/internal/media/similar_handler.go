@@ -0,0 +1,58 @@
+package media
+
+import (
+	"fmt"
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+)
+
+// parseSimilarity 讀取 ?similarity=0.9 (介於 MinSimilarity 與 1 之間，預設 DefaultSimilarity)
+func parseSimilarity(c *gin.Context) (float64, error) {
+	raw := c.Query("similarity")
+	if raw == "" {
+		return DefaultSimilarity, nil
+	}
+	v, err := strconv.ParseFloat(raw, 64)
+	if err != nil || v < MinSimilarity || v > 1 {
+		return 0, fmt.Errorf("similarity must be between %.2f and 1", MinSimilarity)
+	}
+	return v, nil
+}
+
+// SimilarGroupsHandler 列出視覺上相似的媒體群組 (GET /media/similar?similarity=0.9&page=1&limit=20)
+// 分頁以群組為單位
+func (h *Handler) SimilarGroupsHandler(c *gin.Context) {
+	userID := c.MustGet("userID").(string)
+	similarity, err := parseSimilarity(c)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	limit, offset := parsePagination(c)
+
+	groups, err := h.Service.SimilarGroups(c.Request.Context(), userID, similarity, limit, offset)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	for _, g := range groups {
+		h.attachURLs(userID, g.Media...)
+	}
+	jsonWithETag(c, http.StatusOK, groups)
+}
+
+// BackfillSimilarHandler 為既有圖片補算感知雜湊並建立相似配對 (POST /media/similar/scan)
+func (h *Handler) BackfillSimilarHandler(c *gin.Context) {
+	userID := c.MustGet("userID").(string)
+
+	hashed, failed, err := h.Service.BackfillPerceptualHashes(c.Request.Context(), userID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error(), "hashed": hashed, "failed": failed})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"hashed": hashed, "failed": failed})
+}
@@ -0,0 +1,375 @@
+package media
+
+import (
+	"bytes"
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+)
+
+// SmartRules 智慧相簿的規則文件，所有條件以 AND 結合，未設定的欄位不限制
+// 垃圾桶與封存中的媒體一律排除 (與主時間軸相同)
+type SmartRules struct {
+	MediaType   string       `json:"media_type,omitempty"`   // image 或 video
+	MinDuration *float64     `json:"min_duration,omitempty"` // 影片長度下限 (秒，含)
+	MaxDuration *float64     `json:"max_duration,omitempty"` // 影片長度上限 (秒，含)
+	TakenAfter  *time.Time   `json:"taken_after,omitempty"`  // 拍攝時間下限 (含)
+	TakenBefore *time.Time   `json:"taken_before,omitempty"` // 拍攝時間上限 (不含)
+	CameraMake  string       `json:"camera_make,omitempty"`  // 不分大小寫完全比對
+	CameraModel string       `json:"camera_model,omitempty"` // 不分大小寫完全比對
+	BoundingBox *BoundingBox `json:"bbox,omitempty"`
+	HasLocation *bool        `json:"has_location,omitempty"`
+	Favorite    *bool        `json:"favorite,omitempty"`
+	Tags        []string     `json:"tags,omitempty"`
+	TagMode     string       `json:"tag_mode,omitempty"` // any (預設) 或 all
+}
+
+// BoundingBox 經緯度範圍；MinLongitude 大於 MaxLongitude 時視為跨越換日線
+type BoundingBox struct {
+	MinLatitude  *float64 `json:"min_lat"`
+	MinLongitude *float64 `json:"min_lng"`
+	MaxLatitude  *float64 `json:"max_lat"`
+	MaxLongitude *float64 `json:"max_lng"`
+}
+
+// SmartAlbum 智慧相簿 (封面與數量為依規則即時計算的結果)
+type SmartAlbum struct {
+	Album
+	Rules *SmartRules `json:"rules"`
+}
+
+// ParseSmartRules 解析並驗證規則文件，未知欄位 (包含 bbox 內) 一律拒絕
+func ParseSmartRules(data []byte) (*SmartRules, error) {
+	d := json.NewDecoder(bytes.NewReader(data))
+	d.DisallowUnknownFields()
+
+	r := &SmartRules{}
+	if err := d.Decode(r); err != nil {
+		return nil, fmt.Errorf("invalid rules: %w", err)
+	}
+	if d.More() {
+		return nil, fmt.Errorf("invalid rules: unexpected data after rules object")
+	}
+	if err := r.Validate(); err != nil {
+		return nil, err
+	}
+	return r, nil
+}
+
+// Validate 檢查規則是否合理 (至少需要一個條件，避免誤建包含全部媒體的相簿)
+func (r *SmartRules) Validate() error {
+	switch r.MediaType {
+	case "", "image", "video":
+	default:
+		return fmt.Errorf("media_type must be image or video")
+	}
+	if (r.MinDuration != nil && *r.MinDuration < 0) || (r.MaxDuration != nil && *r.MaxDuration < 0) {
+		return fmt.Errorf("duration must not be negative")
+	}
+	if r.MinDuration != nil && r.MaxDuration != nil && *r.MinDuration > *r.MaxDuration {
+		return fmt.Errorf("min_duration must not exceed max_duration")
+	}
+	if r.TakenAfter != nil && r.TakenBefore != nil && !r.TakenAfter.Before(*r.TakenBefore) {
+		return fmt.Errorf("taken_after must be before taken_before")
+	}
+	if b := r.BoundingBox; b != nil {
+		if b.MinLatitude == nil || b.MinLongitude == nil || b.MaxLatitude == nil || b.MaxLongitude == nil {
+			return fmt.Errorf("bbox requires min_lat, min_lng, max_lat and max_lng")
+		}
+		for _, lat := range []float64{*b.MinLatitude, *b.MaxLatitude} {
+			if lat < -90 || lat > 90 {
+				return fmt.Errorf("latitude must be between -90 and 90")
+			}
+		}
+		for _, lng := range []float64{*b.MinLongitude, *b.MaxLongitude} {
+			if lng < -180 || lng > 180 {
+				return fmt.Errorf("longitude must be between -180 and 180")
+			}
+		}
+		if *b.MinLatitude > *b.MaxLatitude {
+			return fmt.Errorf("min_lat must not exceed max_lat")
+		}
+	}
+	if len(r.Tags) > 0 && len(NormalizeTags(r.Tags)) == 0 {
+		return fmt.Errorf("tags must contain at least one valid name")
+	}
+	switch r.TagMode {
+	case "", "any", "all":
+	default:
+		return fmt.Errorf("tag_mode must be any or all")
+	}
+
+	if r.MediaType == "" && r.MinDuration == nil && r.MaxDuration == nil &&
+		r.TakenAfter == nil && r.TakenBefore == nil && r.CameraMake == "" && r.CameraModel == "" &&
+		r.BoundingBox == nil && r.HasLocation == nil && r.Favorite == nil && len(r.Tags) == 0 {
+		return fmt.Errorf("rules must contain at least one condition")
+	}
+	return nil
+}
+
+// conditions 將規則轉換為 SQL 條件 (資料表別名須為 m)，參數透過 arg 加入
+func (r *SmartRules) conditions(arg func(any) string) []string {
+	var conds []string
+	switch r.MediaType {
+	case "image":
+		conds = append(conds, "m.mime_type LIKE 'image/%'")
+	case "video":
+		conds = append(conds, "m.mime_type LIKE 'video/%'")
+	}
+	if r.MinDuration != nil {
+		conds = append(conds, "m.duration >= "+arg(*r.MinDuration))
+	}
+	if r.MaxDuration != nil {
+		conds = append(conds, "m.duration <= "+arg(*r.MaxDuration))
+	}
+	if r.TakenAfter != nil {
+		conds = append(conds, "m.taken_at >= "+arg(*r.TakenAfter))
+	}
+	if r.TakenBefore != nil {
+		conds = append(conds, "m.taken_at < "+arg(*r.TakenBefore))
+	}
+	if r.CameraMake != "" {
+		conds = append(conds, "lower(m.camera_make) = lower("+arg(r.CameraMake)+")")
+	}
+	if r.CameraModel != "" {
+		conds = append(conds, "lower(m.camera_model) = lower("+arg(r.CameraModel)+")")
+	}
+	if b := r.BoundingBox; b != nil {
+		conds = append(conds, "m.latitude BETWEEN "+arg(*b.MinLatitude)+" AND "+arg(*b.MaxLatitude))
+		if *b.MinLongitude <= *b.MaxLongitude {
+			conds = append(conds, "m.longitude BETWEEN "+arg(*b.MinLongitude)+" AND "+arg(*b.MaxLongitude))
+		} else {
+			conds = append(conds, "(m.longitude >= "+arg(*b.MinLongitude)+" OR m.longitude <= "+arg(*b.MaxLongitude)+")")
+		}
+	}
+	if r.HasLocation != nil {
+		if *r.HasLocation {
+			conds = append(conds, "m.latitude IS NOT NULL")
+		} else {
+			conds = append(conds, "m.latitude IS NULL")
+		}
+	}
+	if r.Favorite != nil {
+		if *r.Favorite {
+			conds = append(conds, "m.is_favorite")
+		} else {
+			conds = append(conds, "NOT m.is_favorite")
+		}
+	}
+	if tags := NormalizeTags(r.Tags); len(tags) > 0 {
+		conds = append(conds, tagFilterCondition(arg, tags, r.TagMode == "all"))
+	}
+	return conds
+}
+
+// smartAlbumQuery 產生智慧相簿的 WHERE 子句，$1 固定為使用者 ID
+func smartAlbumQuery(userID string, rules *SmartRules) (where string, arg func(any) string, args *[]any) {
+	list := []any{userID}
+	arg = func(v any) string {
+		list = append(list, v)
+		return fmt.Sprintf("$%d", len(list))
+	}
+	conds := append([]string{"m.user_id = $1", "m.deleted_at IS NULL", "NOT m.is_archived"}, rules.conditions(arg)...)
+	return strings.Join(conds, " AND "), arg, &list
+}
+
+const smartAlbumColumns = `id, user_id, title, description, rules, cover_media_id, created_at, updated_at`
+
+// scanSmartAlbum 掃描 smart_albums 資料列，cover 為使用者指定的封面 (尚未套用規則)
+func scanSmartAlbum(row rowScanner, a *SmartAlbum, cover **string) error {
+	var raw []byte
+	if err := row.Scan(&a.ID, &a.UserID, &a.Title, &a.Description, &raw, cover, &a.CreatedAt, &a.UpdatedAt); err != nil {
+		return err
+	}
+	a.Rules = &SmartRules{}
+	if err := json.Unmarshal(raw, a.Rules); err != nil {
+		return fmt.Errorf("failed to decode rules: %w", err)
+	}
+	return nil
+}
+
+// CreateSmartAlbum 建立智慧相簿
+func (s *Service) CreateSmartAlbum(ctx context.Context, userID, title, description string, rules *SmartRules) (*SmartAlbum, error) {
+	if err := rules.Validate(); err != nil {
+		return nil, err
+	}
+	raw, err := json.Marshal(rules)
+	if err != nil {
+		return nil, fmt.Errorf("failed to encode rules: %w", err)
+	}
+
+	var id string
+	query := `INSERT INTO smart_albums (user_id, title, description, rules) VALUES ($1, $2, $3, $4::jsonb) RETURNING id`
+	if err := s.DB.QueryRowContext(ctx, query, userID, title, description, string(raw)).Scan(&id); err != nil {
+		return nil, fmt.Errorf("failed to create smart album: %w", err)
+	}
+	return s.GetSmartAlbum(ctx, userID, id)
+}
+
+// ListSmartAlbums 取得使用者的智慧相簿列表 (最近更新優先)
+// 每個相簿的數量與封面需各自以規則查詢，相簿數量通常不多
+func (s *Service) ListSmartAlbums(ctx context.Context, userID string) ([]*SmartAlbum, error) {
+	query := `SELECT ` + smartAlbumColumns + ` FROM smart_albums WHERE user_id = $1 ORDER BY updated_at DESC`
+	rows, err := s.DB.QueryContext(ctx, query, userID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query smart albums: %w", err)
+	}
+	defer rows.Close()
+
+	list := []*SmartAlbum{}
+	covers := []*string{}
+	for rows.Next() {
+		a := &SmartAlbum{}
+		var cover *string
+		if err := scanSmartAlbum(rows, a, &cover); err != nil {
+			return nil, fmt.Errorf("failed to scan smart album: %w", err)
+		}
+		list = append(list, a)
+		covers = append(covers, cover)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to iterate smart albums: %w", err)
+	}
+	rows.Close()
+
+	for i, a := range list {
+		if err := s.fillSmartAlbum(ctx, a, covers[i]); err != nil {
+			return nil, err
+		}
+	}
+	return list, nil
+}
+
+// GetSmartAlbum 取得單一智慧相簿
+func (s *Service) GetSmartAlbum(ctx context.Context, userID, albumID string) (*SmartAlbum, error) {
+	a, cover, err := s.loadSmartAlbum(ctx, userID, albumID)
+	if err != nil {
+		return nil, err
+	}
+	if err := s.fillSmartAlbum(ctx, a, cover); err != nil {
+		return nil, err
+	}
+	return a, nil
+}
+
+// UpdateSmartAlbum 更新標題、描述或規則 (nil 代表不修改)
+// 規則變更後若指定的封面不再符合，會自動改回預設封面
+func (s *Service) UpdateSmartAlbum(ctx context.Context, userID, albumID string, title, description *string, rules *SmartRules) (*SmartAlbum, error) {
+	var raw *string
+	if rules != nil {
+		if err := rules.Validate(); err != nil {
+			return nil, err
+		}
+		b, err := json.Marshal(rules)
+		if err != nil {
+			return nil, fmt.Errorf("failed to encode rules: %w", err)
+		}
+		str := string(b)
+		raw = &str
+	}
+
+	query := `
+		UPDATE smart_albums
+		SET title = COALESCE($3, title), description = COALESCE($4, description),
+		    rules = COALESCE($5::jsonb, rules), updated_at = NOW()
+		WHERE id = $1 AND user_id = $2
+	`
+	if err := s.execAlbum(ctx, query, albumID, userID, title, description, raw); err != nil {
+		return nil, err
+	}
+	return s.GetSmartAlbum(ctx, userID, albumID)
+}
+
+// DeleteSmartAlbum 刪除智慧相簿
+func (s *Service) DeleteSmartAlbum(ctx context.Context, userID, albumID string) error {
+	return s.execAlbum(ctx, `DELETE FROM smart_albums WHERE id = $1 AND user_id = $2`, albumID, userID)
+}
+
+// SetSmartAlbumCover 指定封面 (必須符合規則)；mediaID 為空字串時恢復為預設封面
+func (s *Service) SetSmartAlbumCover(ctx context.Context, userID, albumID, mediaID string) (*SmartAlbum, error) {
+	a, _, err := s.loadSmartAlbum(ctx, userID, albumID)
+	if err != nil {
+		return nil, err
+	}
+
+	var cover *string
+	if mediaID != "" {
+		where, arg, args := smartAlbumQuery(userID, a.Rules)
+		var matches bool
+		query := `SELECT EXISTS (SELECT 1 FROM media m WHERE ` + where + ` AND m.id = ` + arg(mediaID) + `)`
+		if err := s.DB.QueryRowContext(ctx, query, *args...).Scan(&matches); err != nil {
+			return nil, fmt.Errorf("failed to check cover: %w", err)
+		}
+		if !matches {
+			return nil, ErrMediaNotInAlbum
+		}
+		cover = &mediaID
+	}
+
+	query := `UPDATE smart_albums SET cover_media_id = $3, updated_at = NOW() WHERE id = $1 AND user_id = $2`
+	if err := s.execAlbum(ctx, query, albumID, userID, cover); err != nil {
+		return nil, err
+	}
+	return s.GetSmartAlbum(ctx, userID, albumID)
+}
+
+// ListSmartAlbumMedia 依規則取得相簿內容 (排序與分頁方式與 List 相同)
+func (s *Service) ListSmartAlbumMedia(ctx context.Context, userID, albumID string, limit, offset int) ([]*Media, error) {
+	a, _, err := s.loadSmartAlbum(ctx, userID, albumID)
+	if err != nil {
+		return nil, err
+	}
+
+	where, arg, args := smartAlbumQuery(userID, a.Rules)
+	query := `
+		SELECT ` + mediaColumns + `
+		FROM media m
+		WHERE ` + where + `
+		ORDER BY m.taken_at DESC NULLS LAST, m.uploaded_at DESC
+		LIMIT ` + arg(limit) + ` OFFSET ` + arg(offset)
+
+	rows, err := s.DB.QueryContext(ctx, query, *args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query smart album media: %w", err)
+	}
+	defer rows.Close()
+
+	return collectMedia(rows)
+}
+
+// loadSmartAlbum 讀取智慧相簿定義，回傳使用者指定的封面
+func (s *Service) loadSmartAlbum(ctx context.Context, userID, albumID string) (*SmartAlbum, *string, error) {
+	query := `SELECT ` + smartAlbumColumns + ` FROM smart_albums WHERE id = $1 AND user_id = $2`
+	a := &SmartAlbum{}
+	var cover *string
+	if err := scanSmartAlbum(s.DB.QueryRowContext(ctx, query, albumID, userID), a, &cover); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, nil, ErrAlbumNotFound
+		}
+		return nil, nil, fmt.Errorf("failed to query smart album: %w", err)
+	}
+	return a, cover, nil
+}
+
+// fillSmartAlbum 依規則計算數量與實際封面
+// 指定的封面仍符合規則時使用之，否則以排序後的第一個項目作為封面
+func (s *Service) fillSmartAlbum(ctx context.Context, a *SmartAlbum, cover *string) error {
+	where, arg, args := smartAlbumQuery(a.UserID, a.Rules)
+	query := `
+		SELECT
+			(SELECT COUNT(*) FROM media m WHERE ` + where + `),
+			COALESCE(
+				(SELECT m.id FROM media m WHERE ` + where + ` AND m.id = ` + arg(cover) + `::uuid),
+				(SELECT m.id FROM media m WHERE ` + where + `
+				 ORDER BY m.taken_at DESC NULLS LAST, m.uploaded_at DESC LIMIT 1)
+			)
+	`
+	if err := s.DB.QueryRowContext(ctx, query, *args...).Scan(&a.MediaCount, &a.CoverMediaID); err != nil {
+		return fmt.Errorf("failed to evaluate smart album: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,177 @@
+package media
+
+import (
+	"encoding/json"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+)
+
+type smartAlbumRequest struct {
+	Title       *string         `json:"title"`
+	Description *string         `json:"description"`
+	Rules       json.RawMessage `json:"rules"`
+}
+
+// bindSmartAlbumRequest 解析請求並驗證規則；requireAll 為 true 時 (建立) 標題與規則皆為必填
+func bindSmartAlbumRequest(c *gin.Context, requireAll bool) (*smartAlbumRequest, *SmartRules, bool) {
+	var req smartAlbumRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid request body"})
+		return nil, nil, false
+	}
+	if (requireAll && req.Title == nil) || (req.Title != nil && (*req.Title == "" || len(*req.Title) > 255)) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "title must be 1-255 characters"})
+		return nil, nil, false
+	}
+
+	hasRules := len(req.Rules) > 0 && string(req.Rules) != "null"
+	if requireAll && !hasRules {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "rules are required"})
+		return nil, nil, false
+	}
+	var rules *SmartRules
+	if hasRules {
+		var err error
+		if rules, err = ParseSmartRules(req.Rules); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return nil, nil, false
+		}
+	}
+	return &req, rules, true
+}
+
+// ListSmartAlbumsHandler 取得智慧相簿列表 (GET /smart-albums)
+func (h *Handler) ListSmartAlbumsHandler(c *gin.Context) {
+	userID := c.MustGet("userID").(string)
+
+	albums, err := h.Service.ListSmartAlbums(c.Request.Context(), userID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	jsonWithETag(c, http.StatusOK, albums)
+}
+
+// CreateSmartAlbumHandler 建立智慧相簿 (POST /smart-albums)
+// body: {"title": "...", "rules": {"media_type": "video", "min_duration": 60}}
+func (h *Handler) CreateSmartAlbumHandler(c *gin.Context) {
+	userID := c.MustGet("userID").(string)
+	req, rules, ok := bindSmartAlbumRequest(c, true)
+	if !ok {
+		return
+	}
+	description := ""
+	if req.Description != nil {
+		description = *req.Description
+	}
+
+	album, err := h.Service.CreateSmartAlbum(c.Request.Context(), userID, *req.Title, description, rules)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusCreated, album)
+}
+
+// GetSmartAlbumHandler 取得單一智慧相簿 (GET /smart-albums/:id)
+func (h *Handler) GetSmartAlbumHandler(c *gin.Context) {
+	userID := c.MustGet("userID").(string)
+	albumID, ok := albumParam(c)
+	if !ok {
+		return
+	}
+
+	album, err := h.Service.GetSmartAlbum(c.Request.Context(), userID, albumID)
+	if err != nil {
+		respondAlbumError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, album)
+}
+
+// UpdateSmartAlbumHandler 修改標題、描述或規則 (PATCH /smart-albums/:id)
+func (h *Handler) UpdateSmartAlbumHandler(c *gin.Context) {
+	userID := c.MustGet("userID").(string)
+	albumID, ok := albumParam(c)
+	if !ok {
+		return
+	}
+	req, rules, ok := bindSmartAlbumRequest(c, false)
+	if !ok {
+		return
+	}
+
+	album, err := h.Service.UpdateSmartAlbum(c.Request.Context(), userID, albumID, req.Title, req.Description, rules)
+	if err != nil {
+		respondAlbumError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, album)
+}
+
+// DeleteSmartAlbumHandler 刪除智慧相簿 (DELETE /smart-albums/:id)
+func (h *Handler) DeleteSmartAlbumHandler(c *gin.Context) {
+	userID := c.MustGet("userID").(string)
+	albumID, ok := albumParam(c)
+	if !ok {
+		return
+	}
+
+	if err := h.Service.DeleteSmartAlbum(c.Request.Context(), userID, albumID); err != nil {
+		respondAlbumError(c, err)
+		return
+	}
+
+	c.Status(http.StatusNoContent)
+}
+
+// ListSmartAlbumMediaHandler 取得智慧相簿內容 (GET /smart-albums/:id/media?page=1&limit=20)
+func (h *Handler) ListSmartAlbumMediaHandler(c *gin.Context) {
+	userID := c.MustGet("userID").(string)
+	albumID, ok := albumParam(c)
+	if !ok {
+		return
+	}
+	limit, offset := parsePagination(c)
+
+	list, err := h.Service.ListSmartAlbumMedia(c.Request.Context(), userID, albumID, limit, offset)
+	if err != nil {
+		respondAlbumError(c, err)
+		return
+	}
+
+	h.attachURLs(userID, list...)
+	jsonWithETag(c, http.StatusOK, list)
+}
+
+// SetSmartAlbumCoverHandler 指定智慧相簿封面 (PUT /smart-albums/:id/cover)
+func (h *Handler) SetSmartAlbumCoverHandler(c *gin.Context) {
+	userID := c.MustGet("userID").(string)
+	albumID, ok := albumParam(c)
+	if !ok {
+		return
+	}
+
+	var req albumCoverRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid request body"})
+		return
+	}
+	if req.MediaID != "" && !uuidPattern.MatchString(req.MediaID) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid media_id"})
+		return
+	}
+
+	album, err := h.Service.SetSmartAlbumCover(c.Request.Context(), userID, albumID, req.MediaID)
+	if err != nil {
+		respondAlbumError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, album)
+}
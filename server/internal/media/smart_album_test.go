@@ -0,0 +1,62 @@
+package media
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestParseSmartRules(t *testing.T) {
+	r, err := ParseSmartRules([]byte(`{"media_type": "video", "min_duration": 60, "camera_model": "ILCE-7M4"}`))
+	if err != nil {
+		t.Fatalf("ParseSmartRules failed: %v", err)
+	}
+	if r.MediaType != "video" || r.MinDuration == nil || *r.MinDuration != 60 || r.CameraModel != "ILCE-7M4" {
+		t.Errorf("unexpected rules: %+v", r)
+	}
+
+	invalid := []string{
+		`{}`,
+		`{"camera": "ILCE-7M4"}`,
+		`{"bbox": {"min_lat": 0, "min_lng": 0, "max_lat": 1, "max_lng": 1, "zoom": 3}}`,
+		`{"bbox": {"min_lat": 0, "min_lng": 0, "max_lat": 1}}`,
+		`{"bbox": {"min_lat": 10, "min_lng": 0, "max_lat": 1, "max_lng": 1}}`,
+		`{"media_type": "audio"}`,
+		`{"min_duration": 120, "max_duration": 60}`,
+		`{"taken_after": "2025-01-01T00:00:00Z", "taken_before": "2024-01-01T00:00:00Z"}`,
+		`{"tags": ["beach"], "tag_mode": "some"}`,
+		`{"favorite": true} {"favorite": false}`,
+	}
+	for _, body := range invalid {
+		if _, err := ParseSmartRules([]byte(body)); err == nil {
+			t.Errorf("expected error for %s", body)
+		}
+	}
+}
+
+func TestSmartRulesConditions(t *testing.T) {
+	r, err := ParseSmartRules([]byte(`{
+		"taken_after": "2024-01-01T00:00:00Z",
+		"taken_before": "2025-01-01T00:00:00Z",
+		"bbox": {"min_lat": 21.8, "min_lng": 170, "max_lat": 25.4, "max_lng": -170}
+	}`))
+	if err != nil {
+		t.Fatalf("ParseSmartRules failed: %v", err)
+	}
+
+	where, _, args := smartAlbumQuery("user-1", r)
+	for _, want := range []string{
+		"m.user_id = $1",
+		"NOT m.is_archived",
+		"m.taken_at >= $2",
+		"m.taken_at < $3",
+		"m.latitude BETWEEN $4 AND $5",
+		"(m.longitude >= $6 OR m.longitude <= $7)",
+	} {
+		if !strings.Contains(where, want) {
+			t.Errorf("expected %q in %s", want, where)
+		}
+	}
+	if len(*args) != 7 {
+		t.Errorf("expected 7 args, got %d", len(*args))
+	}
+}
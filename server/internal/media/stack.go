@@ -0,0 +1,359 @@
+package media
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"sort"
+	"time"
+)
+
+// DefaultStackWindow 連拍判定的預設間隔：相鄰兩張的拍攝時間差不超過此值視為同一組
+const DefaultStackWindow = time.Second
+
+// stackDimensionTolerance 同一組連拍的寬高差異上限 (比例)
+const stackDimensionTolerance = 0.1
+
+var (
+	ErrStackNotFound   = errors.New("stack not found")
+	ErrMediaNotInStack = errors.New("media not found in stack")
+)
+
+// stackCoverOrder 決定堆疊封面的排序 (資料表別名：成員 sm、堆疊 st)
+// 指定的封面優先，否則為最早拍攝的項目
+const stackCoverOrder = `COALESCE(sm.id = st.cover_media_id, FALSE) DESC, sm.taken_at NULLS LAST, sm.id`
+
+// stackCollapsedCondition 時間軸中堆疊只顯示封面 (資料表別名須為 m)
+// 封面移至垃圾桶或封存狀態不同時，改由同一狀態中的下一個項目代表
+const stackCollapsedCondition = `(m.stack_id IS NULL OR m.id = (
+	SELECT sm.id FROM media sm JOIN media_stacks st ON st.id = sm.stack_id
+	WHERE sm.stack_id = m.stack_id AND sm.deleted_at IS NULL AND sm.is_archived = m.is_archived
+	ORDER BY ` + stackCoverOrder + `
+	LIMIT 1))`
+
+// stackCandidate 連拍偵測所需的欄位
+type stackCandidate struct {
+	ID          string
+	StackID     string // 已所屬的堆疊，空字串代表沒有
+	BurstID     string
+	CameraMake  string
+	CameraModel string
+	TakenAt     time.Time
+	Width       int
+	Height      int
+}
+
+// stackWindow 回傳連拍判定間隔
+func (s *Service) stackWindow() time.Duration {
+	if s.StackWindow > 0 {
+		return s.StackWindow
+	}
+	return DefaultStackWindow
+}
+
+// groupStackCandidates 將候選項目分組 (只回傳兩張以上的組)
+// 1. 相同 BurstUUID 的項目直接成組
+// 2. 其餘項目依相機分開，拍攝時間相鄰不超過 window 且寬高相近者串接為同一組
+func groupStackCandidates(items []stackCandidate, window time.Duration) [][]stackCandidate {
+	var groups [][]stackCandidate
+	used := make([]bool, len(items))
+
+	bursts := map[string][]int{}
+	var burstOrder []string
+	for i, it := range items {
+		if it.BurstID == "" {
+			continue
+		}
+		if _, ok := bursts[it.BurstID]; !ok {
+			burstOrder = append(burstOrder, it.BurstID)
+		}
+		bursts[it.BurstID] = append(bursts[it.BurstID], i)
+	}
+	for _, id := range burstOrder {
+		idx := bursts[id]
+		if len(idx) < 2 {
+			continue
+		}
+		group := make([]stackCandidate, 0, len(idx))
+		for _, i := range idx {
+			used[i] = true
+			group = append(group, items[i])
+		}
+		groups = append(groups, group)
+	}
+
+	var rest []stackCandidate
+	for i, it := range items {
+		if !used[i] && it.CameraModel != "" {
+			rest = append(rest, it)
+		}
+	}
+	sort.SliceStable(rest, func(i, j int) bool {
+		a, b := rest[i], rest[j]
+		if a.CameraMake != b.CameraMake {
+			return a.CameraMake < b.CameraMake
+		}
+		if a.CameraModel != b.CameraModel {
+			return a.CameraModel < b.CameraModel
+		}
+		return a.TakenAt.Before(b.TakenAt)
+	})
+
+	var current []stackCandidate
+	flush := func() {
+		if len(current) >= 2 {
+			groups = append(groups, current)
+		}
+		current = nil
+	}
+	for _, it := range rest {
+		if len(current) > 0 {
+			prev := current[len(current)-1]
+			if prev.CameraMake != it.CameraMake || prev.CameraModel != it.CameraModel ||
+				it.TakenAt.Sub(prev.TakenAt) > window || !similarDimensions(prev, it) {
+				flush()
+			}
+		}
+		current = append(current, it)
+	}
+	flush()
+	return groups
+}
+
+// similarDimensions 寬高皆已知、方向相同且差異在容許範圍內
+func similarDimensions(a, b stackCandidate) bool {
+	if a.Width <= 0 || a.Height <= 0 || b.Width <= 0 || b.Height <= 0 {
+		return false
+	}
+	if (a.Width >= a.Height) != (b.Width >= b.Height) {
+		return false
+	}
+	within := func(x, y int) bool {
+		diff := x - y
+		if diff < 0 {
+			diff = -diff
+		}
+		return float64(diff) <= stackDimensionTolerance*float64(max(x, y))
+	}
+	return within(a.Width, b.Width) && within(a.Height, b.Height)
+}
+
+// DetectStacks 對使用者所有照片執行連拍偵測 (背景補掃)，回傳新加入堆疊的項目數
+func (s *Service) DetectStacks(ctx context.Context, userID string) (int64, error) {
+	return s.detectStacks(ctx, userID, nil, nil)
+}
+
+// detectStacksNear 上傳時只檢查拍攝時間前後的項目
+func (s *Service) detectStacksNear(ctx context.Context, userID string, takenAt time.Time) error {
+	from, to := takenAt.Add(-time.Minute), takenAt.Add(time.Minute)
+	_, err := s.detectStacks(ctx, userID, &from, &to)
+	return err
+}
+
+// detectStacks 偵測並寫入堆疊；使用者解散過的項目 (stack_opt_out) 不再自動歸組
+// 一組中已有項目屬於某個堆疊時沿用該堆疊，多個堆疊被串接時合併為一個
+func (s *Service) detectStacks(ctx context.Context, userID string, from, to *time.Time) (int64, error) {
+	query := `
+		SELECT id, COALESCE(stack_id::text, ''), COALESCE(burst_id, ''),
+		       COALESCE(camera_make, ''), COALESCE(camera_model, ''), taken_at,
+		       COALESCE(width, 0), COALESCE(height, 0)
+		FROM media
+		WHERE user_id = $1 AND deleted_at IS NULL AND NOT stack_opt_out
+		  AND mime_type LIKE 'image/%' AND taken_at IS NOT NULL
+		  AND ($2::timestamptz IS NULL OR taken_at >= $2)
+		  AND ($3::timestamptz IS NULL OR taken_at <= $3)
+		ORDER BY camera_make, camera_model, taken_at, id
+	`
+	rows, err := s.DB.QueryContext(ctx, query, userID, from, to)
+	if err != nil {
+		return 0, fmt.Errorf("failed to query stack candidates: %w", err)
+	}
+	var items []stackCandidate
+	for rows.Next() {
+		var c stackCandidate
+		if err := rows.Scan(&c.ID, &c.StackID, &c.BurstID, &c.CameraMake, &c.CameraModel, &c.TakenAt, &c.Width, &c.Height); err != nil {
+			rows.Close()
+			return 0, fmt.Errorf("failed to scan stack candidate: %w", err)
+		}
+		items = append(items, c)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return 0, fmt.Errorf("failed to iterate stack candidates: %w", err)
+	}
+
+	groups := groupStackCandidates(items, s.stackWindow())
+	if len(groups) == 0 {
+		return 0, nil
+	}
+
+	tx, err := s.DB.BeginTx(ctx, nil)
+	if err != nil {
+		return 0, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	var stacked int64
+	for _, group := range groups {
+		stackID := ""
+		ids := make([]string, len(group))
+		for i, c := range group {
+			ids[i] = c.ID
+			if stackID == "" {
+				stackID = c.StackID
+			}
+		}
+		if stackID == "" {
+			if err := tx.QueryRowContext(ctx, `INSERT INTO media_stacks (user_id) VALUES ($1) RETURNING id`, userID).Scan(&stackID); err != nil {
+				return 0, fmt.Errorf("failed to create stack: %w", err)
+			}
+		}
+
+		res, err := tx.ExecContext(ctx, `
+			UPDATE media SET stack_id = $3
+			WHERE user_id = $1 AND id = ANY($2::uuid[]) AND stack_id IS DISTINCT FROM $3
+		`, userID, ids, stackID)
+		if err != nil {
+			return 0, fmt.Errorf("failed to assign stack: %w", err)
+		}
+		n, _ := res.RowsAffected()
+		stacked += n
+	}
+
+	// 合併後不再有成員的堆疊
+	_, err = tx.ExecContext(ctx, `
+		DELETE FROM media_stacks st
+		WHERE st.user_id = $1 AND NOT EXISTS (SELECT 1 FROM media WHERE stack_id = st.id)
+	`, userID)
+	if err != nil {
+		return 0, fmt.Errorf("failed to clean up stacks: %w", err)
+	}
+
+	if err := tx.Commit(); err != nil {
+		return 0, fmt.Errorf("failed to commit: %w", err)
+	}
+	return stacked, nil
+}
+
+// GetStack 展開堆疊，回傳所有成員 (依拍攝時間) 與實際封面
+func (s *Service) GetStack(ctx context.Context, userID, stackID string) (*Stack, error) {
+	var cover *string
+	err := s.DB.QueryRowContext(ctx, `SELECT cover_media_id FROM media_stacks WHERE id = $1 AND user_id = $2`, stackID, userID).Scan(&cover)
+	if err == sql.ErrNoRows {
+		return nil, ErrStackNotFound
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to query stack: %w", err)
+	}
+
+	query := `
+		SELECT ` + mediaColumns + `
+		FROM media m
+		WHERE m.stack_id = $1 AND m.user_id = $2 AND m.deleted_at IS NULL
+		ORDER BY m.taken_at NULLS LAST, m.id
+	`
+	rows, err := s.DB.QueryContext(ctx, query, stackID, userID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query stack media: %w", err)
+	}
+	defer rows.Close()
+
+	list, err := collectMedia(rows)
+	if err != nil {
+		return nil, err
+	}
+
+	st := &Stack{ID: stackID, Media: list}
+	if len(list) > 0 {
+		st.CoverMediaID = list[0].ID
+		for _, m := range list {
+			if cover != nil && m.ID == *cover {
+				st.CoverMediaID = m.ID
+			}
+		}
+	}
+	return st, nil
+}
+
+// SetStackCover 指定堆疊封面 (必須為未刪除的成員)
+func (s *Service) SetStackCover(ctx context.Context, userID, stackID, mediaID string) (*Stack, error) {
+	res, err := s.DB.ExecContext(ctx, `
+		UPDATE media_stacks SET cover_media_id = $3
+		WHERE id = $1 AND user_id = $2
+		  AND EXISTS (SELECT 1 FROM media WHERE id = $3 AND stack_id = $1 AND deleted_at IS NULL)
+	`, stackID, userID, mediaID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to set stack cover: %w", err)
+	}
+	if n, err := res.RowsAffected(); err == nil && n == 0 {
+		if _, err := s.GetStack(ctx, userID, stackID); err != nil {
+			return nil, err
+		}
+		return nil, ErrMediaNotInStack
+	}
+	return s.GetStack(ctx, userID, stackID)
+}
+
+// Unstack 解散堆疊，成員恢復個別顯示且不再被自動歸組
+func (s *Service) Unstack(ctx context.Context, userID, stackID string) error {
+	tx, err := s.DB.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	_, err = tx.ExecContext(ctx, `
+		UPDATE media SET stack_id = NULL, stack_opt_out = TRUE
+		WHERE stack_id = $1 AND user_id = $2
+	`, stackID, userID)
+	if err != nil {
+		return fmt.Errorf("failed to unstack media: %w", err)
+	}
+	res, err := tx.ExecContext(ctx, `DELETE FROM media_stacks WHERE id = $1 AND user_id = $2`, stackID, userID)
+	if err != nil {
+		return fmt.Errorf("failed to delete stack: %w", err)
+	}
+	if n, err := res.RowsAffected(); err == nil && n == 0 {
+		return ErrStackNotFound
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("failed to commit: %w", err)
+	}
+	return nil
+}
+
+// TrashStackExceptCover 將封面以外的成員移至垃圾桶，並把目前封面固定為指定封面
+// 回傳移至垃圾桶的數量
+func (s *Service) TrashStackExceptCover(ctx context.Context, userID, stackID string) (int64, error) {
+	st, err := s.GetStack(ctx, userID, stackID)
+	if err != nil {
+		return 0, err
+	}
+	if st.CoverMediaID == "" {
+		return 0, nil
+	}
+
+	tx, err := s.DB.BeginTx(ctx, nil)
+	if err != nil {
+		return 0, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	if _, err := tx.ExecContext(ctx, `UPDATE media_stacks SET cover_media_id = $2 WHERE id = $1`, stackID, st.CoverMediaID); err != nil {
+		return 0, fmt.Errorf("failed to set stack cover: %w", err)
+	}
+	res, err := tx.ExecContext(ctx, `
+		UPDATE media SET deleted_at = NOW()
+		WHERE stack_id = $1 AND user_id = $2 AND deleted_at IS NULL AND id <> $3
+	`, stackID, userID, st.CoverMediaID)
+	if err != nil {
+		return 0, fmt.Errorf("failed to trash stack media: %w", err)
+	}
+
+	if err := tx.Commit(); err != nil {
+		return 0, fmt.Errorf("failed to commit: %w", err)
+	}
+	return res.RowsAffected()
+}
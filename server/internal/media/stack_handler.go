@@ -0,0 +1,120 @@
+package media
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+)
+
+type stackCoverRequest struct {
+	MediaID string `json:"media_id"`
+}
+
+// respondStackError 將堆疊錯誤轉換為 HTTP 狀態碼
+func respondStackError(c *gin.Context, err error) {
+	switch {
+	case errors.Is(err, ErrStackNotFound), errors.Is(err, ErrMediaNotInStack):
+		c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+	default:
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+	}
+}
+
+// stackParam 取得並驗證路徑中的堆疊 ID
+func stackParam(c *gin.Context) (string, bool) {
+	stackID := c.Param("id")
+	if !uuidPattern.MatchString(stackID) {
+		c.JSON(http.StatusNotFound, gin.H{"error": ErrStackNotFound.Error()})
+		return "", false
+	}
+	return stackID, true
+}
+
+// GetStackHandler 展開堆疊 (GET /stacks/:id)
+func (h *Handler) GetStackHandler(c *gin.Context) {
+	userID := c.MustGet("userID").(string)
+	stackID, ok := stackParam(c)
+	if !ok {
+		return
+	}
+
+	stack, err := h.Service.GetStack(c.Request.Context(), userID, stackID)
+	if err != nil {
+		respondStackError(c, err)
+		return
+	}
+
+	h.attachURLs(userID, stack.Media...)
+	jsonWithETag(c, http.StatusOK, stack)
+}
+
+// SetStackCoverHandler 指定堆疊封面 (PUT /stacks/:id/cover，body: {"media_id": "..."})
+func (h *Handler) SetStackCoverHandler(c *gin.Context) {
+	userID := c.MustGet("userID").(string)
+	stackID, ok := stackParam(c)
+	if !ok {
+		return
+	}
+
+	var req stackCoverRequest
+	if err := c.ShouldBindJSON(&req); err != nil || !uuidPattern.MatchString(req.MediaID) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "media_id is required"})
+		return
+	}
+
+	stack, err := h.Service.SetStackCover(c.Request.Context(), userID, stackID, req.MediaID)
+	if err != nil {
+		respondStackError(c, err)
+		return
+	}
+
+	h.attachURLs(userID, stack.Media...)
+	c.JSON(http.StatusOK, stack)
+}
+
+// UnstackHandler 解散堆疊 (DELETE /stacks/:id)
+func (h *Handler) UnstackHandler(c *gin.Context) {
+	userID := c.MustGet("userID").(string)
+	stackID, ok := stackParam(c)
+	if !ok {
+		return
+	}
+
+	if err := h.Service.Unstack(c.Request.Context(), userID, stackID); err != nil {
+		respondStackError(c, err)
+		return
+	}
+
+	c.Status(http.StatusNoContent)
+}
+
+// TrashStackOthersHandler 只保留封面，其餘移至垃圾桶 (POST /stacks/:id/trash-others)
+func (h *Handler) TrashStackOthersHandler(c *gin.Context) {
+	userID := c.MustGet("userID").(string)
+	stackID, ok := stackParam(c)
+	if !ok {
+		return
+	}
+
+	trashed, err := h.Service.TrashStackExceptCover(c.Request.Context(), userID, stackID)
+	if err != nil {
+		respondStackError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"trashed": trashed})
+}
+
+// DetectStacksHandler 對既有照片重新執行連拍偵測 (POST /stacks/detect)
+func (h *Handler) DetectStacksHandler(c *gin.Context) {
+	userID := c.MustGet("userID").(string)
+
+	stacked, err := h.Service.DetectStacks(c.Request.Context(), userID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"stacked": stacked})
+}
@@ -0,0 +1,116 @@
+package media
+
+import (
+	"context"
+	"encoding/binary"
+	"fmt"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+)
+
+func TestGroupStackCandidates(t *testing.T) {
+	base := time.Date(2024, 5, 1, 10, 0, 0, 0, time.UTC)
+	at := func(ms int) time.Time { return base.Add(time.Duration(ms) * time.Millisecond) }
+	sony := func(id string, ms int) stackCandidate {
+		return stackCandidate{ID: id, CameraMake: "SONY", CameraModel: "ILCE-7M4", TakenAt: at(ms), Width: 6000, Height: 4000}
+	}
+
+	rotated := sony("rotated", 1500)
+	rotated.Width, rotated.Height = 4000, 6000
+	other := sony("other-camera", 200)
+	other.CameraModel = "ILCE-1"
+
+	items := []stackCandidate{
+		sony("a", 0), sony("b", 300), sony("c", 900), // 串接成一組
+		rotated,         // 方向不同
+		sony("d", 5000), // 間隔太長
+		other,           // 不同相機
+		{ID: "burst-1", BurstID: "B1", TakenAt: at(0)},
+		{ID: "burst-2", BurstID: "B1", TakenAt: at(8000)},
+	}
+
+	groups := groupStackCandidates(items, time.Second)
+	if len(groups) != 2 {
+		t.Fatalf("expected 2 groups, got %d: %+v", len(groups), groups)
+	}
+	if len(groups[0]) != 2 || groups[0][0].ID != "burst-1" {
+		t.Errorf("expected burst group first, got %+v", groups[0])
+	}
+	ids := []string{}
+	for _, c := range groups[1] {
+		ids = append(ids, c.ID)
+	}
+	if len(ids) != 3 || ids[0] != "a" || ids[2] != "c" {
+		t.Errorf("unexpected sequence group: %v", ids)
+	}
+}
+
+func TestAppleBurstUUID(t *testing.T) {
+	uuid := "6C4F4C9A-5B1E-4F27-9B1F-2B2F0D3C1A11"
+
+	// 標頭 + 1 筆 IFD 項目，字串放在項目之後
+	note := []byte("Apple iOS\x00\x00\x01MM")
+	note = binary.BigEndian.AppendUint16(note, 1)
+	offset := len(note) + 12
+	note = binary.BigEndian.AppendUint16(note, appleTagBurstUUID)
+	note = binary.BigEndian.AppendUint16(note, tiffTypeASCII)
+	note = binary.BigEndian.AppendUint32(note, uint32(len(uuid)+1))
+	note = binary.BigEndian.AppendUint32(note, uint32(offset))
+	note = append(note, uuid+"\x00"...)
+
+	if got := appleBurstUUID(note); got != uuid {
+		t.Errorf("expected %s, got %q", uuid, got)
+	}
+	if got := appleBurstUUID([]byte("Nikon\x00\x02")); got != "" {
+		t.Errorf("expected empty result for non-Apple maker note, got %q", got)
+	}
+	if got := appleBurstUUID(note[:len(note)-10]); got != "" {
+		t.Errorf("expected empty result for truncated maker note, got %q", got)
+	}
+}
+
+func TestListFiltersDoNotCollapseStacks(t *testing.T) {
+	db, mock, err := sqlmock.New(sqlmock.ValueConverterOption(passthroughConverter{}), sqlmock.QueryMatcherOption(sqlmock.QueryMatcherFunc(func(expected, actual string) error {
+		collapsed := strings.Contains(actual, "media_stacks")
+		if (expected == "collapsed") != collapsed {
+			return fmt.Errorf("expected %s query, got: %s", expected, actual)
+		}
+		return nil
+	})))
+	if err != nil {
+		t.Fatalf("failed to open sqlmock: %v", err)
+	}
+	defer db.Close()
+	s := &Service{DB: db}
+
+	// 堆疊中被加入我的最愛的非封面項目
+	member := mediaTestRow(batchActiveID, time.Now())
+	member[22] = true
+	member[28] = "stack-1"
+	member[29] = 3
+
+	mock.ExpectQuery("filtered").WillReturnRows(sqlmock.NewRows(mediaTestColumns).AddRow(member...))
+	list, err := s.List(context.Background(), "user-1", ListOptions{FavoritesOnly: true}, 20, 0)
+	if err != nil {
+		t.Fatalf("List favorites failed: %v", err)
+	}
+	if len(list) != 1 || list[0].ID != batchActiveID {
+		t.Errorf("expected favorited stack member, got %+v", list)
+	}
+
+	mock.ExpectQuery("filtered").WillReturnRows(sqlmock.NewRows(mediaTestColumns))
+	if _, err := s.List(context.Background(), "user-1", ListOptions{Tags: []string{"Beach"}}, 20, 0); err != nil {
+		t.Fatalf("List by tag failed: %v", err)
+	}
+
+	mock.ExpectQuery("collapsed").WillReturnRows(sqlmock.NewRows(mediaTestColumns))
+	if _, err := s.List(context.Background(), "user-1", ListOptions{}, 20, 0); err != nil {
+		t.Fatalf("List timeline failed: %v", err)
+	}
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Error(err)
+	}
+}
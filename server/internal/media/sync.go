@@ -0,0 +1,196 @@
+package media
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"time"
+)
+
+// ChangeOp 媒體異動類型 (由 migrations/000006 的觸發器寫入)
+type ChangeOp string
+
+const (
+	ChangeCreate  ChangeOp = "create"
+	ChangeUpdate  ChangeOp = "update"
+	ChangeDelete  ChangeOp = "delete" // 移至垃圾桶
+	ChangeRestore ChangeOp = "restore"
+	ChangePurge   ChangeOp = "purge" // 永久刪除
+)
+
+// Change 代表同步回應中的單筆異動
+type Change struct {
+	Seq       int64     `json:"seq"`
+	MediaID   string    `json:"media_id"`
+	Op        ChangeOp  `json:"op"`
+	ChangedAt time.Time `json:"changed_at"`
+	Media     *Media    `json:"media,omitempty"` // 最新狀態；purge 時為空
+}
+
+// SyncResult 為 GET /sync 的回應
+type SyncResult struct {
+	Changes        []*Change `json:"changes"`
+	NextToken      string    `json:"next_token"`
+	HasMore        bool      `json:"has_more"`
+	ResyncRequired bool      `json:"resync_required"` // true 時客戶端需重新分頁取得完整列表
+}
+
+// ParseSyncToken 解析同步 token；空字串代表首次同步
+func ParseSyncToken(token string) (int64, bool) {
+	if token == "" {
+		return 0, false
+	}
+	seq, err := strconv.ParseInt(token, 10, 64)
+	if err != nil || seq < 0 {
+		return 0, false
+	}
+	return seq, true
+}
+
+func formatSyncToken(seq int64) string {
+	return strconv.FormatInt(seq, 10)
+}
+
+// Changes 取得 since 之後的異動
+//
+// 同一媒體在本頁內的多次異動只保留最後一筆；以下情況回傳 ResyncRequired：
+//   - 沒有 token 或 token 無法解析
+//   - token 早於已清除的紀錄 (media_change_horizons)
+//   - token 大於目前最大 seq (例如伺服器資料已重建)
+func (s *Service) Changes(ctx context.Context, userID, token string, limit int) (*SyncResult, error) {
+	var latest, pruned int64
+	query := `
+		SELECT COALESCE((SELECT MAX(seq) FROM media_changes WHERE user_id = $1), 0),
+		       COALESCE((SELECT pruned_seq FROM media_change_horizons WHERE user_id = $1), 0)
+	`
+	if err := s.DB.QueryRowContext(ctx, query, userID).Scan(&latest, &pruned); err != nil {
+		return nil, fmt.Errorf("failed to query sync state: %w", err)
+	}
+	if latest < pruned {
+		latest = pruned
+	}
+
+	since, ok := ParseSyncToken(token)
+	if !ok || since < pruned || since > latest {
+		return &SyncResult{Changes: []*Change{}, NextToken: formatSyncToken(latest), ResyncRequired: true}, nil
+	}
+
+	rows, err := s.DB.QueryContext(ctx, `
+		SELECT seq, media_id, op, changed_at
+		FROM media_changes
+		WHERE user_id = $1 AND seq > $2
+		ORDER BY seq
+		LIMIT $3
+	`, userID, since, limit+1)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query changes: %w", err)
+	}
+	defer rows.Close()
+
+	raw := []*Change{}
+	for rows.Next() {
+		ch := &Change{}
+		if err := rows.Scan(&ch.Seq, &ch.MediaID, &ch.Op, &ch.ChangedAt); err != nil {
+			return nil, fmt.Errorf("failed to scan change: %w", err)
+		}
+		raw = append(raw, ch)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to iterate changes: %w", err)
+	}
+
+	result := &SyncResult{Changes: []*Change{}, NextToken: formatSyncToken(since)}
+	if len(raw) > limit {
+		raw = raw[:limit]
+		result.HasMore = true
+	}
+	if len(raw) == 0 {
+		return result, nil
+	}
+	result.NextToken = formatSyncToken(raw[len(raw)-1].Seq)
+
+	// 同一媒體只保留最後一筆異動 (保持 seq 排序)
+	lastIndex := make(map[string]int, len(raw))
+	for i, ch := range raw {
+		lastIndex[ch.MediaID] = i
+	}
+	ids := make([]string, 0, len(lastIndex))
+	for i, ch := range raw {
+		if lastIndex[ch.MediaID] == i {
+			result.Changes = append(result.Changes, ch)
+			if ch.Op != ChangePurge {
+				ids = append(ids, ch.MediaID)
+			}
+		}
+	}
+
+	// 附上媒體最新狀態
+	byID, err := s.getManyByID(ctx, userID, ids)
+	if err != nil {
+		return nil, err
+	}
+	for _, ch := range result.Changes {
+		ch.Media = byID[ch.MediaID]
+	}
+	return result, nil
+}
+
+// getManyByID 依 ID 批次取得媒體 (包含垃圾桶中的項目)
+func (s *Service) getManyByID(ctx context.Context, userID string, ids []string) (map[string]*Media, error) {
+	byID := make(map[string]*Media, len(ids))
+	if len(ids) == 0 {
+		return byID, nil
+	}
+
+	query := `SELECT ` + mediaColumns + ` FROM media m WHERE m.user_id = $1 AND m.id = ANY($2)`
+	rows, err := s.DB.QueryContext(ctx, query, userID, ids)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query media: %w", err)
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		m := &Media{}
+		if err := scanMedia(rows, m); err != nil {
+			return nil, fmt.Errorf("failed to scan media: %w", err)
+		}
+		byID[m.ID] = m
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to iterate media: %w", err)
+	}
+	return byID, nil
+}
+
+// PruneChanges 清除 before 之前的異動紀錄，並更新各使用者的 horizon
+// token 落在被清除範圍內的客戶端下次同步會收到 ResyncRequired
+func (s *Service) PruneChanges(ctx context.Context, before time.Time) (int64, error) {
+	tx, err := s.DB.BeginTx(ctx, nil)
+	if err != nil {
+		return 0, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	_, err = tx.ExecContext(ctx, `
+		INSERT INTO media_change_horizons (user_id, pruned_seq)
+		SELECT c.user_id, MAX(c.seq)
+		FROM media_changes c
+		JOIN users u ON u.id = c.user_id
+		WHERE c.changed_at < $1
+		GROUP BY c.user_id
+		ON CONFLICT (user_id) DO UPDATE
+		SET pruned_seq = GREATEST(media_change_horizons.pruned_seq, EXCLUDED.pruned_seq)
+	`, before)
+	if err != nil {
+		return 0, fmt.Errorf("failed to update change horizons: %w", err)
+	}
+
+	res, err := tx.ExecContext(ctx, `DELETE FROM media_changes WHERE changed_at < $1`, before)
+	if err != nil {
+		return 0, fmt.Errorf("failed to prune changes: %w", err)
+	}
+	if err := tx.Commit(); err != nil {
+		return 0, fmt.Errorf("failed to commit prune: %w", err)
+	}
+	return res.RowsAffected()
+}
@@ -0,0 +1,32 @@
+package media
+
+import (
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+)
+
+// SyncHandler 取得增量同步的異動列表
+//
+// GET /sync?since=<token>&limit=500
+// 客戶端保存回應中的 next_token，下次以 since 帶回；has_more 為 true 時應立即繼續取下一頁
+func (h *Handler) SyncHandler(c *gin.Context) {
+	userID := c.MustGet("userID").(string)
+
+	limit, _ := strconv.Atoi(c.DefaultQuery("limit", "500"))
+	if limit < 1 || limit > 1000 {
+		limit = 500
+	}
+
+	result, err := h.Service.Changes(c.Request.Context(), userID, c.Query("since"), limit)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	for _, ch := range result.Changes {
+		h.attachURLs(userID, ch.Media)
+	}
+	c.JSON(http.StatusOK, result)
+}
@@ -0,0 +1,72 @@
+package media
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+)
+
+func TestChangesResyncRequired(t *testing.T) {
+	db, mock := newMockDB(t)
+	s := NewService(db, t.TempDir())
+
+	for _, token := range []string{"", "abc", "5", "99"} {
+		mock.ExpectQuery("SELECT COALESCE").
+			WithArgs("user-1").
+			WillReturnRows(sqlmock.NewRows([]string{"latest", "pruned"}).AddRow(int64(20), int64(10)))
+
+		result, err := s.Changes(context.Background(), "user-1", token, 100)
+		if err != nil {
+			t.Fatalf("Changes(%q) failed: %v", token, err)
+		}
+		if !result.ResyncRequired || result.NextToken != "20" {
+			t.Errorf("Changes(%q): expected resync with token 20, got %+v", token, result)
+		}
+	}
+
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("there were unfulfilled expectations: %s", err)
+	}
+}
+
+func TestChangesCompactsPerMedia(t *testing.T) {
+	db, mock := newMockDB(t)
+	s := NewService(db, t.TempDir())
+
+	now := time.Now()
+	mock.ExpectQuery("SELECT COALESCE").
+		WithArgs("user-1").
+		WillReturnRows(sqlmock.NewRows([]string{"latest", "pruned"}).AddRow(int64(20), int64(0)))
+	mock.ExpectQuery("FROM media_changes").
+		WithArgs("user-1", int64(10), 4).
+		WillReturnRows(sqlmock.NewRows([]string{"seq", "media_id", "op", "changed_at"}).
+			AddRow(int64(11), "a", "create", now).
+			AddRow(int64(12), "b", "create", now).
+			AddRow(int64(13), "a", "delete", now).
+			AddRow(int64(14), "c", "create", now))
+	mock.ExpectQuery("FROM media m").
+		WillReturnRows(sqlmock.NewRows(mediaTestColumns).
+			AddRow(mediaTestRow("a", now)...).
+			AddRow(mediaTestRow("b", now)...))
+
+	result, err := s.Changes(context.Background(), "user-1", "10", 3)
+	if err != nil {
+		t.Fatalf("Changes failed: %v", err)
+	}
+	if result.ResyncRequired || !result.HasMore || result.NextToken != "13" {
+		t.Errorf("unexpected result: %+v", result)
+	}
+	// a 的 create 與 delete 合併為最後一筆 delete，依 seq 排在 b 之後
+	if len(result.Changes) != 2 || result.Changes[0].MediaID != "b" || result.Changes[1].MediaID != "a" || result.Changes[1].Op != ChangeDelete {
+		t.Fatalf("unexpected changes: %+v", result.Changes)
+	}
+	if result.Changes[0].Media == nil || result.Changes[1].Media == nil {
+		t.Errorf("expected media attached to changes")
+	}
+
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("there were unfulfilled expectations: %s", err)
+	}
+}
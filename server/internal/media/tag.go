@@ -0,0 +1,160 @@
+package media
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"unicode/utf8"
+)
+
+// 標籤來源 (media_tags.source)
+const (
+	TagSourceUser     = "user"
+	TagSourceEmbedded = "embedded" // 由 IPTC/XMP 關鍵字匯入
+	TagSourceSidecar  = "sidecar"  // 由 XMP sidecar 關鍵字匯入
+)
+
+// MaxTagLength 標籤名稱長度上限 (字元數)
+const MaxTagLength = 100
+
+// NormalizeTags 去除前後與重複空白、過濾空字串，並以不分大小寫去重
+func NormalizeTags(names []string) []string {
+	seen := make(map[string]bool, len(names))
+	out := make([]string, 0, len(names))
+	for _, name := range names {
+		name = strings.Join(strings.Fields(name), " ")
+		if name == "" || utf8.RuneCountInString(name) > MaxTagLength {
+			continue
+		}
+		key := strings.ToLower(name)
+		if seen[key] {
+			continue
+		}
+		seen[key] = true
+		out = append(out, name)
+	}
+	return out
+}
+
+// AddTags 為多個媒體加上標籤 (標籤不存在時自動建立)，只會套用在自己的媒體上
+// 回傳新增的關聯數量
+func (s *Service) AddTags(ctx context.Context, userID string, mediaIDs, names []string) (int64, error) {
+	return s.addTags(ctx, s.DB, userID, mediaIDs, names, TagSourceUser)
+}
+
+func (s *Service) addTags(ctx context.Context, db execer, userID string, mediaIDs, names []string, source string) (int64, error) {
+	names = NormalizeTags(names)
+	if len(names) == 0 || len(mediaIDs) == 0 {
+		return 0, nil
+	}
+
+	// 先建立缺少的標籤 (保留第一次建立時的大小寫)
+	_, err := db.ExecContext(ctx, `
+		INSERT INTO tags (user_id, name)
+		SELECT $1, n FROM unnest($2::text[]) AS n
+		ON CONFLICT (user_id, lower(name)) DO NOTHING
+	`, userID, names)
+	if err != nil {
+		return 0, fmt.Errorf("failed to create tags: %w", err)
+	}
+
+	res, err := db.ExecContext(ctx, `
+		INSERT INTO media_tags (media_id, tag_id, source)
+		SELECT m.id, t.id, $4
+		FROM media m
+		JOIN tags t ON t.user_id = $1 AND lower(t.name) = ANY(SELECT lower(n) FROM unnest($3::text[]) AS n)
+		WHERE m.user_id = $1 AND m.id = ANY($2::uuid[])
+		ON CONFLICT (media_id, tag_id) DO NOTHING
+	`, userID, mediaIDs, names, source)
+	if err != nil {
+		return 0, fmt.Errorf("failed to tag media: %w", err)
+	}
+	return res.RowsAffected()
+}
+
+// RemoveTags 從多個媒體移除標籤，並清除已無任何媒體的標籤
+// 回傳移除的關聯數量
+func (s *Service) RemoveTags(ctx context.Context, userID string, mediaIDs, names []string) (int64, error) {
+	names = NormalizeTags(names)
+	if len(names) == 0 || len(mediaIDs) == 0 {
+		return 0, nil
+	}
+
+	tx, err := s.DB.BeginTx(ctx, nil)
+	if err != nil {
+		return 0, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	res, err := tx.ExecContext(ctx, `
+		DELETE FROM media_tags mt
+		USING tags t, media m
+		WHERE mt.tag_id = t.id AND mt.media_id = m.id
+		  AND t.user_id = $1 AND m.user_id = $1
+		  AND m.id = ANY($2::uuid[])
+		  AND lower(t.name) = ANY(SELECT lower(n) FROM unnest($3::text[]) AS n)
+	`, userID, mediaIDs, names)
+	if err != nil {
+		return 0, fmt.Errorf("failed to untag media: %w", err)
+	}
+
+	_, err = tx.ExecContext(ctx, `
+		DELETE FROM tags t
+		WHERE t.user_id = $1
+		  AND lower(t.name) = ANY(SELECT lower(n) FROM unnest($2::text[]) AS n)
+		  AND NOT EXISTS (SELECT 1 FROM media_tags mt WHERE mt.tag_id = t.id)
+	`, userID, names)
+	if err != nil {
+		return 0, fmt.Errorf("failed to clean up tags: %w", err)
+	}
+
+	if err := tx.Commit(); err != nil {
+		return 0, fmt.Errorf("failed to commit: %w", err)
+	}
+	return res.RowsAffected()
+}
+
+// ListTags 取得使用者所有標籤與使用數量 (依名稱排序)
+func (s *Service) ListTags(ctx context.Context, userID string) ([]*Tag, error) {
+	query := `
+		SELECT t.id, t.name, COUNT(m.id)
+		FROM tags t
+		LEFT JOIN media_tags mt ON mt.tag_id = t.id
+		LEFT JOIN media m ON m.id = mt.media_id AND m.deleted_at IS NULL
+		WHERE t.user_id = $1
+		GROUP BY t.id, t.name
+		ORDER BY lower(t.name)
+	`
+	rows, err := s.DB.QueryContext(ctx, query, userID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query tags: %w", err)
+	}
+	defer rows.Close()
+
+	list := []*Tag{}
+	for rows.Next() {
+		t := &Tag{}
+		if err := rows.Scan(&t.ID, &t.Name, &t.MediaCount); err != nil {
+			return nil, fmt.Errorf("failed to scan tag: %w", err)
+		}
+		list = append(list, t)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to iterate tags: %w", err)
+	}
+	return list, nil
+}
+
+// tagFilterCondition 產生標籤篩選條件；matchAll 為 true 時需包含所有標籤 (AND)，否則任一 (OR)
+func tagFilterCondition(arg func(any) string, names []string, matchAll bool) string {
+	lowered := make([]string, len(names))
+	for i, n := range names {
+		lowered[i] = strings.ToLower(n)
+	}
+	if matchAll {
+		return `(SELECT COUNT(DISTINCT lower(t.name)) FROM media_tags mt JOIN tags t ON t.id = mt.tag_id
+			WHERE mt.media_id = m.id AND lower(t.name) = ANY(` + arg(lowered) + `::text[])) = ` + arg(len(lowered))
+	}
+	return `EXISTS (SELECT 1 FROM media_tags mt JOIN tags t ON t.id = mt.tag_id
+		WHERE mt.media_id = m.id AND lower(t.name) = ANY(` + arg(lowered) + `::text[]))`
+}
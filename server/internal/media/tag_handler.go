@@ -0,0 +1,77 @@
+package media
+
+import (
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+)
+
+type tagsRequest struct {
+	MediaIDs []string `json:"media_ids"`
+	Tags     []string `json:"tags"`
+}
+
+// bindTagsRequest 解析並驗證 {"media_ids": [...], "tags": [...]}
+func bindTagsRequest(c *gin.Context) (*tagsRequest, bool) {
+	var req tagsRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid request body"})
+		return nil, false
+	}
+	if err := validateIDs(req.MediaIDs); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return nil, false
+	}
+	if len(NormalizeTags(req.Tags)) == 0 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "tags must contain at least one valid name"})
+		return nil, false
+	}
+	return &req, true
+}
+
+// ListTagsHandler 取得所有標籤與使用數量 (GET /tags)
+func (h *Handler) ListTagsHandler(c *gin.Context) {
+	userID := c.MustGet("userID").(string)
+
+	tags, err := h.Service.ListTags(c.Request.Context(), userID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	jsonWithETag(c, http.StatusOK, tags)
+}
+
+// AddTagsHandler 為一或多個媒體加上標籤 (POST /media/tags)
+func (h *Handler) AddTagsHandler(c *gin.Context) {
+	userID := c.MustGet("userID").(string)
+	req, ok := bindTagsRequest(c)
+	if !ok {
+		return
+	}
+
+	added, err := h.Service.AddTags(c.Request.Context(), userID, req.MediaIDs, req.Tags)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"added": added})
+}
+
+// RemoveTagsHandler 從一或多個媒體移除標籤 (DELETE /media/tags)
+func (h *Handler) RemoveTagsHandler(c *gin.Context) {
+	userID := c.MustGet("userID").(string)
+	req, ok := bindTagsRequest(c)
+	if !ok {
+		return
+	}
+
+	removed, err := h.Service.RemoveTags(c.Request.Context(), userID, req.MediaIDs, req.Tags)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"removed": removed})
+}
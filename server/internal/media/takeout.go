@@ -0,0 +1,330 @@
+package media
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+	"unicode/utf8"
+)
+
+// Google Takeout 的檔名規則
+const (
+	takeoutSidecarSuffix = ".supplemental-metadata" // 2024 年之後的 sidecar 檔名：IMG_1234.JPG.supplemental-metadata.json
+	takeoutMaxStemRunes  = 46                       // sidecar 檔名 (不含 .json) 超過時會被截斷
+	takeoutEditedSuffix  = "-edited"                // 在 Google 相簿編輯後的版本，沿用原始檔的 sidecar
+	takeoutAlbumMetadata = "metadata.json"          // 相簿資料夾中的相簿資訊
+)
+
+var (
+	// takeoutYearFolder 依年份自動產生的資料夾 (不是相簿)
+	takeoutYearFolder = regexp.MustCompile(`^Photos from \d{4}$`)
+	// takeoutDuplicateSuffix 同名檔案的編號：IMG_1234(1).JPG 對應 IMG_1234.JPG(1).json
+	takeoutDuplicateSuffix = regexp.MustCompile(`\(\d+\)$`)
+)
+
+// takeoutGeo sidecar 中的位置 (0, 0 代表沒有位置)
+type takeoutGeo struct {
+	Latitude  float64 `json:"latitude"`
+	Longitude float64 `json:"longitude"`
+}
+
+func (g takeoutGeo) location() *Location {
+	if g.Latitude == 0 && g.Longitude == 0 {
+		return nil
+	}
+	if g.Latitude < -90 || g.Latitude > 90 || g.Longitude < -180 || g.Longitude > 180 {
+		return nil
+	}
+	return &Location{Latitude: g.Latitude, Longitude: g.Longitude}
+}
+
+// takeoutSidecar 媒體檔案旁的 *.json
+type takeoutSidecar struct {
+	Title          string `json:"title"` // 原始檔名 (Takeout 中的檔名可能被截斷)
+	Description    string `json:"description"`
+	PhotoTakenTime struct {
+		Timestamp string `json:"timestamp"` // Unix 秒數字串
+	} `json:"photoTakenTime"`
+	GeoData     takeoutGeo `json:"geoData"`     // Google 相簿中的位置 (含使用者修改)
+	GeoDataExif takeoutGeo `json:"geoDataExif"` // 檔案 EXIF 中的位置
+	Favorited   bool       `json:"favorited"`
+	Archived    bool       `json:"archived"`
+	Trashed     bool       `json:"trashed"`
+}
+
+func (sc *takeoutSidecar) takenAt() *time.Time {
+	sec, err := strconv.ParseInt(sc.PhotoTakenTime.Timestamp, 10, 64)
+	if err != nil || sec <= 0 {
+		return nil
+	}
+	t := time.Unix(sec, 0).UTC()
+	if t.Before(earliestTakenAt) {
+		return nil
+	}
+	return &t
+}
+
+func (sc *takeoutSidecar) location() *Location {
+	if loc := sc.GeoData.location(); loc != nil {
+		return loc
+	}
+	return sc.GeoDataExif.location()
+}
+
+// takeoutImport 單次匯入的狀態
+type takeoutImport struct {
+	s      *Service
+	userID string
+	root   string
+	albums map[string]string // 資料夾 → 相簿 ID ("" 代表不是相簿)
+	jsons  map[string]map[string]bool
+}
+
+// ImportTakeout 匯入解壓縮後的 Google Takeout 目錄 (可指定 Takeout/ 或 Takeout/Google Photos/)
+//
+// 媒體檔案透過與上傳相同的流程處理：EXIF 缺少的拍攝時間與位置以 sidecar 補上，
+// 說明寫入 caption，我的最愛與封存狀態一併匯入；非年份資料夾對應為同名相簿。
+// 重複執行是安全的：已存在的檔案 (相同 Hash) 略過，只補上相簿關聯，不覆寫使用者之後的修改
+func (s *Service) ImportTakeout(ctx context.Context, userID, dir string, progress func(ImportProgress)) (*ImportSummary, error) {
+	root, err := takeoutPhotosRoot(dir)
+	if err != nil {
+		return nil, err
+	}
+
+	imp := &takeoutImport{s: s, userID: userID, root: root, albums: map[string]string{}, jsons: map[string]map[string]bool{}}
+	files, err := imp.scan()
+	if err != nil {
+		return nil, err
+	}
+
+	sum := &ImportSummary{Failures: []ImportFailure{}}
+	sum.Total = len(files)
+	for _, path := range files {
+		if err := ctx.Err(); err != nil {
+			return sum, err
+		}
+		rel, _ := filepath.Rel(root, path)
+		status, err := imp.importOne(ctx, path, &sum.ImportProgress)
+		sum.record(rel, status, err)
+		if progress != nil {
+			progress(sum.ImportProgress)
+		}
+	}
+	sum.Current = ""
+	return sum, nil
+}
+
+// takeoutPhotosRoot 找出 Google 相簿的資料夾
+func takeoutPhotosRoot(dir string) (string, error) {
+	info, err := os.Stat(dir)
+	if err != nil {
+		return "", fmt.Errorf("failed to open takeout directory: %w", err)
+	}
+	if !info.IsDir() {
+		return "", fmt.Errorf("not a directory: %s", dir)
+	}
+	for _, sub := range []string{"Google Photos", filepath.Join("Takeout", "Google Photos")} {
+		if info, err := os.Stat(filepath.Join(dir, sub)); err == nil && info.IsDir() {
+			return filepath.Join(dir, sub), nil
+		}
+	}
+	return dir, nil
+}
+
+// scan 列出所有媒體檔案 (依路徑排序，重複執行時順序一致) 並建立各資料夾的 json 索引
+func (imp *takeoutImport) scan() ([]string, error) {
+	var files []string
+	err := filepath.WalkDir(imp.root, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if d.IsDir() || !d.Type().IsRegular() {
+			return nil
+		}
+		dir, name := filepath.Split(path)
+		if strings.EqualFold(filepath.Ext(name), ".json") {
+			if imp.jsons[dir] == nil {
+				imp.jsons[dir] = map[string]bool{}
+			}
+			imp.jsons[dir][name] = true
+		} else if isImportCandidate(name) {
+			files = append(files, path)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to scan takeout directory: %w", err)
+	}
+	sort.Strings(files)
+	return files, nil
+}
+
+// importOne 匯入單一檔案並加入資料夾對應的相簿
+func (imp *takeoutImport) importOne(ctx context.Context, path string, p *ImportProgress) (string, error) {
+	sidecar, err := imp.sidecar(path)
+	if err != nil {
+		return "", err
+	}
+	// 使用者匯入後移到垃圾桶的項目不重新建立
+	opts := ingestOptions{SkipTrashed: true}
+	filename := ""
+	if sidecar != nil {
+		if sidecar.Trashed {
+			return "skipped", nil
+		}
+		opts.TakenAt = sidecar.takenAt()
+		opts.Location = sidecar.location()
+		opts.Caption = truncateRunes(strings.TrimSpace(sidecar.Description), MaxCaptionLength)
+		// 以 sidecar 還原被截斷的原始檔名 (編輯後的版本保留 -edited 檔名)
+		if sidecar.Title != "" && strings.EqualFold(filepath.Ext(sidecar.Title), filepath.Ext(path)) &&
+			!strings.HasSuffix(strings.TrimSuffix(filepath.Base(path), filepath.Ext(path)), takeoutEditedSuffix) {
+			filename = sidecar.Title
+		}
+	}
+
+	result, err := imp.s.importFile(ctx, imp.userID, path, filename, opts)
+	if err != nil {
+		return "", err
+	}
+	if result.Status == "skipped" {
+		return result.Status, nil
+	}
+	mediaID := result.ExistingID
+	if result.Status == "created" {
+		mediaID = result.Media.ID
+		if sidecar != nil {
+			imp.applyFlags(ctx, mediaID, sidecar)
+		}
+	}
+
+	albumID, err := imp.album(ctx, filepath.Dir(path), p)
+	if err != nil {
+		return result.Status, err
+	}
+	if albumID != "" {
+		n, err := imp.s.addToAlbum(ctx, imp.s.DB, imp.userID, albumID, []string{mediaID})
+		if err != nil {
+			return result.Status, err
+		}
+		if n > 0 {
+			imp.s.touchAlbum(ctx, albumID)
+		}
+	}
+	return result.Status, nil
+}
+
+// applyFlags 匯入我的最愛與封存狀態 (只用於新建立的項目，失敗不影響匯入)
+func (imp *takeoutImport) applyFlags(ctx context.Context, mediaID string, sc *takeoutSidecar) {
+	if sc.Favorited {
+		if _, err := setMediaFlag(ctx, imp.s.DB, "is_favorite", imp.userID, []string{mediaID}, true); err != nil {
+			fmt.Printf("failed to import favorite for %s: %v\n", mediaID, err)
+		}
+	}
+	if sc.Archived {
+		if _, err := setMediaFlag(ctx, imp.s.DB, "is_archived", imp.userID, []string{mediaID}, true); err != nil {
+			fmt.Printf("failed to import archived state for %s: %v\n", mediaID, err)
+		}
+	}
+}
+
+// sidecar 讀取媒體檔案對應的 json；找不到時回傳 nil (仍會匯入，只是沒有補充資訊)
+func (imp *takeoutImport) sidecar(path string) (*takeoutSidecar, error) {
+	dir, name := filepath.Split(path)
+	for _, candidate := range takeoutSidecarNames(name) {
+		if !imp.jsons[dir][candidate] {
+			continue
+		}
+		data, err := os.ReadFile(filepath.Join(dir, candidate))
+		if err != nil {
+			return nil, fmt.Errorf("failed to read sidecar: %w", err)
+		}
+		sc := &takeoutSidecar{}
+		if err := json.Unmarshal(data, sc); err != nil {
+			return nil, fmt.Errorf("invalid sidecar %s: %w", candidate, err)
+		}
+		return sc, nil
+	}
+	return nil, nil
+}
+
+// takeoutSidecarNames 依優先順序列出媒體檔案可能的 sidecar 檔名
+func takeoutSidecarNames(name string) []string {
+	ext := filepath.Ext(name)
+	base := strings.TrimSuffix(name, ext)
+	base = strings.TrimSuffix(base, takeoutEditedSuffix)
+	dup := takeoutDuplicateSuffix.FindString(base)
+	base = strings.TrimSuffix(base, dup)
+
+	var names []string
+	for _, stem := range []string{base + ext + takeoutSidecarSuffix, base + ext, base} {
+		names = append(names, truncateRunes(stem, takeoutMaxStemRunes)+dup+".json")
+	}
+	return names
+}
+
+// album 回傳資料夾對應的相簿 ID；同名相簿已存在時沿用 (重複執行不會建立重複的相簿)
+func (imp *takeoutImport) album(ctx context.Context, dir string, p *ImportProgress) (string, error) {
+	if id, ok := imp.albums[dir]; ok {
+		return id, nil
+	}
+	title := takeoutAlbumTitle(imp.root, dir)
+	if title == "" {
+		imp.albums[dir] = ""
+		return "", nil
+	}
+
+	var id string
+	err := imp.s.DB.QueryRowContext(ctx, `
+		SELECT id FROM albums WHERE user_id = $1 AND title = $2 ORDER BY created_at LIMIT 1
+	`, imp.userID, title).Scan(&id)
+	if err == sql.ErrNoRows {
+		album, err := imp.s.CreateAlbum(ctx, imp.userID, title, "")
+		if err != nil {
+			return "", err
+		}
+		id = album.ID
+		p.Albums++
+	} else if err != nil {
+		return "", fmt.Errorf("failed to query album: %w", err)
+	}
+	imp.albums[dir] = id
+	return id, nil
+}
+
+// takeoutAlbumTitle 資料夾對應的相簿名稱：優先使用 metadata.json 的 title，
+// 根目錄與依年份產生的資料夾不是相簿
+func takeoutAlbumTitle(root, dir string) string {
+	if filepath.Clean(dir) == filepath.Clean(root) {
+		return ""
+	}
+	if data, err := os.ReadFile(filepath.Join(dir, takeoutAlbumMetadata)); err == nil {
+		var meta struct {
+			Title string `json:"title"`
+		}
+		if json.Unmarshal(data, &meta) == nil && strings.TrimSpace(meta.Title) != "" {
+			return strings.TrimSpace(meta.Title)
+		}
+	}
+	name := filepath.Base(dir)
+	if takeoutYearFolder.MatchString(name) {
+		return ""
+	}
+	return name
+}
+
+// truncateRunes 截斷至 n 個字元 (不切斷多位元組字元)
+func truncateRunes(s string, n int) string {
+	if utf8.RuneCountInString(s) <= n {
+		return s
+	}
+	return string([]rune(s)[:n])
+}
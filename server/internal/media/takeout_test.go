@@ -0,0 +1,154 @@
+package media
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/DATA-DOG/go-sqlmock"
+)
+
+func TestTakeoutSidecarNames(t *testing.T) {
+	tests := []struct {
+		file string
+		want string // 第一個候選檔名
+	}{
+		{"IMG_1234.JPG", "IMG_1234.JPG.supplemental-metadata.json"},
+		{"IMG_1234(1).JPG", "IMG_1234.JPG.supplemental-metadata(1).json"},
+		{"IMG_1234-edited.JPG", "IMG_1234.JPG.supplemental-metadata.json"},
+		{"Screenshot_20200101-120000_Messages.jpg", "Screenshot_20200101-120000_Messages.jpg.supple.json"},
+	}
+	for _, tt := range tests {
+		names := takeoutSidecarNames(tt.file)
+		if names[0] != tt.want {
+			t.Errorf("%s: expected %q, got %q", tt.file, tt.want, names[0])
+		}
+	}
+	if names := takeoutSidecarNames("IMG_1234.JPG"); names[1] != "IMG_1234.JPG.json" || names[2] != "IMG_1234.json" {
+		t.Errorf("unexpected fallback names: %v", names)
+	}
+}
+
+func TestTakeoutSidecarValues(t *testing.T) {
+	sc := &takeoutSidecar{}
+	sc.PhotoTakenTime.Timestamp = "1577880000"
+	sc.GeoDataExif = takeoutGeo{Latitude: 35.0, Longitude: 135.7}
+	if got := sc.takenAt(); got == nil || got.Unix() != 1577880000 {
+		t.Errorf("unexpected taken_at: %v", got)
+	}
+	if loc := sc.location(); loc == nil || loc.Latitude != 35.0 {
+		t.Errorf("expected exif location fallback, got %v", loc)
+	}
+
+	sc.PhotoTakenTime.Timestamp = "0"
+	sc.GeoDataExif = takeoutGeo{}
+	if sc.takenAt() != nil || sc.location() != nil {
+		t.Error("zero values should be treated as missing")
+	}
+}
+
+func writeTakeoutFile(t *testing.T, path, content string) {
+	t.Helper()
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(path, []byte(content), 0o644); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestTakeoutAlbumTitle(t *testing.T) {
+	root := t.TempDir()
+	writeTakeoutFile(t, filepath.Join(root, "Kyoto", takeoutAlbumMetadata), `{"title": "Kyoto 2019 🍁"}`)
+
+	tests := map[string]string{
+		root:                                    "",
+		filepath.Join(root, "Photos from 2019"): "",
+		filepath.Join(root, "Kyoto"):            "Kyoto 2019 🍁",
+		filepath.Join(root, "Family"):           "Family",
+	}
+	for dir, want := range tests {
+		if got := takeoutAlbumTitle(root, dir); got != want {
+			t.Errorf("%s: expected %q, got %q", dir, want, got)
+		}
+	}
+}
+
+// 重複執行：已匯入的檔案略過，但仍補上相簿關聯 (沿用既有相簿)；垃圾桶中的項目不匯入
+func TestImportTakeoutRerun(t *testing.T) {
+	dir := t.TempDir()
+	photos := filepath.Join(dir, "Takeout", "Google Photos")
+	writeTakeoutFile(t, filepath.Join(photos, "Photos from 2020", "IMG_2.jpg"), "two")
+	writeTakeoutFile(t, filepath.Join(photos, "Photos from 2020", "IMG_3.jpg"), "three")
+	writeTakeoutFile(t, filepath.Join(photos, "Photos from 2020", "IMG_3.jpg.json"), `{"trashed": true}`)
+	writeTakeoutFile(t, filepath.Join(photos, "Photos from 2020", "print-subscriptions.json"), `{}`)
+	writeTakeoutFile(t, filepath.Join(photos, "Trip", "IMG_1.JPG"), "one")
+	writeTakeoutFile(t, filepath.Join(photos, "Trip", "IMG_1.JPG.supplemental-metadata.json"), `{"title": "IMG_1.JPG", "favorited": true}`)
+	writeTakeoutFile(t, filepath.Join(photos, "Trip", takeoutAlbumMetadata), `{"title": "Trip to Kyoto"}`)
+	writeTakeoutFile(t, filepath.Join(photos, "Trip", "notes.txt"), "ignored")
+
+	db, mock := newMockDB(t)
+	s := &Service{DB: db, UploadDir: t.TempDir()}
+
+	mock.ExpectQuery(`SELECT id FROM media WHERE user_id = \$1 AND file_hash = \$2`).
+		WithArgs("user-1", sqlmock.AnyArg()).
+		WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow("media-2"))
+	mock.ExpectQuery(`SELECT id FROM media WHERE user_id = \$1 AND file_hash = \$2`).
+		WithArgs("user-1", sqlmock.AnyArg()).
+		WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow("media-1"))
+	mock.ExpectQuery(`SELECT id FROM albums WHERE user_id = \$1 AND title = \$2`).
+		WithArgs("user-1", "Trip to Kyoto").
+		WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow("album-1"))
+	mock.ExpectExec(`INSERT INTO album_media`).
+		WithArgs("album-1", "user-1", []string{"media-1"}).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectExec(`UPDATE albums SET updated_at = NOW\(\)`).WillReturnResult(sqlmock.NewResult(0, 1))
+
+	var reports []ImportProgress
+	sum, err := s.ImportTakeout(context.Background(), "user-1", dir, func(p ImportProgress) {
+		reports = append(reports, p)
+	})
+	if err != nil {
+		t.Fatalf("ImportTakeout failed: %v", err)
+	}
+	if sum.Total != 3 || sum.Skipped != 3 || sum.Imported != 0 || sum.Failed != 0 || sum.Albums != 0 {
+		t.Errorf("unexpected summary: %+v", sum.ImportProgress)
+	}
+	if len(reports) != 3 || reports[2].Done != 3 || !strings.HasPrefix(reports[2].Current, "Trip") {
+		t.Errorf("unexpected progress reports: %+v", reports)
+	}
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("unmet expectations: %v", err)
+	}
+}
+
+// 匯入後被使用者移到垃圾桶的項目：重新執行時略過，不重新建立也不加回相簿
+func TestImportTakeoutSkipsTrashed(t *testing.T) {
+	dir := t.TempDir()
+	photos := filepath.Join(dir, "Takeout", "Google Photos")
+	writeTakeoutFile(t, filepath.Join(photos, "Trip", "IMG_1.JPG"), "one")
+	writeTakeoutFile(t, filepath.Join(photos, "Trip", takeoutAlbumMetadata), `{"title": "Trip to Kyoto"}`)
+
+	db, mock := newMockDB(t)
+	s := &Service{DB: db, UploadDir: t.TempDir()}
+
+	mock.ExpectQuery(`SELECT id FROM media WHERE user_id = \$1 AND file_hash = \$2 AND deleted_at IS NULL`).
+		WithArgs("user-1", sqlmock.AnyArg()).
+		WillReturnRows(sqlmock.NewRows([]string{"id"}))
+	mock.ExpectQuery(`SELECT id FROM media WHERE user_id = \$1 AND file_hash = \$2 AND deleted_at IS NOT NULL`).
+		WithArgs("user-1", sqlmock.AnyArg()).
+		WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow("media-1"))
+
+	sum, err := s.ImportTakeout(context.Background(), "user-1", dir, nil)
+	if err != nil {
+		t.Fatalf("ImportTakeout failed: %v", err)
+	}
+	if sum.Total != 1 || sum.Skipped != 1 || sum.Imported != 0 || sum.Failed != 0 {
+		t.Errorf("unexpected summary: %+v", sum.ImportProgress)
+	}
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("unmet expectations: %v", err)
+	}
+}
@@ -0,0 +1,110 @@
+package media
+
+import (
+	"context"
+	"fmt"
+	"time"
+	"unicode/utf8"
+)
+
+// MaxCaptionLength 說明文字長度上限 (字元數)
+const MaxCaptionLength = 2000
+
+// earliestTakenAt 拍攝時間的合理下限 (攝影術發明之前的日期視為錯誤輸入)
+var earliestTakenAt = time.Date(1826, time.January, 1, 0, 0, 0, 0, time.UTC)
+
+// validRating 評等是否有效 (0 代表未評等，以 nil 表示)
+func validRating(r int) bool {
+	return r == -1 || (r >= 1 && r <= 5)
+}
+
+// Location 經緯度
+type Location struct {
+	Latitude  float64 `json:"latitude"`
+	Longitude float64 `json:"longitude"`
+}
+
+// MediaPatch 描述 PATCH /media/:id 的變更；未設定的欄位保持不變
+type MediaPatch struct {
+	SetTakenAt bool
+	TakenAt    *time.Time // SetTakenAt 且為 nil 時清除拍攝時間
+
+	SetLocation bool
+	Location    *Location // SetLocation 且為 nil 時清除位置
+
+	Caption *string
+
+	SetRating bool
+	Rating    *int // SetRating 且為 nil 時清除評等
+
+	RevertTakenAt  bool // 還原為解析出的原始拍攝時間
+	RevertLocation bool // 還原為解析出的原始位置
+}
+
+// Validate 檢查覆寫值是否合理
+func (p *MediaPatch) Validate() error {
+	if p.SetTakenAt && p.RevertTakenAt {
+		return fmt.Errorf("taken_at cannot be set and reverted at the same time")
+	}
+	if p.SetLocation && p.RevertLocation {
+		return fmt.Errorf("location cannot be set and reverted at the same time")
+	}
+	if p.TakenAt != nil {
+		if p.TakenAt.Before(earliestTakenAt) || p.TakenAt.After(time.Now().Add(24*time.Hour)) {
+			return fmt.Errorf("taken_at is out of range")
+		}
+	}
+	if p.Location != nil {
+		if p.Location.Latitude < -90 || p.Location.Latitude > 90 {
+			return fmt.Errorf("latitude must be between -90 and 90")
+		}
+		if p.Location.Longitude < -180 || p.Location.Longitude > 180 {
+			return fmt.Errorf("longitude must be between -180 and 180")
+		}
+	}
+	if p.Rating != nil && !validRating(*p.Rating) {
+		return fmt.Errorf("rating must be between 1 and 5, or -1 for rejected")
+	}
+	if p.Caption != nil && utf8.RuneCountInString(*p.Caption) > MaxCaptionLength {
+		return fmt.Errorf("caption exceeds %d characters", MaxCaptionLength)
+	}
+	return nil
+}
+
+// Update 套用使用者覆寫並回傳更新後的媒體
+// 生效值直接寫入 taken_at / latitude / longitude，因此列表排序與「那年今日」會立即反映；
+// 同步紀錄由資料庫觸發器記錄為 update
+func (s *Service) Update(ctx context.Context, userID, mediaID string, p MediaPatch) (*Media, error) {
+	if err := p.Validate(); err != nil {
+		return nil, err
+	}
+
+	var lat, lng *float64
+	if p.Location != nil {
+		lat, lng = &p.Location.Latitude, &p.Location.Longitude
+	}
+
+	query := `
+		UPDATE media SET
+			taken_at = CASE WHEN $3 THEN extracted_taken_at WHEN $4 THEN $5::timestamptz ELSE taken_at END,
+			latitude = CASE WHEN $6 THEN extracted_latitude WHEN $7 THEN $8::double precision ELSE latitude END,
+			longitude = CASE WHEN $6 THEN extracted_longitude WHEN $7 THEN $9::double precision ELSE longitude END,
+			caption = COALESCE($10, caption),
+			rating = CASE WHEN $11 THEN $12::smallint ELSE rating END
+		WHERE id = $1 AND user_id = $2 AND deleted_at IS NULL
+	`
+	res, err := s.DB.ExecContext(ctx, query, mediaID, userID,
+		p.RevertTakenAt, p.SetTakenAt, p.TakenAt,
+		p.RevertLocation, p.SetLocation, lat, lng,
+		p.Caption,
+		p.SetRating, p.Rating,
+	)
+	if err != nil {
+		return nil, fmt.Errorf("failed to update media: %w", err)
+	}
+	if n, err := res.RowsAffected(); err == nil && n == 0 {
+		return nil, ErrMediaNotFound
+	}
+
+	return s.GetByID(ctx, userID, mediaID)
+}
@@ -0,0 +1,130 @@
+package media
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// parseMediaPatch 解析 PATCH body；欄位未出現代表不修改，明確給 null 代表清除
+func parseMediaPatch(body []byte) (MediaPatch, error) {
+	var p MediaPatch
+	var raw map[string]json.RawMessage
+	if err := json.Unmarshal(body, &raw); err != nil {
+		return p, fmt.Errorf("invalid request body")
+	}
+
+	for key, value := range raw {
+		isNull := string(value) == "null"
+		switch key {
+		case "taken_at":
+			p.SetTakenAt = true
+			if !isNull {
+				var t time.Time
+				if err := json.Unmarshal(value, &t); err != nil {
+					return p, fmt.Errorf("taken_at must be an RFC3339 timestamp")
+				}
+				p.TakenAt = &t
+			}
+		case "location":
+			p.SetLocation = true
+			if !isNull {
+				var loc struct {
+					Latitude  *float64 `json:"latitude"`
+					Longitude *float64 `json:"longitude"`
+				}
+				if err := json.Unmarshal(value, &loc); err != nil || loc.Latitude == nil || loc.Longitude == nil {
+					return p, fmt.Errorf("location requires latitude and longitude")
+				}
+				p.Location = &Location{Latitude: *loc.Latitude, Longitude: *loc.Longitude}
+			}
+		case "caption":
+			caption := ""
+			if !isNull {
+				if err := json.Unmarshal(value, &caption); err != nil {
+					return p, fmt.Errorf("caption must be a string")
+				}
+			}
+			p.Caption = &caption
+		case "rating":
+			p.SetRating = true
+			if !isNull {
+				var r int
+				if err := json.Unmarshal(value, &r); err != nil {
+					return p, fmt.Errorf("rating must be an integer")
+				}
+				// 0 與 XMP 相同代表未評等
+				if r != 0 {
+					p.Rating = &r
+				}
+			}
+		case "revert":
+			var fields []string
+			if err := json.Unmarshal(value, &fields); err != nil {
+				return p, fmt.Errorf("revert must be a list of fields")
+			}
+			for _, f := range fields {
+				switch f {
+				case "taken_at":
+					p.RevertTakenAt = true
+				case "location":
+					p.RevertLocation = true
+				default:
+					return p, fmt.Errorf("cannot revert field: %s", f)
+				}
+			}
+		default:
+			return p, fmt.Errorf("unknown field: %s", key)
+		}
+	}
+	return p, nil
+}
+
+// UpdateHandler 修改 Metadata (PATCH /media/:id)
+//
+// body 欄位皆為選填：
+//   - taken_at: RFC3339 時間，null 代表清除
+//   - location: {"latitude": 25.03, "longitude": 121.56}，null 代表清除
+//   - caption: 說明文字
+//   - rating: 1~5 星，-1 代表排除，0 或 null 代表清除
+//   - revert: ["taken_at", "location"] 還原為檔案解析出的原始值
+func (h *Handler) UpdateHandler(c *gin.Context) {
+	userID := c.MustGet("userID").(string)
+	mediaID := c.Param("id")
+
+	if !uuidPattern.MatchString(mediaID) {
+		c.JSON(http.StatusNotFound, gin.H{"error": ErrMediaNotFound.Error()})
+		return
+	}
+
+	body, err := c.GetRawData()
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid request body"})
+		return
+	}
+	patch, err := parseMediaPatch(body)
+	if err == nil {
+		err = patch.Validate()
+	}
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	media, err := h.Service.Update(c.Request.Context(), userID, mediaID, patch)
+	if err != nil {
+		if errors.Is(err, ErrMediaNotFound) {
+			c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	h.attachURLs(userID, media)
+	c.JSON(http.StatusOK, media)
+}
@@ -0,0 +1,62 @@
+package media
+
+import "testing"
+
+func TestParseMediaPatch(t *testing.T) {
+	p, err := parseMediaPatch([]byte(`{"taken_at": "2001-09-09T01:46:40Z", "location": null, "caption": "Scanned"}`))
+	if err != nil {
+		t.Fatalf("parseMediaPatch failed: %v", err)
+	}
+	if !p.SetTakenAt || p.TakenAt == nil || p.TakenAt.Unix() != 1000000000 {
+		t.Errorf("unexpected taken_at: %+v", p)
+	}
+	if !p.SetLocation || p.Location != nil {
+		t.Errorf("expected location to be cleared: %+v", p)
+	}
+	if p.Caption == nil || *p.Caption != "Scanned" {
+		t.Errorf("unexpected caption: %+v", p.Caption)
+	}
+
+	p, err = parseMediaPatch([]byte(`{"revert": ["taken_at", "location"]}`))
+	if err != nil || !p.RevertTakenAt || !p.RevertLocation || p.SetTakenAt {
+		t.Errorf("unexpected revert patch: %+v, %v", p, err)
+	}
+
+	p, err = parseMediaPatch([]byte(`{"rating": 0}`))
+	if err != nil || !p.SetRating || p.Rating != nil {
+		t.Errorf("expected rating 0 to clear: %+v, %v", p, err)
+	}
+
+	invalid := []string{
+		`{"unknown": 1}`,
+		`{"rating": "five"}`,
+		`{"location": {"latitude": 10}}`,
+		`{"revert": ["caption"]}`,
+		`{"taken_at": "yesterday"}`,
+	}
+	for _, body := range invalid {
+		if _, err := parseMediaPatch([]byte(body)); err == nil {
+			t.Errorf("expected error for %s", body)
+		}
+	}
+}
+
+func TestMediaPatchValidate(t *testing.T) {
+	invalid := []string{
+		`{"location": {"latitude": 91, "longitude": 0}}`,
+		`{"location": {"latitude": 0, "longitude": -181}}`,
+		`{"taken_at": "1700-01-01T00:00:00Z"}`,
+		`{"taken_at": "2001-01-01T00:00:00Z", "revert": ["taken_at"]}`,
+		`{"rating": 6}`,
+		`{"rating": -2}`,
+	}
+	for _, body := range invalid {
+		p, err := parseMediaPatch([]byte(body))
+		if err != nil {
+			t.Fatalf("parseMediaPatch(%s) failed: %v", body, err)
+		}
+		if err := p.Validate(); err == nil {
+			t.Errorf("expected validation error for %s", body)
+		}
+	}
+}
@@ -0,0 +1,209 @@
+package media
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+)
+
+// 衍生檔案的縮放模式
+const (
+	FitContain = "contain" // 等比縮放至框內 (預設)
+	FitCover   = "cover"   // 等比縮放並裁切填滿
+	FitFill    = "fill"    // 拉伸至指定尺寸
+)
+
+// 衍生檔案的輸出格式
+const (
+	FormatAuto     = "auto" // 依 Accept 協商 WebP 或 JPEG
+	FormatWebP     = "webp"
+	FormatJPEG     = "jpeg"
+	FormatOriginal = "original" // 原始檔 (位元組完全相同)
+)
+
+// MaxVariantDimension 衍生檔案的最大邊長
+const MaxVariantDimension = 4096
+
+// VariantOptions 描述一個衍生檔案
+type VariantOptions struct {
+	Width  int
+	Height int
+	Fit    string
+	Format string // FormatWebP 或 FormatJPEG (協商後)
+
+	negotiated bool // format 未指定或為 auto，由 Accept 協商
+}
+
+// displayableTypes 瀏覽器可直接顯示的圖片格式
+var displayableTypes = map[string]bool{
+	"image/jpeg": true,
+	"image/png":  true,
+	"image/gif":  true,
+	"image/webp": true,
+}
+
+// Validate 檢查參數並補上預設值
+func (o *VariantOptions) Validate() error {
+	if o.Width < 0 || o.Height < 0 || o.Width > MaxVariantDimension || o.Height > MaxVariantDimension {
+		return fmt.Errorf("invalid variant size: w and h must be between 1 and %d", MaxVariantDimension)
+	}
+	switch o.Fit {
+	case "":
+		o.Fit = FitContain
+	case FitContain, FitCover, FitFill:
+	default:
+		return fmt.Errorf("invalid variant fit: %s", o.Fit)
+	}
+	if (o.Fit == FitCover || o.Fit == FitFill) && (o.Width == 0 || o.Height == 0) {
+		return fmt.Errorf("fit=%s requires both w and h", o.Fit)
+	}
+	if o.Format != FormatWebP && o.Format != FormatJPEG {
+		return fmt.Errorf("invalid variant format: %s", o.Format)
+	}
+	return nil
+}
+
+// NegotiateFormat 將 format 參數 (含 auto) 依 Accept 標頭解析為實際輸出格式
+func NegotiateFormat(format, accept string) (string, error) {
+	switch strings.ToLower(format) {
+	case "", FormatAuto:
+		if strings.Contains(accept, "image/webp") {
+			return FormatWebP, nil
+		}
+		return FormatJPEG, nil
+	case FormatWebP:
+		return FormatWebP, nil
+	case FormatJPEG, "jpg":
+		return FormatJPEG, nil
+	case FormatOriginal:
+		return FormatOriginal, nil
+	}
+	return "", fmt.Errorf("unsupported format: %s", format)
+}
+
+// ContentType 回傳輸出格式的 MIME type
+func (o VariantOptions) ContentType() string {
+	if o.Format == FormatWebP {
+		return "image/webp"
+	}
+	return "image/jpeg"
+}
+
+// cacheKey 以檔案 Hash 為基礎：內容相同的媒體共用衍生檔案，且內容不變故永不過期
+func (o VariantOptions) cacheKey(fileHash string) string {
+	ext := "jpg"
+	if o.Format == FormatWebP {
+		ext = "webp"
+	}
+	return fmt.Sprintf("%s/%s/%dx%d_%s.%s", fileHash[:2], fileHash, o.Width, o.Height, o.Fit, ext)
+}
+
+// CanServeOriginal 不需縮放且原始格式即為協商結果 (或未指定格式且原始檔可直接顯示) 時，可直接回傳原始檔
+func (o VariantOptions) CanServeOriginal(m *Media) bool {
+	if o.Width != 0 || o.Height != 0 {
+		return false
+	}
+	return m.MimeType == o.ContentType() || (o.negotiated && displayableTypes[m.MimeType])
+}
+
+// Variant 取得 (必要時產生) 衍生檔案並回傳已開啟的檔案 (呼叫端需關閉)
+// 原始檔不會被修改；影片以第一秒的畫面作為來源
+func (s *Service) Variant(ctx context.Context, m *Media, opts VariantOptions) (*os.File, error) {
+	if s.Variants == nil {
+		return nil, fmt.Errorf("variant cache is not configured")
+	}
+	if len(m.FileHash) < 2 {
+		return nil, fmt.Errorf("invalid file hash")
+	}
+	if !strings.HasPrefix(m.MimeType, "image/") && !strings.HasPrefix(m.MimeType, "video/") {
+		return nil, fmt.Errorf("unsupported media type: %s", m.MimeType)
+	}
+
+	srcPath := filepath.Join(s.UploadDir, m.StoragePath)
+	return s.Variants.GetOrCreate(opts.cacheKey(m.FileHash), func(tmpPath string) error {
+		if strings.HasPrefix(m.MimeType, "video/") {
+			posterPath := tmpPath + ".poster.jpg"
+			defer os.Remove(posterPath)
+			if err := extractPosterFrame(ctx, srcPath, posterPath); err != nil {
+				return err
+			}
+			srcPath = posterPath
+		}
+		return runVipsThumbnail(ctx, srcPath, tmpPath, opts)
+	})
+}
+
+// extractPosterFrame 使用 ffmpeg 擷取影片第一秒的畫面 (過短的影片退回第一個畫格)
+func extractPosterFrame(ctx context.Context, videoPath, outPath string) error {
+	var lastErr error
+	for _, seek := range []string{"1", "0"} {
+		cmd := exec.CommandContext(ctx, "ffmpeg",
+			"-v", "error",
+			"-y",
+			"-ss", seek,
+			"-i", videoPath,
+			"-frames:v", "1",
+			"-q:v", "2",
+			outPath,
+		)
+		out, err := cmd.CombinedOutput()
+		if err != nil {
+			lastErr = fmt.Errorf("%w: %s", err, strings.TrimSpace(string(out)))
+			continue
+		}
+		if info, err := os.Stat(outPath); err == nil && info.Size() > 0 {
+			return nil
+		}
+		lastErr = fmt.Errorf("no frame at %ss", seek)
+	}
+	return fmt.Errorf("ffmpeg poster extraction failed: %w", lastErr)
+}
+
+// runVipsThumbnail 使用 libvips 縮放與轉檔 (支援 HEIC，並依 EXIF Orientation 自動轉正)
+func runVipsThumbnail(ctx context.Context, srcPath, outPath string, opts VariantOptions) error {
+	size := vipsSize(opts)
+
+	// vipsthumbnail 依副檔名決定輸出格式，先寫入正確副檔名再改名
+	target, saveOptions := outPath+".jpg", "[Q=82,strip,optimize_coding]"
+	if opts.Format == FormatWebP {
+		target, saveOptions = outPath+".webp", "[Q=80,strip]"
+	}
+	defer os.Remove(target)
+
+	args := []string{srcPath, "--size", size, "-o", target + saveOptions}
+	if opts.Fit == FitCover {
+		args = append(args, "--smartcrop", "centre")
+	}
+
+	cmd := exec.CommandContext(ctx, "vipsthumbnail", args...)
+	if out, err := cmd.CombinedOutput(); err != nil {
+		return fmt.Errorf("vipsthumbnail execution failed: %w: %s", err, strings.TrimSpace(string(out)))
+	}
+	return os.Rename(target, outPath)
+}
+
+// vipsSize 轉換為 vipsthumbnail 的 --size 參數
+// contain/cover 只縮小不放大 (">")；未指定尺寸時使用極大值 (僅轉檔)
+// 只有單邊時需寫成 "Wx" 或 "xH"：單一數字會被視為 W×W 的框，另一邊也會被限制
+func vipsSize(opts VariantOptions) string {
+	w, h := opts.Width, opts.Height
+	if w == 0 && h == 0 {
+		return fmt.Sprintf("%dx%d>", 100000, 100000)
+	}
+	var size string
+	switch {
+	case w == 0:
+		size = fmt.Sprintf("x%d", h)
+	case h == 0:
+		size = fmt.Sprintf("%dx", w)
+	default:
+		size = fmt.Sprintf("%dx%d", w, h)
+	}
+	if opts.Fit == FitFill {
+		return size + "!"
+	}
+	return size + ">"
+}
@@ -0,0 +1,205 @@
+package media
+
+import (
+	"container/list"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"slices"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// VariantCache 為衍生檔案 (縮圖、轉檔) 的磁碟快取，超過容量上限時依 LRU 淘汰
+//
+// 最近使用時間記錄在檔案的 mtime，重啟後可依此重建 LRU 順序
+type VariantCache struct {
+	Dir      string
+	MaxBytes int64
+
+	mu       sync.Mutex
+	size     int64
+	lru      *list.List               // Front 為最近使用
+	entries  map[string]*list.Element // key -> element (value 為 *cacheEntry)
+	inflight map[string]*inflightCall
+}
+
+type cacheEntry struct {
+	key  string
+	size int64
+}
+
+// inflightCall 合併同一 key 的並行產生請求
+type inflightCall struct {
+	done chan struct{}
+	err  error
+}
+
+// NewVariantCache 建立快取並掃描既有檔案
+func NewVariantCache(dir string, maxBytes int64) (*VariantCache, error) {
+	c := &VariantCache{
+		Dir:      dir,
+		MaxBytes: maxBytes,
+		lru:      list.New(),
+		entries:  make(map[string]*list.Element),
+		inflight: make(map[string]*inflightCall),
+	}
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return nil, fmt.Errorf("failed to create cache directory: %w", err)
+	}
+	if err := c.load(); err != nil {
+		return nil, err
+	}
+	return c, nil
+}
+
+// load 依 mtime 由舊到新重建 LRU
+func (c *VariantCache) load() error {
+	type found struct {
+		key     string
+		size    int64
+		modTime time.Time
+	}
+	var files []found
+	err := filepath.WalkDir(c.Dir, func(path string, d fs.DirEntry, err error) error {
+		if err != nil || d.IsDir() {
+			return err
+		}
+		info, err := d.Info()
+		if err != nil {
+			return err
+		}
+		if isCacheTempFile(d.Name()) {
+			return os.Remove(path) // 上次中斷留下的暫存檔
+		}
+		rel, err := filepath.Rel(c.Dir, path)
+		if err != nil {
+			return err
+		}
+		files = append(files, found{key: filepath.ToSlash(rel), size: info.Size(), modTime: info.ModTime()})
+		return nil
+	})
+	if err != nil {
+		return fmt.Errorf("failed to scan cache directory: %w", err)
+	}
+
+	sort.Slice(files, func(i, j int) bool { return files[i].modTime.Before(files[j].modTime) })
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	for _, f := range files {
+		c.entries[f.key] = c.lru.PushFront(&cacheEntry{key: f.key, size: f.size})
+		c.size += f.size
+	}
+	c.evictLocked()
+	return nil
+}
+
+// isCacheTempFile 是否為產生中的暫存檔：<key>.<n>.tmp 以及產生過程寫在旁邊的檔案
+// (例如影片封面 <key>.<n>.tmp.poster.jpg)；快取的 key 本身不含 tmp 段落
+func isCacheTempFile(name string) bool {
+	return slices.Contains(strings.Split(name, "."), "tmp")
+}
+
+func (c *VariantCache) path(key string) string {
+	return filepath.Join(c.Dir, filepath.FromSlash(key))
+}
+
+// GetOrCreate 開啟 key 對應的檔案 (呼叫端需關閉)；不存在時呼叫 create 寫入 tmpPath 產生
+// 同一 key 的並行請求只會執行一次 create。檔案在持有鎖時開啟，之後即使被淘汰刪除，已開啟的檔案仍可完整讀取
+func (c *VariantCache) GetOrCreate(key string, create func(tmpPath string) error) (*os.File, error) {
+	for {
+		c.mu.Lock()
+		if el, ok := c.entries[key]; ok {
+			path := c.path(key)
+			f, err := os.Open(path)
+			if err != nil {
+				// 檔案已被外部刪除：移除記錄後重新產生
+				c.removeLocked(el)
+				c.mu.Unlock()
+				continue
+			}
+			c.lru.MoveToFront(el)
+			c.mu.Unlock()
+			now := time.Now()
+			_ = os.Chtimes(path, now, now)
+			return f, nil
+		}
+		if call, ok := c.inflight[key]; ok {
+			c.mu.Unlock()
+			<-call.done
+			if call.err != nil {
+				return nil, call.err
+			}
+			continue
+		}
+		call := &inflightCall{done: make(chan struct{})}
+		c.inflight[key] = call
+		c.mu.Unlock()
+
+		call.err = c.create(key, create)
+
+		c.mu.Lock()
+		delete(c.inflight, key)
+		c.mu.Unlock()
+		close(call.done)
+		if call.err != nil {
+			return nil, call.err
+		}
+	}
+}
+
+func (c *VariantCache) create(key string, create func(tmpPath string) error) error {
+	path := c.path(key)
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return fmt.Errorf("failed to create cache directory: %w", err)
+	}
+	tmpPath := fmt.Sprintf("%s.%d.tmp", path, time.Now().UnixNano())
+	defer os.Remove(tmpPath)
+
+	if err := create(tmpPath); err != nil {
+		return err
+	}
+	info, err := os.Stat(tmpPath)
+	if err != nil {
+		return fmt.Errorf("variant was not generated: %w", err)
+	}
+	if err := os.Rename(tmpPath, path); err != nil {
+		return fmt.Errorf("failed to store variant: %w", err)
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.entries[key] = c.lru.PushFront(&cacheEntry{key: key, size: info.Size()})
+	c.size += info.Size()
+	c.evictLocked()
+	return nil
+}
+
+// evictLocked 淘汰最久未使用的檔案直到低於容量上限 (呼叫端需持有鎖)
+func (c *VariantCache) evictLocked() {
+	for c.MaxBytes > 0 && c.size > c.MaxBytes && c.lru.Len() > 1 {
+		entry := c.removeLocked(c.lru.Back())
+		if err := os.Remove(c.path(entry.key)); err != nil && !os.IsNotExist(err) {
+			fmt.Printf("failed to evict cached variant %s: %v\n", entry.key, err)
+		}
+	}
+}
+
+// removeLocked 移除記錄 (不刪除檔案；呼叫端需持有鎖)
+func (c *VariantCache) removeLocked(el *list.Element) *cacheEntry {
+	entry := el.Value.(*cacheEntry)
+	c.lru.Remove(el)
+	delete(c.entries, entry.key)
+	c.size -= entry.size
+	return entry
+}
+
+// Size 目前快取使用的位元組數
+func (c *VariantCache) Size() int64 {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.size
+}
@@ -0,0 +1,127 @@
+package media
+
+import (
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestVariantCacheEvictsLeastRecentlyUsed(t *testing.T) {
+	c, err := NewVariantCache(t.TempDir(), 25)
+	if err != nil {
+		t.Fatalf("NewVariantCache failed: %v", err)
+	}
+
+	calls := 0
+	write := func(tmpPath string) error {
+		calls++
+		return os.WriteFile(tmpPath, []byte(strings.Repeat("x", 10)), 0644)
+	}
+
+	get := func(key string) string {
+		t.Helper()
+		f, err := c.GetOrCreate(key, write)
+		if err != nil {
+			t.Fatalf("GetOrCreate %s failed: %v", key, err)
+		}
+		f.Close()
+		return f.Name()
+	}
+
+	pathA := get("aa/a.jpg")
+	get("bb/b.jpg")
+	// 命中 a，使 b 成為最久未使用
+	get("aa/a.jpg")
+	if calls != 2 {
+		t.Errorf("expected cache hit, create called %d times", calls)
+	}
+
+	pathC := get("cc/c.jpg")
+
+	if c.Size() != 20 {
+		t.Errorf("expected size 20 after eviction, got %d", c.Size())
+	}
+	for _, p := range []string{pathA, pathC} {
+		if _, err := os.Stat(p); err != nil {
+			t.Errorf("expected %s to remain: %v", p, err)
+		}
+	}
+	if _, err := os.Stat(c.path("bb/b.jpg")); !os.IsNotExist(err) {
+		t.Errorf("expected b to be evicted")
+	}
+
+	// 重新載入後應保留既有檔案
+	reloaded, err := NewVariantCache(c.Dir, 25)
+	if err != nil {
+		t.Fatalf("reload failed: %v", err)
+	}
+	if reloaded.Size() != 20 {
+		t.Errorf("expected reloaded size 20, got %d", reloaded.Size())
+	}
+}
+
+func TestVariantCacheOpenFileSurvivesEviction(t *testing.T) {
+	c, err := NewVariantCache(t.TempDir(), 15)
+	if err != nil {
+		t.Fatalf("NewVariantCache failed: %v", err)
+	}
+	write := func(content string) func(string) error {
+		return func(tmpPath string) error { return os.WriteFile(tmpPath, []byte(content), 0644) }
+	}
+
+	f, err := c.GetOrCreate("aa/a.jpg", write("aaaaaaaaaa"))
+	if err != nil {
+		t.Fatalf("GetOrCreate a failed: %v", err)
+	}
+	defer f.Close()
+
+	// 產生 b 會淘汰 a，但已開啟的 a 仍可完整讀取
+	b, err := c.GetOrCreate("bb/b.jpg", write("bbbbbbbbbb"))
+	if err != nil {
+		t.Fatalf("GetOrCreate b failed: %v", err)
+	}
+	b.Close()
+	if _, err := os.Stat(c.path("aa/a.jpg")); !os.IsNotExist(err) {
+		t.Fatalf("expected a to be evicted")
+	}
+	data, err := io.ReadAll(f)
+	if err != nil || string(data) != "aaaaaaaaaa" {
+		t.Errorf("expected evicted file to remain readable, got %q, %v", data, err)
+	}
+
+	// 被外部刪除的檔案會重新產生
+	os.Remove(c.path("bb/b.jpg"))
+	b, err = c.GetOrCreate("bb/b.jpg", write("bbbbbbbbbb"))
+	if err != nil {
+		t.Fatalf("expected missing file to be regenerated: %v", err)
+	}
+	b.Close()
+	if c.Size() != 10 {
+		t.Errorf("expected size 10, got %d", c.Size())
+	}
+}
+
+// 重啟時清除上次中斷留下的暫存檔，包含產生過程寫在暫存檔旁的檔案 (影片封面等)
+func TestVariantCacheLoadRemovesTempFiles(t *testing.T) {
+	dir := t.TempDir()
+	kept := filepath.Join(dir, "aa", "a.jpg")
+	leftovers := []string{kept + ".1700000000.tmp", kept + ".1700000000.tmp.poster.jpg", kept + ".1700000000.tmp.v.png"}
+	for _, path := range append([]string{kept}, leftovers...) {
+		writeTakeoutFile(t, path, "0123456789")
+	}
+
+	c, err := NewVariantCache(dir, 100)
+	if err != nil {
+		t.Fatalf("NewVariantCache failed: %v", err)
+	}
+	for _, path := range leftovers {
+		if _, err := os.Stat(path); !os.IsNotExist(err) {
+			t.Errorf("expected %s to be removed", filepath.Base(path))
+		}
+	}
+	if c.Size() != 10 {
+		t.Errorf("expected only the cached file to be counted, got %d", c.Size())
+	}
+}
@@ -0,0 +1,60 @@
+package media
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+)
+
+func TestVipsSize(t *testing.T) {
+	cases := []struct {
+		opts VariantOptions
+		want string
+	}{
+		{VariantOptions{Width: 400, Fit: FitContain}, "400x>"},
+		{VariantOptions{Height: 300, Fit: FitContain}, "x300>"},
+		{VariantOptions{Width: 400, Height: 300, Fit: FitCover}, "400x300>"},
+		{VariantOptions{Width: 400, Height: 300, Fit: FitFill}, "400x300!"},
+		{VariantOptions{Fit: FitContain}, "100000x100000>"},
+	}
+	for _, c := range cases {
+		if got := vipsSize(c.opts); got != c.want {
+			t.Errorf("vipsSize(%+v) = %q, want %q", c.opts, got, c.want)
+		}
+	}
+}
+
+func TestParseVariantQueryDefaultsToDisplayVariant(t *testing.T) {
+	parse := func(query string) (VariantOptions, bool) {
+		t.Helper()
+		c, _ := gin.CreateTestContext(httptest.NewRecorder())
+		c.Request = httptest.NewRequest(http.MethodGet, "/media/1/file"+query, nil)
+		c.Request.Header.Set("Accept", "image/avif,image/webp,*/*")
+		opts, original, err := parseVariantQuery(c)
+		if err != nil {
+			t.Fatalf("parseVariantQuery(%q) failed: %v", query, err)
+		}
+		return opts, original
+	}
+
+	opts, original := parse("")
+	if original || opts.Format != FormatWebP {
+		t.Fatalf("expected negotiated display variant, got %+v original=%v", opts, original)
+	}
+	// HEIC 需轉檔；瀏覽器可顯示的格式直接回傳原始檔
+	if opts.CanServeOriginal(&Media{MimeType: "image/heic"}) {
+		t.Error("HEIC must not be served as original by default")
+	}
+	if !opts.CanServeOriginal(&Media{MimeType: "image/jpeg"}) {
+		t.Error("JPEG can be served as is when no size is requested")
+	}
+
+	if _, original := parse("?format=original"); !original {
+		t.Error("expected original for format=original")
+	}
+	if opts, _ := parse("?format=jpeg"); opts.CanServeOriginal(&Media{MimeType: "image/png"}) {
+		t.Error("explicit format must be honoured")
+	}
+}
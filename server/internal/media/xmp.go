@@ -0,0 +1,289 @@
+package media
+
+import (
+	"bytes"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"math"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// XMP 命名空間
+const (
+	nsRDF       = "http://www.w3.org/1999/02/22-rdf-syntax-ns#"
+	nsDC        = "http://purl.org/dc/elements/1.1/"
+	nsXMP       = "http://ns.adobe.com/xap/1.0/"
+	nsEXIF      = "http://ns.adobe.com/exif/1.0/"
+	nsPhotoshop = "http://ns.adobe.com/photoshop/1.0/"
+)
+
+// xmpData 為從 XMP 中讀取的欄位
+type xmpData struct {
+	Keywords    []string // dc:subject
+	Title       string   // dc:title (優先使用 x-default 語系)
+	Description string   // dc:description (優先使用 x-default 語系)
+	Rating      *int     // xmp:Rating；0 代表未評等
+	TakenAt     *time.Time
+	Location    *Location // exif:GPSLatitude / exif:GPSLongitude
+}
+
+// findXMPPacket 在檔案內容中尋找 XMP 封包 (<x:xmpmeta> ... </x:xmpmeta>)
+// JPEG (APP1)、HEIC、PNG (iTXt)、TIFF 都以純文字 XML 形式內嵌，不需解析容器格式
+func findXMPPacket(data []byte) []byte {
+	start := bytes.Index(data, []byte("<x:xmpmeta"))
+	if start < 0 {
+		return nil
+	}
+	endTag := []byte("</x:xmpmeta>")
+	end := bytes.Index(data[start:], endTag)
+	if end < 0 {
+		return nil
+	}
+	return data[start : start+end+len(endTag)]
+}
+
+// parseXMP 以串流方式解析 XMP，只擷取需要的欄位，未知內容一律略過
+// 簡單屬性可寫成 rdf:Description 的屬性或子元素 (Lightroom 與 darktable 各用一種)，兩者皆支援
+func parseXMP(packet []byte) (*xmpData, error) {
+	d := xml.NewDecoder(bytes.NewReader(packet))
+	d.Strict = false
+
+	result := &xmpData{}
+	var stack []xml.Name
+	var text strings.Builder
+	var lang string
+	var lat, lng *float64
+	var takenAt, dateCreated string
+	titleDefault, descDefault := false, false
+
+	inside := func(space, local string) bool {
+		for _, n := range stack {
+			if n.Space == space && n.Local == local {
+				return true
+			}
+		}
+		return false
+	}
+
+	// property 處理簡單屬性 (屬性或元素內容)
+	property := func(name xml.Name, value string) {
+		value = strings.TrimSpace(value)
+		switch {
+		case name.Space == nsXMP && name.Local == "Rating":
+			// 規格允許實數 (例如 "3.0")
+			if r, err := strconv.ParseFloat(value, 64); err == nil && r >= -1 && r <= 5 {
+				rating := int(math.Round(r))
+				result.Rating = &rating
+			}
+		case name.Space == nsEXIF && name.Local == "GPSLatitude":
+			if v, ok := parseXMPCoordinate(value, 'N', 'S'); ok {
+				lat = &v
+			}
+		case name.Space == nsEXIF && name.Local == "GPSLongitude":
+			if v, ok := parseXMPCoordinate(value, 'E', 'W'); ok {
+				lng = &v
+			}
+		case name.Space == nsEXIF && name.Local == "DateTimeOriginal":
+			takenAt = value
+		case name.Space == nsPhotoshop && name.Local == "DateCreated":
+			dateCreated = value
+		}
+	}
+
+	// alt 處理語系替代文字：第一個值之後只接受 x-default 取代
+	alt := func(dst *string, isDefault *bool, value string) {
+		value = strings.TrimSpace(value)
+		if value == "" || *isDefault {
+			return
+		}
+		if lang == "x-default" {
+			*dst, *isDefault = value, true
+		} else if *dst == "" {
+			*dst = value
+		}
+	}
+
+	for {
+		tok, err := d.Token()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return result, err
+		}
+
+		switch t := tok.(type) {
+		case xml.StartElement:
+			stack = append(stack, t.Name)
+			text.Reset()
+			for _, a := range t.Attr {
+				if a.Name.Local == "lang" && t.Name.Space == nsRDF && t.Name.Local == "li" {
+					lang = a.Value
+				}
+				property(a.Name, a.Value)
+			}
+		case xml.CharData:
+			text.Write(t)
+		case xml.EndElement:
+			value := text.String()
+			if t.Name.Space == nsRDF && t.Name.Local == "li" {
+				switch {
+				case inside(nsDC, "subject"):
+					if kw := strings.TrimSpace(value); kw != "" {
+						result.Keywords = append(result.Keywords, kw)
+					}
+				case inside(nsDC, "title"):
+					alt(&result.Title, &titleDefault, value)
+				case inside(nsDC, "description"):
+					alt(&result.Description, &descDefault, value)
+				}
+				lang = ""
+			} else {
+				property(t.Name, value)
+			}
+			if len(stack) > 0 {
+				stack = stack[:len(stack)-1]
+			}
+			text.Reset()
+		}
+	}
+
+	if lat != nil && lng != nil {
+		result.Location = &Location{Latitude: *lat, Longitude: *lng}
+	}
+	for _, v := range []string{takenAt, dateCreated} {
+		if t, ok := parseXMPDate(v); ok {
+			result.TakenAt = &t
+			break
+		}
+	}
+	return result, nil
+}
+
+// parseXMPCoordinate 解析 XMP 的 GPS 座標："DDD,MM,SSk" 或 "DDD,MM.mmk" (k 為方位)
+// 也接受部分工具寫入的十進位數值；pos / neg 為正負方位字元
+func parseXMPCoordinate(s string, pos, neg byte) (float64, bool) {
+	s = strings.TrimSpace(s)
+	if s == "" {
+		return 0, false
+	}
+	sign := 1.0
+	switch strings.ToUpper(s[len(s)-1:])[0] {
+	case pos:
+		s = s[:len(s)-1]
+	case neg:
+		sign = -1
+		s = s[:len(s)-1]
+	}
+
+	parts := strings.Split(s, ",")
+	if len(parts) > 3 {
+		return 0, false
+	}
+	var v float64
+	for i, part := range parts {
+		n, err := strconv.ParseFloat(strings.TrimSpace(part), 64)
+		if err != nil || (i > 0 && (n < 0 || n >= 60)) {
+			return 0, false
+		}
+		v += n / math.Pow(60, float64(i))
+	}
+	v *= sign
+
+	limit := 90.0
+	if pos == 'E' {
+		limit = 180
+	}
+	if math.IsNaN(v) || v < -limit || v > limit {
+		return 0, false
+	}
+	return v, true
+}
+
+// xmpDateLayouts XMP 日期格式 (ISO 8601 的子集，秒與時區可省略)
+var xmpDateLayouts = []string{
+	time.RFC3339Nano,
+	"2006-01-02T15:04Z07:00",
+	"2006-01-02T15:04:05.999999999",
+	"2006-01-02T15:04",
+	"2006-01-02",
+}
+
+// parseXMPDate 解析 XMP 日期；沒有時區時與 EXIF 相同視為伺服器時區
+func parseXMPDate(s string) (time.Time, bool) {
+	s = strings.TrimSpace(s)
+	if s == "" {
+		return time.Time{}, false
+	}
+	for _, layout := range xmpDateLayouts {
+		if t, err := time.ParseInLocation(layout, s, time.Local); err == nil {
+			if t.Before(earliestTakenAt) {
+				return time.Time{}, false
+			}
+			return t, true
+		}
+	}
+	return time.Time{}, false
+}
+
+// formatXMPCoordinate 輸出為 "DDD,MM.mmmmmmk"
+func formatXMPCoordinate(v float64, pos, neg byte) string {
+	dir := pos
+	if v < 0 {
+		dir, v = neg, -v
+	}
+	deg := math.Floor(v)
+	minutes := (v - deg) * 60
+	return fmt.Sprintf("%d,%.6f%c", int(deg), minutes, dir)
+}
+
+// buildXMPSidecar 產生媒體的 XMP sidecar，內容為 GoGallery 中的生效值 (含使用者覆寫)：
+// 評等、說明 (dc:description 與 dc:title)、標籤 (dc:subject)、拍攝時間與位置；沒有值的欄位不輸出
+func buildXMPSidecar(m *Media) []byte {
+	var b bytes.Buffer
+	esc := func(s string) string {
+		var e bytes.Buffer
+		xml.EscapeText(&e, []byte(s))
+		return e.String()
+	}
+
+	b.WriteString("<?xpacket begin=\"\uFEFF\" id=\"W5M0MpCehiHzreSzNTczkc9d\"?>\n")
+	b.WriteString(`<x:xmpmeta xmlns:x="adobe:ns:meta/" x:xmptk="GoGallery">` + "\n")
+	b.WriteString(` <rdf:RDF xmlns:rdf="` + nsRDF + `">` + "\n")
+	b.WriteString(`  <rdf:Description rdf:about=""` + "\n")
+	b.WriteString(`    xmlns:dc="` + nsDC + `"` + "\n")
+	b.WriteString(`    xmlns:xmp="` + nsXMP + `"` + "\n")
+	b.WriteString(`    xmlns:exif="` + nsEXIF + `">` + "\n")
+
+	if m.Rating != nil {
+		fmt.Fprintf(&b, "   <xmp:Rating>%d</xmp:Rating>\n", *m.Rating)
+	}
+	if m.TakenAt != nil {
+		fmt.Fprintf(&b, "   <exif:DateTimeOriginal>%s</exif:DateTimeOriginal>\n", m.TakenAt.In(time.Local).Format(time.RFC3339))
+	}
+	if m.Latitude != nil && m.Longitude != nil {
+		fmt.Fprintf(&b, "   <exif:GPSLatitude>%s</exif:GPSLatitude>\n", formatXMPCoordinate(*m.Latitude, 'N', 'S'))
+		fmt.Fprintf(&b, "   <exif:GPSLongitude>%s</exif:GPSLongitude>\n", formatXMPCoordinate(*m.Longitude, 'E', 'W'))
+	}
+	if m.Caption != "" {
+		// 說明同時寫入標題 (部分軟體只顯示 dc:title)；讀回時以 dc:description 為準
+		fmt.Fprintf(&b, "   <dc:title><rdf:Alt><rdf:li xml:lang=\"x-default\">%s</rdf:li></rdf:Alt></dc:title>\n", esc(m.Caption))
+		fmt.Fprintf(&b, "   <dc:description><rdf:Alt><rdf:li xml:lang=\"x-default\">%s</rdf:li></rdf:Alt></dc:description>\n", esc(m.Caption))
+	}
+	if len(m.Tags) > 0 {
+		b.WriteString("   <dc:subject><rdf:Bag>")
+		for _, tag := range m.Tags {
+			b.WriteString("<rdf:li>" + esc(tag) + "</rdf:li>")
+		}
+		b.WriteString("</rdf:Bag></dc:subject>\n")
+	}
+
+	b.WriteString("  </rdf:Description>\n")
+	b.WriteString(" </rdf:RDF>\n")
+	b.WriteString("</x:xmpmeta>\n")
+	b.WriteString(`<?xpacket end="w"?>` + "\n")
+	return b.Bytes()
+}
@@ -0,0 +1,444 @@
+package media
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/binary"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"hash"
+	"hash/crc32"
+	"io"
+	"os"
+	"sort"
+	"time"
+)
+
+// ZIP 格式常數 (APPNOTE 6.3)
+const (
+	zipLocalHeaderSig    = 0x04034b50
+	zipCentralHeaderSig  = 0x02014b50
+	zipDataDescriptorSig = 0x08074b50
+	zipEOCDSig           = 0x06054b50
+	zip64EOCDSig         = 0x06064b50
+	zip64LocatorSig      = 0x07064b50
+	zip64ExtraID         = 0x0001
+
+	zipVersion20 = 20
+	zipVersion45 = 45 // ZIP64
+
+	// 資料描述區 (大小於檔案之後才寫入) 與 UTF-8 檔名
+	zipFlags = 0x0008 | 0x0800
+
+	zipLocalHeaderLen   = 30
+	zipCentralHeaderLen = 46
+	zipEOCDLen          = 22
+	zip64EOCDLen        = 56
+	zip64LocatorLen     = 20
+	zip64ExtraLen       = 28
+
+	uint16max = 0xffff
+	uint32max = 0xffffffff
+)
+
+// zipEntry 壓縮檔中的一個檔案 (一律不壓縮：照片與影片本身已壓縮，且大小可事先得知)
+type zipEntry struct {
+	name     string
+	path     string // 磁碟上的檔案；data 不為 nil 時不使用
+	data     []byte // 記憶體中的內容 (manifest)
+	fileHash string
+	size     int64
+	modTime  time.Time
+	offset   int64 // local header 的位置
+
+	crc    uint32
+	hasCRC bool
+}
+
+func (e *zipEntry) zip64() bool {
+	return e.size >= uint32max
+}
+
+// centralZip64 中央目錄是否需要 ZIP64 extra (大小或位置超過 32 位元)
+func (e *zipEntry) centralZip64() bool {
+	return e.zip64() || e.offset >= uint32max
+}
+
+func (e *zipEntry) descriptorLen() int64 {
+	if e.zip64() {
+		return 24
+	}
+	return 16
+}
+
+type zipSegmentKind int
+
+const (
+	segBytes      zipSegmentKind = iota // 固定內容 (local header、manifest)
+	segFile                             // 磁碟檔案內容
+	segDescriptor                       // 資料描述區 (需要 CRC)
+	segTrailer                          // 中央目錄與結尾記錄 (需要所有 CRC)
+)
+
+type zipSegment struct {
+	start  int64
+	length int64
+	kind   zipSegmentKind
+	entry  *zipEntry
+	bytes  []byte
+}
+
+// ZipArchive 以串流方式產生的 ZIP 壓縮檔，不使用暫存檔
+//
+// 實作 io.ReadSeeker，可交給 http.ServeContent 處理 Range 與 If-Range：
+// 版面 (每個檔案的位置與總大小) 只由檔名與檔案大小決定，任意位置都能重新產生相同的位元組；
+// CRC32 在循序讀取檔案時順便計算並快取，續傳時若缺少先前檔案的 CRC 才另外讀取該檔案
+type ZipArchive struct {
+	Name string // 下載檔名
+	ETag string // 強 ETag，內容 (檔名、大小、Hash) 不變時保持相同
+
+	ctx      context.Context
+	svc      *Service // 讀寫 CRC 快取；nil 時不快取
+	entries  []*zipEntry
+	segments []zipSegment
+	size     int64
+	pos      int64
+	trailer  []byte
+
+	// 目前開啟的檔案與循序計算中的 CRC
+	file      *os.File
+	fileEntry *zipEntry
+	filePos   int64
+	crcHash   hash.Hash32
+}
+
+// newZipArchive 依序排列項目並計算版面
+func newZipArchive(ctx context.Context, svc *Service, name string, entries []*zipEntry) *ZipArchive {
+	a := &ZipArchive{Name: name, ctx: ctx, svc: svc, entries: entries}
+
+	tag := sha256.New()
+	var off int64
+	add := func(seg zipSegment) {
+		seg.start = off
+		a.segments = append(a.segments, seg)
+		off += seg.length
+	}
+	for _, e := range entries {
+		if e.data != nil {
+			e.size = int64(len(e.data))
+			e.crc, e.hasCRC = crc32.ChecksumIEEE(e.data), true
+		} else if e.size == 0 {
+			e.hasCRC = true
+		}
+		e.offset = off
+		header := localFileHeader(e)
+		add(zipSegment{kind: segBytes, length: int64(len(header)), bytes: header})
+		switch {
+		case e.data != nil && e.size > 0:
+			add(zipSegment{kind: segBytes, length: e.size, bytes: e.data})
+		case e.size > 0:
+			add(zipSegment{kind: segFile, length: e.size, entry: e})
+		}
+		add(zipSegment{kind: segDescriptor, length: e.descriptorLen(), entry: e})
+
+		fmt.Fprintf(tag, "%s\x00%s\x00%d\x00%d\n", e.name, e.fileHash, e.size, e.modTime.Unix())
+		if e.data != nil {
+			tag.Write(e.data)
+		}
+	}
+	add(zipSegment{kind: segTrailer, length: a.trailerLen(off)})
+
+	a.size = off
+	a.ETag = `"zip-` + hex.EncodeToString(tag.Sum(nil)[:16]) + `"`
+	return a
+}
+
+// Size 壓縮檔的總大小
+func (a *ZipArchive) Size() int64 {
+	return a.size
+}
+
+// Seek 實作 io.Seeker
+func (a *ZipArchive) Seek(offset int64, whence int) (int64, error) {
+	switch whence {
+	case io.SeekStart:
+	case io.SeekCurrent:
+		offset += a.pos
+	case io.SeekEnd:
+		offset += a.size
+	default:
+		return 0, errors.New("zip archive: invalid whence")
+	}
+	if offset < 0 {
+		return 0, errors.New("zip archive: negative position")
+	}
+	a.pos = offset
+	return offset, nil
+}
+
+// Read 實作 io.Reader
+func (a *ZipArchive) Read(p []byte) (int, error) {
+	if a.pos >= a.size {
+		return 0, io.EOF
+	}
+	if err := a.ctx.Err(); err != nil {
+		return 0, err
+	}
+	i := sort.Search(len(a.segments), func(i int) bool {
+		return a.segments[i].start+a.segments[i].length > a.pos
+	})
+	seg := a.segments[i]
+	off := a.pos - seg.start
+	if remain := seg.length - off; int64(len(p)) > remain {
+		p = p[:remain]
+	}
+
+	var n int
+	var err error
+	switch seg.kind {
+	case segBytes:
+		n = copy(p, seg.bytes[off:])
+	case segFile:
+		n, err = a.readFile(seg.entry, off, p)
+	case segDescriptor:
+		var b []byte
+		if b, err = a.descriptor(seg.entry); err == nil {
+			n = copy(p, b[off:])
+		}
+	case segTrailer:
+		var b []byte
+		if b, err = a.buildTrailer(seg.start); err == nil {
+			n = copy(p, b[off:])
+		}
+	}
+	a.pos += int64(n)
+	return n, err
+}
+
+// Close 關閉目前開啟的檔案
+func (a *ZipArchive) Close() error {
+	if a.file == nil {
+		return nil
+	}
+	err := a.file.Close()
+	a.file, a.fileEntry = nil, nil
+	return err
+}
+
+// readFile 讀取檔案內容；從檔案開頭循序讀到結尾時順便計算 CRC
+func (a *ZipArchive) readFile(e *zipEntry, off int64, p []byte) (int, error) {
+	if a.fileEntry != e || a.filePos != off {
+		if a.fileEntry != e {
+			a.Close()
+			f, err := os.Open(e.path)
+			if err != nil {
+				return 0, fmt.Errorf("failed to open %s: %w", e.name, err)
+			}
+			a.file, a.fileEntry = f, e
+		}
+		if _, err := a.file.Seek(off, io.SeekStart); err != nil {
+			return 0, err
+		}
+		a.filePos = off
+		a.crcHash = nil
+		if off == 0 && !e.hasCRC {
+			a.crcHash = crc32.NewIEEE()
+		}
+	}
+
+	n, err := io.ReadFull(a.file, p)
+	if err == io.EOF || err == io.ErrUnexpectedEOF {
+		err = fmt.Errorf("%s changed during export: %w", e.name, io.ErrUnexpectedEOF)
+	}
+	if a.crcHash != nil {
+		a.crcHash.Write(p[:n])
+	}
+	a.filePos += int64(n)
+	if a.crcHash != nil && a.filePos == e.size {
+		a.setCRC(e, a.crcHash.Sum32())
+		a.crcHash = nil
+	}
+	return n, err
+}
+
+// entryCRC 取得 CRC；尚未計算時讀取整個檔案
+func (a *ZipArchive) entryCRC(e *zipEntry) (uint32, error) {
+	if e.hasCRC {
+		return e.crc, nil
+	}
+	f, err := os.Open(e.path)
+	if err != nil {
+		return 0, fmt.Errorf("failed to open %s: %w", e.name, err)
+	}
+	defer f.Close()
+	h := crc32.NewIEEE()
+	if n, err := io.Copy(h, f); err != nil {
+		return 0, fmt.Errorf("failed to read %s: %w", e.name, err)
+	} else if n != e.size {
+		return 0, fmt.Errorf("%s changed during export: %w", e.name, io.ErrUnexpectedEOF)
+	}
+	a.setCRC(e, h.Sum32())
+	return e.crc, nil
+}
+
+func (a *ZipArchive) setCRC(e *zipEntry, crc uint32) {
+	e.crc, e.hasCRC = crc, true
+	if a.svc != nil && e.fileHash != "" {
+		a.svc.storeFileCRC(a.ctx, e.fileHash, crc)
+	}
+}
+
+// localFileHeader CRC 與大小寫在資料描述區，此處為 0
+func localFileHeader(e *zipEntry) []byte {
+	date, tm := dosDateTime(e.modTime)
+	b := make([]byte, 0, zipLocalHeaderLen+len(e.name))
+	b = binary.LittleEndian.AppendUint32(b, zipLocalHeaderSig)
+	b = binary.LittleEndian.AppendUint16(b, zipVersion20)
+	b = binary.LittleEndian.AppendUint16(b, zipFlags)
+	b = binary.LittleEndian.AppendUint16(b, 0) // 不壓縮 (store)
+	b = binary.LittleEndian.AppendUint16(b, tm)
+	b = binary.LittleEndian.AppendUint16(b, date)
+	b = binary.LittleEndian.AppendUint32(b, 0) // CRC
+	b = binary.LittleEndian.AppendUint32(b, 0) // 壓縮後大小
+	b = binary.LittleEndian.AppendUint32(b, 0) // 原始大小
+	b = binary.LittleEndian.AppendUint16(b, uint16(len(e.name)))
+	b = binary.LittleEndian.AppendUint16(b, 0) // extra
+	return append(b, e.name...)
+}
+
+func (a *ZipArchive) descriptor(e *zipEntry) ([]byte, error) {
+	crc, err := a.entryCRC(e)
+	if err != nil {
+		return nil, err
+	}
+	b := make([]byte, 0, e.descriptorLen())
+	b = binary.LittleEndian.AppendUint32(b, zipDataDescriptorSig)
+	b = binary.LittleEndian.AppendUint32(b, crc)
+	if e.zip64() {
+		b = binary.LittleEndian.AppendUint64(b, uint64(e.size))
+		b = binary.LittleEndian.AppendUint64(b, uint64(e.size))
+	} else {
+		b = binary.LittleEndian.AppendUint32(b, uint32(e.size))
+		b = binary.LittleEndian.AppendUint32(b, uint32(e.size))
+	}
+	return b, nil
+}
+
+// trailerLen 中央目錄與結尾記錄的長度 (cdOffset 為中央目錄的位置)
+func (a *ZipArchive) trailerLen(cdOffset int64) int64 {
+	var cdSize int64
+	for _, e := range a.entries {
+		cdSize += zipCentralHeaderLen + int64(len(e.name))
+		if e.centralZip64() {
+			cdSize += zip64ExtraLen
+		}
+	}
+	if needZip64EOCD(len(a.entries), cdSize, cdOffset) {
+		return cdSize + zip64EOCDLen + zip64LocatorLen + zipEOCDLen
+	}
+	return cdSize + zipEOCDLen
+}
+
+func needZip64EOCD(records int, cdSize, cdOffset int64) bool {
+	return records >= uint16max || cdSize >= uint32max || cdOffset >= uint32max
+}
+
+// buildTrailer 產生中央目錄與結尾記錄 (需要所有檔案的 CRC)
+func (a *ZipArchive) buildTrailer(cdOffset int64) ([]byte, error) {
+	if a.trailer != nil {
+		return a.trailer, nil
+	}
+	var b []byte
+	for _, e := range a.entries {
+		crc, err := a.entryCRC(e)
+		if err != nil {
+			return nil, err
+		}
+		date, tm := dosDateTime(e.modTime)
+		version := uint16(zipVersion20)
+		if e.centralZip64() {
+			version = zipVersion45
+		}
+		b = binary.LittleEndian.AppendUint32(b, zipCentralHeaderSig)
+		b = binary.LittleEndian.AppendUint16(b, zipVersion45) // version made by
+		b = binary.LittleEndian.AppendUint16(b, version)
+		b = binary.LittleEndian.AppendUint16(b, zipFlags)
+		b = binary.LittleEndian.AppendUint16(b, 0)
+		b = binary.LittleEndian.AppendUint16(b, tm)
+		b = binary.LittleEndian.AppendUint16(b, date)
+		b = binary.LittleEndian.AppendUint32(b, crc)
+		if e.centralZip64() {
+			b = binary.LittleEndian.AppendUint32(b, uint32max)
+			b = binary.LittleEndian.AppendUint32(b, uint32max)
+		} else {
+			b = binary.LittleEndian.AppendUint32(b, uint32(e.size))
+			b = binary.LittleEndian.AppendUint32(b, uint32(e.size))
+		}
+		b = binary.LittleEndian.AppendUint16(b, uint16(len(e.name)))
+		if e.centralZip64() {
+			b = binary.LittleEndian.AppendUint16(b, zip64ExtraLen)
+		} else {
+			b = binary.LittleEndian.AppendUint16(b, 0)
+		}
+		b = binary.LittleEndian.AppendUint16(b, 0) // comment
+		b = binary.LittleEndian.AppendUint16(b, 0) // disk number start
+		b = binary.LittleEndian.AppendUint16(b, 0) // internal attributes
+		b = binary.LittleEndian.AppendUint32(b, 0) // external attributes
+		b = binary.LittleEndian.AppendUint32(b, uint32(min(e.offset, uint32max)))
+		b = append(b, e.name...)
+		if e.centralZip64() {
+			b = binary.LittleEndian.AppendUint16(b, zip64ExtraID)
+			b = binary.LittleEndian.AppendUint16(b, zip64ExtraLen-4)
+			b = binary.LittleEndian.AppendUint64(b, uint64(e.size))
+			b = binary.LittleEndian.AppendUint64(b, uint64(e.size))
+			b = binary.LittleEndian.AppendUint64(b, uint64(e.offset))
+		}
+	}
+
+	cdSize := int64(len(b))
+	records := len(a.entries)
+	if needZip64EOCD(records, cdSize, cdOffset) {
+		eocd64Offset := cdOffset + cdSize
+		b = binary.LittleEndian.AppendUint32(b, zip64EOCDSig)
+		b = binary.LittleEndian.AppendUint64(b, zip64EOCDLen-12)
+		b = binary.LittleEndian.AppendUint16(b, zipVersion45)
+		b = binary.LittleEndian.AppendUint16(b, zipVersion45)
+		b = binary.LittleEndian.AppendUint32(b, 0)
+		b = binary.LittleEndian.AppendUint32(b, 0)
+		b = binary.LittleEndian.AppendUint64(b, uint64(records))
+		b = binary.LittleEndian.AppendUint64(b, uint64(records))
+		b = binary.LittleEndian.AppendUint64(b, uint64(cdSize))
+		b = binary.LittleEndian.AppendUint64(b, uint64(cdOffset))
+
+		b = binary.LittleEndian.AppendUint32(b, zip64LocatorSig)
+		b = binary.LittleEndian.AppendUint32(b, 0)
+		b = binary.LittleEndian.AppendUint64(b, uint64(eocd64Offset))
+		b = binary.LittleEndian.AppendUint32(b, 1)
+	}
+	b = binary.LittleEndian.AppendUint32(b, zipEOCDSig)
+	b = binary.LittleEndian.AppendUint16(b, 0)
+	b = binary.LittleEndian.AppendUint16(b, 0)
+	b = binary.LittleEndian.AppendUint16(b, uint16(min(records, uint16max)))
+	b = binary.LittleEndian.AppendUint16(b, uint16(min(records, uint16max)))
+	b = binary.LittleEndian.AppendUint32(b, uint32(min(cdSize, uint32max)))
+	b = binary.LittleEndian.AppendUint32(b, uint32(min(cdOffset, uint32max)))
+	b = binary.LittleEndian.AppendUint16(b, 0)
+
+	if want := a.size - cdOffset; int64(len(b)) != want {
+		return nil, fmt.Errorf("zip archive: trailer is %d bytes, expected %d", len(b), want)
+	}
+	a.trailer = b
+	return b, nil
+}
+
+// dosDateTime 轉換為 MS-DOS 日期時間 (UTC，1980 年以前以 1980-01-01 表示)
+func dosDateTime(t time.Time) (date, tm uint16) {
+	t = t.UTC()
+	if t.Year() < 1980 {
+		t = time.Date(1980, 1, 1, 0, 0, 0, 0, time.UTC)
+	}
+	date = uint16((t.Year()-1980)<<9 | int(t.Month())<<5 | t.Day())
+	tm = uint16(t.Hour()<<11 | t.Minute()<<5 | t.Second()/2)
+	return date, tm
+}
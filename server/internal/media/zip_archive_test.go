@@ -0,0 +1,131 @@
+package media
+
+import (
+	"archive/zip"
+	"bytes"
+	"context"
+	"io"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+)
+
+func writeZipTestEntries(t *testing.T, files map[string]string, order []string) []*zipEntry {
+	t.Helper()
+	dir := t.TempDir()
+	var entries []*zipEntry
+	for _, name := range order {
+		p := filepath.Join(dir, name)
+		if err := os.WriteFile(p, []byte(files[name]), 0o644); err != nil {
+			t.Fatalf("failed to write %s: %v", name, err)
+		}
+		entries = append(entries, &zipEntry{
+			name:    name,
+			path:    p,
+			size:    int64(len(files[name])),
+			modTime: time.Date(2024, 5, 1, 12, 30, 0, 0, time.UTC),
+		})
+	}
+	return entries
+}
+
+func TestZipArchiveReadable(t *testing.T) {
+	files := map[string]string{"a.jpg": "first image", "b.mov": string(bytes.Repeat([]byte("video"), 10000)), "empty.txt": ""}
+	entries := writeZipTestEntries(t, files, []string{"a.jpg", "b.mov", "empty.txt"})
+	entries = append([]*zipEntry{{name: zipManifestName, data: []byte(`[]`)}}, entries...)
+
+	a := newZipArchive(context.Background(), nil, "test", entries)
+	data, err := io.ReadAll(a)
+	if err != nil {
+		t.Fatalf("failed to read archive: %v", err)
+	}
+	if int64(len(data)) != a.Size() {
+		t.Fatalf("expected %d bytes, got %d", a.Size(), len(data))
+	}
+
+	zr, err := zip.NewReader(bytes.NewReader(data), int64(len(data)))
+	if err != nil {
+		t.Fatalf("archive is not a valid zip: %v", err)
+	}
+	if len(zr.File) != 4 || zr.File[0].Name != zipManifestName {
+		t.Fatalf("unexpected entries: %d", len(zr.File))
+	}
+	for _, f := range zr.File[1:] {
+		rc, err := f.Open()
+		if err != nil {
+			t.Fatalf("failed to open %s: %v", f.Name, err)
+		}
+		got, err := io.ReadAll(rc) // 讀到結尾時會驗證 CRC
+		rc.Close()
+		if err != nil {
+			t.Fatalf("failed to read %s: %v", f.Name, err)
+		}
+		if string(got) != files[f.Name] {
+			t.Errorf("content mismatch for %s", f.Name)
+		}
+		if !f.Modified.Equal(time.Date(2024, 5, 1, 12, 30, 0, 0, time.UTC)) {
+			t.Errorf("unexpected modified time for %s: %v", f.Name, f.Modified)
+		}
+	}
+}
+
+func TestZipArchiveResumeMatchesFullDownload(t *testing.T) {
+	files := map[string]string{"a.jpg": string(bytes.Repeat([]byte("a"), 5000)), "b.jpg": string(bytes.Repeat([]byte("b"), 7000))}
+	order := []string{"a.jpg", "b.jpg"}
+
+	full, err := io.ReadAll(newZipArchive(context.Background(), nil, "test", writeZipTestEntries(t, files, order)))
+	if err != nil {
+		t.Fatalf("failed to read archive: %v", err)
+	}
+
+	// 新的請求從第二個檔案中間續傳：第一個檔案的 CRC 需另外計算
+	resumed := newZipArchive(context.Background(), nil, "test", writeZipTestEntries(t, files, order))
+	offset := int64(len(full)) - 3000
+	if _, err := resumed.Seek(offset, io.SeekStart); err != nil {
+		t.Fatalf("seek failed: %v", err)
+	}
+	tail, err := io.ReadAll(resumed)
+	if err != nil {
+		t.Fatalf("failed to read resumed archive: %v", err)
+	}
+	if !bytes.Equal(tail, full[offset:]) {
+		t.Error("resumed bytes differ from full download")
+	}
+}
+
+func TestUniqueZipName(t *testing.T) {
+	used := map[string]bool{zipManifestName: true}
+	got := []string{
+		uniqueZipName("IMG_0001.JPG", used),
+		uniqueZipName("img_0001.jpg", used),
+		uniqueZipName("IMG_0001.JPG", used),
+		uniqueZipName("manifest.json", used),
+	}
+	want := []string{"IMG_0001.JPG", "img_0001 (1).jpg", "IMG_0001 (2).JPG", "manifest (1).json"}
+	for i := range want {
+		if got[i] != want[i] {
+			t.Errorf("expected %q, got %q", want[i], got[i])
+		}
+	}
+	if name := sanitizeZipName(&Media{ID: "id-1", OriginalFilename: "../evil\x00.jpg", StoragePath: "x.jpg"}); name != ".._evil.jpg" {
+		t.Errorf("unexpected sanitized name %q", name)
+	}
+}
+
+func TestPurgeExpiredZipExports(t *testing.T) {
+	db, mock := newMockDB(t)
+	s := &Service{DB: db}
+
+	mock.ExpectExec(`DELETE FROM zip_exports WHERE created_at <= \$1`).
+		WithArgs(sqlmock.AnyArg()).
+		WillReturnResult(sqlmock.NewResult(0, 3))
+	if err := s.PurgeExpiredZipExports(context.Background()); err != nil {
+		t.Fatalf("PurgeExpiredZipExports failed: %v", err)
+	}
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("unmet expectations: %v", err)
+	}
+}
@@ -0,0 +1,337 @@
+package media
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+	"time"
+	"unicode"
+)
+
+var (
+	ErrExportNotFound       = errors.New("export not found")
+	ErrShareDownloadBlocked = errors.New("downloading originals is not allowed for this link")
+)
+
+// ZipExportTTL 多選匯出記錄的保留時間 (期間內可重複下載與續傳)
+const ZipExportTTL = 7 * 24 * time.Hour
+
+// zipManifestName manifest 在壓縮檔中的檔名 (其他檔案遇到同名時會改名)
+const zipManifestName = "manifest.json"
+
+// ZipExport 多選匯出記錄
+type ZipExport struct {
+	ID         string    `json:"id"`
+	MediaCount int       `json:"media_count"`
+	ExpiresAt  time.Time `json:"expires_at"`
+}
+
+// ZipExportOptions 匯出時附加的內容
+type ZipExportOptions struct {
+	Manifest bool // 附上 manifest.json
+	XMP      bool // 每個檔案旁附上 XMP sidecar (原始檔名.xmp)，供 Lightroom / darktable 讀取評等、說明與標籤
+}
+
+// zipManifestItem manifest 中的一筆：壓縮檔內的檔名與媒體的中繼資料
+type zipManifestItem struct {
+	File     string `json:"file"`
+	Metadata any    `json:"metadata"`
+}
+
+// sanitizeZipName 將原始檔名轉為安全的壓縮檔內檔名 (移除路徑與控制字元)
+// 沒有可用檔名時以媒體 ID 加上儲存檔案的副檔名
+func sanitizeZipName(m *Media) string {
+	name := strings.Map(func(r rune) rune {
+		switch {
+		case r == '/' || r == '\\':
+			return '_'
+		case unicode.IsControl(r):
+			return -1
+		}
+		return r
+	}, m.OriginalFilename)
+	name = strings.TrimSpace(name)
+	if name == "" || name == "." || name == ".." {
+		name = m.ID + path.Ext(m.StoragePath)
+	}
+	return name
+}
+
+// uniqueZipName 檔名重複時 (不分大小寫，避免在 Windows/macOS 解壓縮時互相覆蓋) 加上 " (n)"
+func uniqueZipName(name string, used map[string]bool) string {
+	candidate := name
+	ext := path.Ext(name)
+	base := strings.TrimSuffix(name, ext)
+	for n := 1; used[strings.ToLower(candidate)]; n++ {
+		candidate = fmt.Sprintf("%s (%d)%s", base, n, ext)
+	}
+	used[strings.ToLower(candidate)] = true
+	return candidate
+}
+
+// buildZipArchive 依序將媒體放入壓縮檔；磁碟上找不到的檔案略過
+// metadata 回傳寫入 manifest 的內容，為 nil 時不產生 manifest；withXMP 時每個檔案後接著其 XMP sidecar
+func (s *Service) buildZipArchive(ctx context.Context, name string, list []*Media, metadata func(*Media) any, withXMP bool) (*ZipArchive, error) {
+	used := map[string]bool{}
+	if metadata != nil {
+		used[zipManifestName] = true
+	}
+
+	var entries []*zipEntry
+	var manifest []zipManifestItem
+	var hashes []string
+	for _, m := range list {
+		p := filepath.Join(s.UploadDir, m.StoragePath)
+		info, err := os.Stat(p)
+		if err != nil {
+			fmt.Printf("skipping %s in zip export: %v\n", m.ID, err)
+			continue
+		}
+		modTime := m.UploadedAt
+		if m.TakenAt != nil {
+			modTime = *m.TakenAt
+		}
+		e := &zipEntry{
+			name:     uniqueZipName(sanitizeZipName(m), used),
+			path:     p,
+			fileHash: m.FileHash,
+			size:     info.Size(),
+			modTime:  modTime,
+		}
+		entries = append(entries, e)
+		hashes = append(hashes, m.FileHash)
+		if withXMP {
+			entries = append(entries, &zipEntry{name: uniqueZipName(e.name+xmpSidecarExt, used), data: buildXMPSidecar(m), modTime: modTime})
+		}
+		if metadata != nil {
+			manifest = append(manifest, zipManifestItem{File: e.name, Metadata: metadata(m)})
+		}
+	}
+
+	if metadata != nil {
+		if manifest == nil {
+			manifest = []zipManifestItem{}
+		}
+		data, err := json.MarshalIndent(manifest, "", "  ")
+		if err != nil {
+			return nil, fmt.Errorf("failed to encode manifest: %w", err)
+		}
+		entries = append([]*zipEntry{{name: zipManifestName, data: data, modTime: stableManifestTime(list)}}, entries...)
+	}
+
+	crcs, err := s.loadFileCRCs(ctx, hashes)
+	if err != nil {
+		return nil, err
+	}
+	for _, e := range entries {
+		if crc, ok := crcs[e.fileHash]; ok && e.data == nil {
+			e.crc, e.hasCRC = crc, true
+		}
+	}
+	return newZipArchive(ctx, s, name, entries), nil
+}
+
+// stableManifestTime manifest 的修改時間不可隨請求改變，否則續傳時內容不一致；以最新上傳的媒體為準
+func stableManifestTime(list []*Media) time.Time {
+	var t time.Time
+	for _, m := range list {
+		if m.UploadedAt.After(t) {
+			t = m.UploadedAt
+		}
+	}
+	return t
+}
+
+// loadFileCRCs 讀取已快取的 CRC32
+func (s *Service) loadFileCRCs(ctx context.Context, hashes []string) (map[string]uint32, error) {
+	crcs := map[string]uint32{}
+	if len(hashes) == 0 {
+		return crcs, nil
+	}
+	rows, err := s.DB.QueryContext(ctx, `SELECT file_hash, crc32 FROM file_checksums WHERE file_hash = ANY($1::text[])`, hashes)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query checksums: %w", err)
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var hash string
+		var crc int64
+		if err := rows.Scan(&hash, &crc); err != nil {
+			return nil, fmt.Errorf("failed to scan checksum: %w", err)
+		}
+		crcs[hash] = uint32(crc)
+	}
+	return crcs, rows.Err()
+}
+
+// storeFileCRC 快取 CRC32 (失敗不影響下載)
+func (s *Service) storeFileCRC(ctx context.Context, fileHash string, crc uint32) {
+	_, err := s.DB.ExecContext(ctx, `
+		INSERT INTO file_checksums (file_hash, crc32) VALUES ($1, $2)
+		ON CONFLICT (file_hash) DO NOTHING
+	`, fileHash, int64(crc))
+	if err != nil {
+		fmt.Printf("failed to store checksum for %s: %v\n", fileHash, err)
+	}
+}
+
+// queryExportMedia 讀取匯出項目 (query 需回傳 mediaColumns 與 m.storage_path)，重複的項目只保留第一個
+func (s *Service) queryExportMedia(ctx context.Context, query string, args ...any) ([]*Media, error) {
+	rows, err := s.DB.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query export media: %w", err)
+	}
+	defer rows.Close()
+
+	list := []*Media{}
+	seen := map[string]bool{}
+	for rows.Next() {
+		m := &Media{}
+		if err := scanMedia(rows, m, &m.StoragePath); err != nil {
+			return nil, fmt.Errorf("failed to scan media: %w", err)
+		}
+		if !seen[m.ID] {
+			seen[m.ID] = true
+			list = append(list, m)
+		}
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to iterate export media: %w", err)
+	}
+	return list, nil
+}
+
+// mediaMetadata manifest 使用與 API 相同的媒體欄位
+func mediaMetadata(m *Media) any {
+	return m
+}
+
+// AlbumArchive 匯出相簿 (擁有者與成員皆可，依手動排序，不含垃圾桶)
+func (s *Service) AlbumArchive(ctx context.Context, userID, albumID string, opts ZipExportOptions) (*ZipArchive, error) {
+	album, err := s.GetAlbum(ctx, userID, albumID)
+	if err != nil {
+		return nil, err
+	}
+
+	query := `
+		SELECT ` + mediaColumns + `, m.storage_path
+		FROM album_media am
+		JOIN media m ON m.id = am.media_id
+		WHERE am.album_id = $1 AND m.deleted_at IS NULL
+		ORDER BY am.position, am.added_at, m.id
+	`
+	list, err := s.queryExportMedia(ctx, query, albumID)
+	if err != nil {
+		return nil, err
+	}
+	var metadata func(*Media) any
+	if opts.Manifest {
+		metadata = mediaMetadata
+	}
+	return s.buildZipArchive(ctx, album.Title, list, metadata, opts.XMP)
+}
+
+// CreateZipExport 記錄多選匯出 (之後以 SelectionArchive 下載)
+func (s *Service) CreateZipExport(ctx context.Context, userID string, mediaIDs []string, opts ZipExportOptions) (*ZipExport, error) {
+	e := &ZipExport{MediaCount: len(mediaIDs)}
+	var createdAt time.Time
+	err := s.DB.QueryRowContext(ctx, `
+		INSERT INTO zip_exports (user_id, media_ids, include_manifest, include_xmp) VALUES ($1, $2::uuid[], $3, $4)
+		RETURNING id, created_at
+	`, userID, mediaIDs, opts.Manifest, opts.XMP).Scan(&e.ID, &createdAt)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create export: %w", err)
+	}
+	e.ExpiresAt = createdAt.Add(ZipExportTTL)
+	return e, nil
+}
+
+// PurgeExpiredZipExports 刪除超過 ZipExportTTL 的多選匯出記錄 (已無法下載)
+func (s *Service) PurgeExpiredZipExports(ctx context.Context) error {
+	if _, err := s.DB.ExecContext(ctx, `DELETE FROM zip_exports WHERE created_at <= $1`, time.Now().Add(-ZipExportTTL)); err != nil {
+		return fmt.Errorf("failed to delete zip exports: %w", err)
+	}
+	return nil
+}
+
+// SelectionArchive 下載多選匯出；只包含目前仍可讀取的項目 (自己的、共享相簿與伴侶分享)
+func (s *Service) SelectionArchive(ctx context.Context, userID, exportID string) (*ZipArchive, error) {
+	var opts ZipExportOptions
+	var createdAt time.Time
+	err := s.DB.QueryRowContext(ctx, `
+		SELECT include_manifest, include_xmp, created_at FROM zip_exports
+		WHERE id = $1 AND user_id = $2 AND created_at > $3
+	`, exportID, userID, time.Now().Add(-ZipExportTTL)).Scan(&opts.Manifest, &opts.XMP, &createdAt)
+	if err == sql.ErrNoRows {
+		return nil, ErrExportNotFound
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to query export: %w", err)
+	}
+
+	query := `
+		SELECT ` + mediaColumns + `, m.storage_path
+		FROM zip_exports x
+		CROSS JOIN LATERAL unnest(x.media_ids) WITH ORDINALITY AS v(id, ord)
+		JOIN media m ON m.id = v.id
+		WHERE x.id = $1 AND m.deleted_at IS NULL
+		  AND (m.user_id = $2 OR ` + mediaSharedWith("$2") + ` OR ` + partnerVisible("$2", false) + `)
+		ORDER BY v.ord
+	`
+	list, err := s.queryExportMedia(ctx, query, exportID, userID)
+	if err != nil {
+		return nil, err
+	}
+	var metadata func(*Media) any
+	if opts.Manifest {
+		metadata = mediaMetadata
+	}
+	name := "GoGallery " + createdAt.UTC().Format("2006-01-02 150405")
+	return s.buildZipArchive(ctx, name, list, metadata, opts.XMP)
+}
+
+// SharedArchive 公開分享連結的整包下載 (需允許下載原始檔)；manifest 只包含分享頁面可見的欄位
+func (s *Service) SharedArchive(ctx context.Context, token string, withManifest bool) (*ZipArchive, error) {
+	shareID, allowDownload, err := s.resolveShareToken(ctx, token)
+	if err != nil {
+		return nil, err
+	}
+	if !allowDownload {
+		return nil, ErrShareDownloadBlocked
+	}
+
+	var title string
+	err = s.DB.QueryRowContext(ctx, `
+		SELECT COALESCE(NULLIF(sl.title, ''), (SELECT a.title FROM albums a WHERE a.id = sl.album_id), '')
+		FROM share_links sl WHERE sl.id = $1
+	`, shareID).Scan(&title)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query share link: %w", err)
+	}
+	if title == "" {
+		title = "GoGallery"
+	}
+
+	query := `
+		SELECT ` + mediaColumns + `, m.storage_path
+		FROM share_links sl
+		CROSS JOIN LATERAL ` + shareMembers + `
+		WHERE sl.id = $1 AND m.deleted_at IS NULL
+		ORDER BY sm.position, sm.added_at, m.id
+	`
+	list, err := s.queryExportMedia(ctx, query, shareID)
+	if err != nil {
+		return nil, err
+	}
+	var metadata func(*Media) any
+	if withManifest {
+		metadata = func(m *Media) any { return newSharedMedia(m) }
+	}
+	return s.buildZipArchive(ctx, title, list, metadata, false)
+}
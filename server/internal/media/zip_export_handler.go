@@ -0,0 +1,114 @@
+package media
+
+import (
+	"errors"
+	"mime"
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+type zipExportRequest struct {
+	MediaIDs        []string `json:"media_ids"`
+	IncludeManifest bool     `json:"include_manifest"`
+	IncludeXMP      bool     `json:"include_xmp"`
+}
+
+// serveZipArchive 以 http.ServeContent 回應壓縮檔 (支援 Range、If-Range 與 HEAD)
+func serveZipArchive(c *gin.Context, a *ZipArchive) {
+	defer a.Close()
+
+	c.Header("Content-Type", "application/zip")
+	c.Header("Content-Disposition", mime.FormatMediaType("attachment", map[string]string{"filename": a.Name + ".zip"}))
+	c.Header("Cache-Control", listCacheControl)
+	c.Header("ETag", a.ETag)
+	http.ServeContent(c.Writer, c.Request, "", time.Time{}, a)
+}
+
+// ExportAlbumHandler 將相簿下載為 ZIP (GET /albums/:id/export?manifest=true&xmp=true)
+// 原始檔不經壓縮直接串流，檔名重複時自動加上編號；manifest=true 時附上 manifest.json，xmp=true 時附上 XMP sidecar
+func (h *Handler) ExportAlbumHandler(c *gin.Context) {
+	userID := c.MustGet("userID").(string)
+	albumID, ok := albumParam(c)
+	if !ok {
+		return
+	}
+
+	opts := ZipExportOptions{Manifest: c.Query("manifest") == "true", XMP: c.Query("xmp") == "true"}
+	archive, err := h.Service.AlbumArchive(c.Request.Context(), userID, albumID, opts)
+	if err != nil {
+		respondAlbumError(c, err)
+		return
+	}
+
+	serveZipArchive(c, archive)
+}
+
+// CreateZipExportHandler 建立多選匯出 (POST /exports)
+// body: {"media_ids": [...], "include_manifest": true, "include_xmp": true}；回傳的 id 以 GET /exports/:id 下載
+func (h *Handler) CreateZipExportHandler(c *gin.Context) {
+	userID := c.MustGet("userID").(string)
+
+	var req zipExportRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid request body"})
+		return
+	}
+	if err := validateIDs(req.MediaIDs); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	opts := ZipExportOptions{Manifest: req.IncludeManifest, XMP: req.IncludeXMP}
+	export, err := h.Service.CreateZipExport(c.Request.Context(), userID, req.MediaIDs, opts)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusCreated, export)
+}
+
+// DownloadZipExportHandler 下載多選匯出 (GET /exports/:id)，中斷後可以 Range 續傳
+func (h *Handler) DownloadZipExportHandler(c *gin.Context) {
+	userID := c.MustGet("userID").(string)
+	exportID := c.Param("id")
+	if !uuidPattern.MatchString(exportID) {
+		c.JSON(http.StatusNotFound, gin.H{"error": ErrExportNotFound.Error()})
+		return
+	}
+
+	archive, err := h.Service.SelectionArchive(c.Request.Context(), userID, exportID)
+	if err != nil {
+		if errors.Is(err, ErrExportNotFound) {
+			c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	serveZipArchive(c, archive)
+}
+
+// ExportSharedHandler 公開分享連結的整包下載 (GET /s/:token/export?manifest=true)
+// 屬於公開路由；連結需允許下載原始檔
+func (h *Handler) ExportSharedHandler(c *gin.Context) {
+	token, ok := shareTokenParam(c)
+	if !ok {
+		return
+	}
+
+	archive, err := h.Service.SharedArchive(c.Request.Context(), token, c.Query("manifest") == "true")
+	if err != nil {
+		if errors.Is(err, ErrShareDownloadBlocked) {
+			c.JSON(http.StatusForbidden, gin.H{"error": err.Error()})
+			return
+		}
+		respondShareError(c, err)
+		return
+	}
+
+	serveZipArchive(c, archive)
+}
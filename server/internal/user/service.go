@@ -3,8 +3,13 @@ package user
 import (
 	"context"
 	"database/sql"
+	"errors"
+	"fmt"
 )
 
+// ErrUserNotFound 找不到指定的使用者
+var ErrUserNotFound = errors.New("user not found")
+
 // GoogleAuthenticator 定義 Google 驗證介面 (Dependency Inversion)
 type GoogleAuthenticator interface {
 	FetchJWKs() ([]map[string]interface{}, error)
@@ -55,3 +60,17 @@ func (s *Service) UpsertByGoogle(ctx context.Context, token string) (string, str
 
 	return sub, email, name, picture, nil
 }
+
+// LookupID 以使用者 ID 或 email (不分大小寫) 查詢使用者 ID，供伺服器端管理指令使用
+func (s *Service) LookupID(ctx context.Context, idOrEmail string) (string, error) {
+	var id string
+	query := `SELECT id FROM users WHERE id::text = $1 OR LOWER(email) = LOWER($1) ORDER BY created_at LIMIT 1`
+	err := s.DB.QueryRowContext(ctx, query, idOrEmail).Scan(&id)
+	if err == sql.ErrNoRows {
+		return "", ErrUserNotFound
+	}
+	if err != nil {
+		return "", fmt.Errorf("failed to query user: %w", err)
+	}
+	return id, nil
+}
@@ -71,3 +71,32 @@ func TestUpsertByGoogle(t *testing.T) {
 		t.Errorf("there were unfulfilled expectations: %s", err)
 	}
 }
+
+func TestLookupID(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("an error '%s' was not expected when opening a stub database connection", err)
+	}
+	defer db.Close()
+
+	s := &Service{DB: db}
+
+	mock.ExpectQuery("SELECT id FROM users").
+		WithArgs("Test@Example.com").
+		WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow("user-1"))
+	id, err := s.LookupID(context.Background(), "Test@Example.com")
+	if err != nil || id != "user-1" {
+		t.Errorf("unexpected result: %s, %v", id, err)
+	}
+
+	mock.ExpectQuery("SELECT id FROM users").
+		WithArgs("nobody@example.com").
+		WillReturnRows(sqlmock.NewRows([]string{"id"}))
+	if _, err := s.LookupID(context.Background(), "nobody@example.com"); !errors.Is(err, ErrUserNotFound) {
+		t.Errorf("expected ErrUserNotFound, got %v", err)
+	}
+
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("there were unfulfilled expectations: %s", err)
+	}
+}
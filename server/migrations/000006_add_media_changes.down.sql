@@ -0,0 +1,4 @@
+DROP TRIGGER IF EXISTS trg_media_changes ON media;
+DROP FUNCTION IF EXISTS record_media_change();
+DROP TABLE IF EXISTS media_change_horizons;
+DROP TABLE IF EXISTS media_changes;
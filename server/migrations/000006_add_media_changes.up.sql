@@ -0,0 +1,56 @@
+-- 媒體異動紀錄 (Delta Sync Change Feed)
+-- user_id / media_id 不設外鍵：永久刪除 (purge) 後仍需保留紀錄供離線客戶端同步
+CREATE TABLE IF NOT EXISTS media_changes (
+    seq BIGSERIAL PRIMARY KEY,
+    user_id UUID NOT NULL,
+    media_id UUID NOT NULL,
+    op VARCHAR(16) NOT NULL, -- create / update / delete / restore / purge
+    changed_at TIMESTAMPTZ NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE INDEX IF NOT EXISTS idx_media_changes_user_seq ON media_changes (user_id, seq);
+CREATE INDEX IF NOT EXISTS idx_media_changes_changed_at ON media_changes (changed_at);
+
+-- 每位使用者已清除 (prune) 的最大 seq，token 小於此值的客戶端必須完整重新同步
+CREATE TABLE IF NOT EXISTS media_change_horizons (
+    user_id UUID PRIMARY KEY REFERENCES users(id) ON DELETE CASCADE,
+    pruned_seq BIGINT NOT NULL
+);
+
+-- 由觸發器記錄所有 media 異動，確保任何寫入路徑都不會遺漏
+CREATE OR REPLACE FUNCTION record_media_change() RETURNS trigger AS $$
+DECLARE
+    rec media%ROWTYPE;
+    change_op VARCHAR(16);
+BEGIN
+    IF TG_OP = 'INSERT' THEN
+        rec := NEW;
+        change_op := 'create';
+    ELSIF TG_OP = 'DELETE' THEN
+        rec := OLD;
+        change_op := 'purge';
+    ELSE
+        IF OLD IS NOT DISTINCT FROM NEW THEN
+            RETURN NULL;
+        END IF;
+        rec := NEW;
+        IF OLD.deleted_at IS NULL AND NEW.deleted_at IS NOT NULL THEN
+            change_op := 'delete';
+        ELSIF OLD.deleted_at IS NOT NULL AND NEW.deleted_at IS NULL THEN
+            change_op := 'restore';
+        ELSE
+            change_op := 'update';
+        END IF;
+    END IF;
+
+    -- 同一使用者的異動依序取號並提交，避免讀取端因交易交錯而跳過較小的 seq
+    PERFORM pg_advisory_xact_lock(hashtextextended(rec.user_id::text, 0));
+
+    INSERT INTO media_changes (user_id, media_id, op) VALUES (rec.user_id, rec.id, change_op);
+    RETURN NULL;
+END;
+$$ LANGUAGE plpgsql;
+
+CREATE TRIGGER trg_media_changes
+AFTER INSERT OR UPDATE OR DELETE ON media
+FOR EACH ROW EXECUTE FUNCTION record_media_change();
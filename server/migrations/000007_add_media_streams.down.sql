@@ -0,0 +1,2 @@
+DROP INDEX IF EXISTS idx_media_stream_status;
+ALTER TABLE media DROP COLUMN IF EXISTS stream_status;
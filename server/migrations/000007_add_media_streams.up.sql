@@ -0,0 +1,3 @@
+-- 影片 HLS 串流轉檔狀態: NULL (非影片) / pending / processing / ready / failed
+ALTER TABLE media ADD COLUMN IF NOT EXISTS stream_status VARCHAR(16);
+CREATE INDEX IF NOT EXISTS idx_media_stream_status ON media (stream_status) WHERE stream_status IN ('pending', 'processing');
@@ -0,0 +1,25 @@
+-- 相簿
+CREATE TABLE IF NOT EXISTS albums (
+    id UUID PRIMARY KEY DEFAULT uuid_generate_v4(),
+    user_id UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    title VARCHAR(255) NOT NULL,
+    description TEXT NOT NULL DEFAULT '',
+    cover_media_id UUID REFERENCES media(id) ON DELETE SET NULL, -- NULL 時以第一個項目作為封面
+    created_at TIMESTAMPTZ DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMPTZ DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE INDEX IF NOT EXISTS idx_albums_user ON albums (user_id, updated_at DESC);
+
+-- 相簿成員 (手動排序)
+-- 媒體移至垃圾桶時保留成員關係，查詢時過濾，還原後自動重新出現
+CREATE TABLE IF NOT EXISTS album_media (
+    album_id UUID NOT NULL REFERENCES albums(id) ON DELETE CASCADE,
+    media_id UUID NOT NULL REFERENCES media(id) ON DELETE CASCADE,
+    position BIGINT NOT NULL,
+    added_at TIMESTAMPTZ DEFAULT CURRENT_TIMESTAMP,
+    PRIMARY KEY (album_id, media_id)
+);
+
+CREATE INDEX IF NOT EXISTS idx_album_media_position ON album_media (album_id, position);
+CREATE INDEX IF NOT EXISTS idx_album_media_media ON album_media (media_id);
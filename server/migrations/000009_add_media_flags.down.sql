@@ -0,0 +1,4 @@
+DROP INDEX IF EXISTS idx_media_archived;
+DROP INDEX IF EXISTS idx_media_favorite;
+ALTER TABLE media DROP COLUMN IF EXISTS is_archived;
+ALTER TABLE media DROP COLUMN IF EXISTS is_favorite;
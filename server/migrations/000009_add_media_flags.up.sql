@@ -0,0 +1,6 @@
+-- 我的最愛與封存 (封存：不出現在主時間軸，但不移至垃圾桶)
+ALTER TABLE media ADD COLUMN IF NOT EXISTS is_favorite BOOLEAN NOT NULL DEFAULT FALSE;
+ALTER TABLE media ADD COLUMN IF NOT EXISTS is_archived BOOLEAN NOT NULL DEFAULT FALSE;
+
+CREATE INDEX IF NOT EXISTS idx_media_favorite ON media (user_id, taken_at DESC) WHERE is_favorite AND deleted_at IS NULL;
+CREATE INDEX IF NOT EXISTS idx_media_archived ON media (user_id, taken_at DESC) WHERE is_archived AND deleted_at IS NULL;
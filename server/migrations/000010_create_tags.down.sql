@@ -0,0 +1,4 @@
+DROP TRIGGER IF EXISTS trg_media_tag_changes ON media_tags;
+DROP FUNCTION IF EXISTS record_media_tag_change();
+DROP TABLE IF EXISTS media_tags;
+DROP TABLE IF EXISTS tags;
@@ -0,0 +1,46 @@
+-- 使用者自訂標籤 (名稱不分大小寫唯一)
+CREATE TABLE IF NOT EXISTS tags (
+    id UUID PRIMARY KEY DEFAULT uuid_generate_v4(),
+    user_id UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    name VARCHAR(100) NOT NULL,
+    created_at TIMESTAMPTZ DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE UNIQUE INDEX IF NOT EXISTS idx_tags_user_name ON tags (user_id, lower(name));
+
+-- 媒體與標籤的多對多關聯
+-- source: user (手動) / embedded (上傳時由 IPTC/XMP 關鍵字匯入)
+CREATE TABLE IF NOT EXISTS media_tags (
+    media_id UUID NOT NULL REFERENCES media(id) ON DELETE CASCADE,
+    tag_id UUID NOT NULL REFERENCES tags(id) ON DELETE CASCADE,
+    source VARCHAR(16) NOT NULL DEFAULT 'user',
+    created_at TIMESTAMPTZ DEFAULT CURRENT_TIMESTAMP,
+    PRIMARY KEY (media_id, tag_id)
+);
+
+CREATE INDEX IF NOT EXISTS idx_media_tags_tag ON media_tags (tag_id);
+
+-- 標籤異動視為媒體的 update，寫入同步紀錄 (媒體被永久刪除時由 purge 涵蓋)
+CREATE OR REPLACE FUNCTION record_media_tag_change() RETURNS trigger AS $$
+DECLARE
+    target_media UUID;
+    owner UUID;
+BEGIN
+    IF TG_OP = 'DELETE' THEN
+        target_media := OLD.media_id;
+    ELSE
+        target_media := NEW.media_id;
+    END IF;
+
+    SELECT user_id INTO owner FROM media WHERE id = target_media;
+    IF FOUND THEN
+        PERFORM pg_advisory_xact_lock(hashtextextended(owner::text, 0));
+        INSERT INTO media_changes (user_id, media_id, op) VALUES (owner, target_media, 'update');
+    END IF;
+    RETURN NULL;
+END;
+$$ LANGUAGE plpgsql;
+
+CREATE TRIGGER trg_media_tag_changes
+AFTER INSERT OR DELETE ON media_tags
+FOR EACH ROW EXECUTE FUNCTION record_media_tag_change();
@@ -0,0 +1,8 @@
+-- 還原為解析出的原始值後移除欄位
+UPDATE media
+SET taken_at = extracted_taken_at, latitude = extracted_latitude, longitude = extracted_longitude;
+
+ALTER TABLE media DROP COLUMN IF EXISTS caption;
+ALTER TABLE media DROP COLUMN IF EXISTS extracted_longitude;
+ALTER TABLE media DROP COLUMN IF EXISTS extracted_latitude;
+ALTER TABLE media DROP COLUMN IF EXISTS extracted_taken_at;
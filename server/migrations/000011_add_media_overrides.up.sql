@@ -0,0 +1,13 @@
+-- 可編輯的 Metadata：taken_at / latitude / longitude 保存目前生效的值 (維持排序與索引)
+-- extracted_* 保存上傳時解析出的原始值，供還原使用
+ALTER TABLE media ADD COLUMN IF NOT EXISTS extracted_taken_at TIMESTAMPTZ;
+ALTER TABLE media ADD COLUMN IF NOT EXISTS extracted_latitude DOUBLE PRECISION;
+ALTER TABLE media ADD COLUMN IF NOT EXISTS extracted_longitude DOUBLE PRECISION;
+ALTER TABLE media ADD COLUMN IF NOT EXISTS caption TEXT NOT NULL DEFAULT '';
+
+-- 回填只複製既有值，生效的內容沒有改變：暫停同步觸發器，避免整個圖庫被記錄為 update 而讓客戶端重新下載
+ALTER TABLE media DISABLE TRIGGER trg_media_changes;
+UPDATE media
+SET extracted_taken_at = taken_at, extracted_latitude = latitude, extracted_longitude = longitude
+WHERE taken_at IS NOT NULL OR latitude IS NOT NULL OR longitude IS NOT NULL;
+ALTER TABLE media ENABLE TRIGGER trg_media_changes;
@@ -0,0 +1,3 @@
+DROP INDEX IF EXISTS idx_media_user_location;
+DROP INDEX IF EXISTS idx_media_user_camera;
+DROP TABLE IF EXISTS smart_albums;
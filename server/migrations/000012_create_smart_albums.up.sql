@@ -0,0 +1,17 @@
+-- 智慧相簿：以規則文件 (JSON) 定義內容，讀取時轉換為 SQL 條件
+CREATE TABLE IF NOT EXISTS smart_albums (
+    id UUID PRIMARY KEY DEFAULT uuid_generate_v4(),
+    user_id UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    title VARCHAR(255) NOT NULL,
+    description TEXT NOT NULL DEFAULT '',
+    rules JSONB NOT NULL,
+    cover_media_id UUID REFERENCES media(id) ON DELETE SET NULL, -- NULL 或不再符合規則時以第一個項目作為封面
+    created_at TIMESTAMPTZ DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMPTZ DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE INDEX IF NOT EXISTS idx_smart_albums_user ON smart_albums (user_id, updated_at DESC);
+
+-- 常用規則欄位的索引
+CREATE INDEX IF NOT EXISTS idx_media_user_camera ON media (user_id, camera_model) WHERE deleted_at IS NULL;
+CREATE INDEX IF NOT EXISTS idx_media_user_location ON media (user_id, latitude, longitude) WHERE deleted_at IS NULL AND latitude IS NOT NULL;
@@ -0,0 +1,8 @@
+DROP INDEX IF EXISTS idx_media_user_burst;
+DROP INDEX IF EXISTS idx_media_stack;
+
+ALTER TABLE media DROP COLUMN IF EXISTS stack_opt_out;
+ALTER TABLE media DROP COLUMN IF EXISTS burst_id;
+ALTER TABLE media DROP COLUMN IF EXISTS stack_id;
+
+DROP TABLE IF EXISTS media_stacks;
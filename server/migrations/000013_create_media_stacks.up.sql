@@ -0,0 +1,14 @@
+-- 連拍堆疊：同一組連拍在時間軸上只顯示封面
+CREATE TABLE IF NOT EXISTS media_stacks (
+    id UUID PRIMARY KEY DEFAULT uuid_generate_v4(),
+    user_id UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    cover_media_id UUID REFERENCES media(id) ON DELETE SET NULL, -- NULL 時以最早拍攝的項目作為封面
+    created_at TIMESTAMPTZ DEFAULT CURRENT_TIMESTAMP
+);
+
+ALTER TABLE media ADD COLUMN IF NOT EXISTS stack_id UUID REFERENCES media_stacks(id) ON DELETE SET NULL;
+ALTER TABLE media ADD COLUMN IF NOT EXISTS burst_id VARCHAR(100); -- EXIF/MakerNote 中的 BurstUUID
+ALTER TABLE media ADD COLUMN IF NOT EXISTS stack_opt_out BOOLEAN NOT NULL DEFAULT FALSE; -- 使用者解散堆疊後不再自動歸組
+
+CREATE INDEX IF NOT EXISTS idx_media_stack ON media (stack_id) WHERE stack_id IS NOT NULL;
+CREATE INDEX IF NOT EXISTS idx_media_user_burst ON media (user_id, burst_id) WHERE burst_id IS NOT NULL;
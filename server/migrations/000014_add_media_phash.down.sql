@@ -0,0 +1,47 @@
+-- 恢復 000006 的異動紀錄函式
+CREATE OR REPLACE FUNCTION record_media_change() RETURNS trigger AS $$
+DECLARE
+    rec media%ROWTYPE;
+    change_op VARCHAR(16);
+BEGIN
+    IF TG_OP = 'INSERT' THEN
+        rec := NEW;
+        change_op := 'create';
+    ELSIF TG_OP = 'DELETE' THEN
+        rec := OLD;
+        change_op := 'purge';
+    ELSE
+        IF OLD IS NOT DISTINCT FROM NEW THEN
+            RETURN NULL;
+        END IF;
+        rec := NEW;
+        IF OLD.deleted_at IS NULL AND NEW.deleted_at IS NOT NULL THEN
+            change_op := 'delete';
+        ELSIF OLD.deleted_at IS NOT NULL AND NEW.deleted_at IS NULL THEN
+            change_op := 'restore';
+        ELSE
+            change_op := 'update';
+        END IF;
+    END IF;
+
+    -- 同一使用者的異動依序取號並提交，避免讀取端因交易交錯而跳過較小的 seq
+    PERFORM pg_advisory_xact_lock(hashtextextended(rec.user_id::text, 0));
+
+    INSERT INTO media_changes (user_id, media_id, op) VALUES (rec.user_id, rec.id, change_op);
+    RETURN NULL;
+END;
+$$ LANGUAGE plpgsql;
+
+DROP TABLE IF EXISTS media_similar_indexed;
+DROP TABLE IF EXISTS media_similar;
+
+DROP INDEX IF EXISTS idx_media_phash_3;
+DROP INDEX IF EXISTS idx_media_phash_2;
+DROP INDEX IF EXISTS idx_media_phash_1;
+DROP INDEX IF EXISTS idx_media_phash_0;
+
+ALTER TABLE media DROP COLUMN IF EXISTS phash_3;
+ALTER TABLE media DROP COLUMN IF EXISTS phash_2;
+ALTER TABLE media DROP COLUMN IF EXISTS phash_1;
+ALTER TABLE media DROP COLUMN IF EXISTS phash_0;
+ALTER TABLE media DROP COLUMN IF EXISTS phash;
@@ -0,0 +1,71 @@
+-- 感知雜湊 (dHash，64 位元)：以 BIGINT 儲存，相似度以漢明距離 bit_count(a # b) 計算
+-- 重新壓縮、縮放後的同一張圖片雜湊相同或僅差幾個位元
+ALTER TABLE media ADD COLUMN IF NOT EXISTS phash BIGINT;
+
+-- 多重索引雜湊 (multi-index hashing)：64 位元 dHash 切成 4 段 16 位元
+-- 漢明距離 <= r 的兩個雜湊至少有一段的距離 <= r/4 (鴿籠原理)，以各段鄰近值查索引取得候選後再以 bit_count 精確過濾
+ALTER TABLE media ADD COLUMN IF NOT EXISTS phash_0 INTEGER GENERATED ALWAYS AS (((phash >> 48) & 65535)::integer) STORED;
+ALTER TABLE media ADD COLUMN IF NOT EXISTS phash_1 INTEGER GENERATED ALWAYS AS (((phash >> 32) & 65535)::integer) STORED;
+ALTER TABLE media ADD COLUMN IF NOT EXISTS phash_2 INTEGER GENERATED ALWAYS AS (((phash >> 16) & 65535)::integer) STORED;
+ALTER TABLE media ADD COLUMN IF NOT EXISTS phash_3 INTEGER GENERATED ALWAYS AS ((phash & 65535)::integer) STORED;
+
+-- 含垃圾桶中的項目：相似配對在還原後仍然有效
+CREATE INDEX IF NOT EXISTS idx_media_phash_0 ON media (user_id, phash_0) WHERE phash IS NOT NULL;
+CREATE INDEX IF NOT EXISTS idx_media_phash_1 ON media (user_id, phash_1) WHERE phash IS NOT NULL;
+CREATE INDEX IF NOT EXISTS idx_media_phash_2 ON media (user_id, phash_2) WHERE phash IS NOT NULL;
+CREATE INDEX IF NOT EXISTS idx_media_phash_3 ON media (user_id, phash_3) WHERE phash IS NOT NULL;
+
+-- 相似配對：上傳或補算雜湊時寫入距離在 MinSimilarity 以內的配對 (media_id < similar_id)
+-- 相似群組由配對組成，不需每次讀取所有雜湊
+CREATE TABLE IF NOT EXISTS media_similar (
+    media_id UUID NOT NULL REFERENCES media(id) ON DELETE CASCADE,
+    similar_id UUID NOT NULL REFERENCES media(id) ON DELETE CASCADE,
+    user_id UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    distance SMALLINT NOT NULL,
+    PRIMARY KEY (media_id, similar_id),
+    CHECK (media_id < similar_id)
+);
+
+CREATE INDEX IF NOT EXISTS idx_media_similar_user ON media_similar (user_id, distance);
+CREATE INDEX IF NOT EXISTS idx_media_similar_similar ON media_similar (similar_id);
+
+-- 已寫入相似配對的媒體 (既有的雜湊由補算流程建立配對；不在 media 上加欄位，避免產生同步紀錄)
+CREATE TABLE IF NOT EXISTS media_similar_indexed (
+    media_id UUID PRIMARY KEY REFERENCES media(id) ON DELETE CASCADE
+);
+
+-- 雜湊不會回傳給客戶端：補算雜湊 (只更新 phash 與其衍生欄位) 不記錄異動，避免所有客戶端重新同步整個圖庫
+-- 其餘邏輯與 000006 相同
+CREATE OR REPLACE FUNCTION record_media_change() RETURNS trigger AS $$
+DECLARE
+    rec media%ROWTYPE;
+    change_op VARCHAR(16);
+BEGIN
+    IF TG_OP = 'INSERT' THEN
+        rec := NEW;
+        change_op := 'create';
+    ELSIF TG_OP = 'DELETE' THEN
+        rec := OLD;
+        change_op := 'purge';
+    ELSE
+        IF to_jsonb(OLD) - 'phash' - 'phash_0' - 'phash_1' - 'phash_2' - 'phash_3'
+           = to_jsonb(NEW) - 'phash' - 'phash_0' - 'phash_1' - 'phash_2' - 'phash_3' THEN
+            RETURN NULL;
+        END IF;
+        rec := NEW;
+        IF OLD.deleted_at IS NULL AND NEW.deleted_at IS NOT NULL THEN
+            change_op := 'delete';
+        ELSIF OLD.deleted_at IS NOT NULL AND NEW.deleted_at IS NULL THEN
+            change_op := 'restore';
+        ELSE
+            change_op := 'update';
+        END IF;
+    END IF;
+
+    -- 同一使用者的異動依序取號並提交，避免讀取端因交易交錯而跳過較小的 seq
+    PERFORM pg_advisory_xact_lock(hashtextextended(rec.user_id::text, 0));
+
+    INSERT INTO media_changes (user_id, media_id, op) VALUES (rec.user_id, rec.id, change_op);
+    RETURN NULL;
+END;
+$$ LANGUAGE plpgsql;
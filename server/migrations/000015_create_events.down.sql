@@ -0,0 +1,2 @@
+DROP TABLE IF EXISTS event_media;
+DROP TABLE IF EXISTS events;
@@ -0,0 +1,22 @@
+-- 自動分群的事件 (旅行、聚會等)：依時間間隔與 GPS 距離切分時間軸，唯讀
+-- 成員另存於 event_media，重新分群不會修改 media 資料列 (也不會產生同步紀錄)
+CREATE TABLE IF NOT EXISTS events (
+    id UUID PRIMARY KEY DEFAULT uuid_generate_v4(),
+    user_id UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    start_at TIMESTAMPTZ NOT NULL,
+    end_at TIMESTAMPTZ NOT NULL,
+    latitude DOUBLE PRECISION, -- 有 GPS 成員的中心點
+    longitude DOUBLE PRECISION,
+    created_at TIMESTAMPTZ DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMPTZ DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE INDEX IF NOT EXISTS idx_events_user_range ON events (user_id, start_at DESC, end_at);
+
+-- 每個媒體最多屬於一個事件
+CREATE TABLE IF NOT EXISTS event_media (
+    media_id UUID PRIMARY KEY REFERENCES media(id) ON DELETE CASCADE,
+    event_id UUID NOT NULL REFERENCES events(id) ON DELETE CASCADE
+);
+
+CREATE INDEX IF NOT EXISTS idx_event_media_event ON event_media (event_id);
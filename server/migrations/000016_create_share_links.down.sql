@@ -0,0 +1,2 @@
+DROP TABLE IF EXISTS share_link_media;
+DROP TABLE IF EXISTS share_links;
@@ -0,0 +1,26 @@
+-- 公開分享連結：持有 token 即可瀏覽，不需帳號
+-- 分享相簿 (album_id) 時內容隨相簿變動；分享選取項目時成員另存於 share_link_media
+CREATE TABLE IF NOT EXISTS share_links (
+    id UUID PRIMARY KEY DEFAULT uuid_generate_v4(),
+    user_id UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    token VARCHAR(64) UNIQUE NOT NULL, -- 32 位元組隨機值 (base64url)
+    album_id UUID REFERENCES albums(id) ON DELETE CASCADE,
+    title VARCHAR(255) NOT NULL DEFAULT '', -- 空字串時顯示相簿標題
+    allow_download BOOLEAN NOT NULL DEFAULT FALSE, -- 是否允許下載原始檔
+    expires_at TIMESTAMPTZ, -- NULL 代表永不過期
+    revoked_at TIMESTAMPTZ,
+    view_count BIGINT NOT NULL DEFAULT 0,
+    last_viewed_at TIMESTAMPTZ,
+    created_at TIMESTAMPTZ DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE INDEX IF NOT EXISTS idx_share_links_user ON share_links (user_id, created_at DESC) WHERE revoked_at IS NULL;
+
+CREATE TABLE IF NOT EXISTS share_link_media (
+    share_id UUID NOT NULL REFERENCES share_links(id) ON DELETE CASCADE,
+    media_id UUID NOT NULL REFERENCES media(id) ON DELETE CASCADE,
+    position INT NOT NULL,
+    PRIMARY KEY (share_id, media_id)
+);
+
+CREATE INDEX IF NOT EXISTS idx_share_link_media_order ON share_link_media (share_id, position);
@@ -0,0 +1,2 @@
+DROP INDEX IF EXISTS idx_users_email_lower;
+DROP TABLE IF EXISTS album_members;
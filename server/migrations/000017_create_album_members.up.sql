@@ -0,0 +1,14 @@
+-- 共享相簿成員：擁有者以 Email 邀請其他使用者
+-- viewer 可瀏覽與下載，contributor 另可加入 (及移除) 自己的媒體
+CREATE TABLE IF NOT EXISTS album_members (
+    album_id UUID NOT NULL REFERENCES albums(id) ON DELETE CASCADE,
+    user_id UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    role VARCHAR(16) NOT NULL CHECK (role IN ('viewer', 'contributor')),
+    created_at TIMESTAMPTZ DEFAULT CURRENT_TIMESTAMP,
+    PRIMARY KEY (album_id, user_id)
+);
+
+CREATE INDEX IF NOT EXISTS idx_album_members_user ON album_members (user_id);
+
+-- 邀請時以 Email 查詢使用者 (不分大小寫)
+CREATE INDEX IF NOT EXISTS idx_users_email_lower ON users (LOWER(email));
@@ -0,0 +1,13 @@
+-- 伴侶分享：owner 授權 partner 讀取整個媒體庫 (可限定拍攝於 start_date 之後的項目)
+-- 授權為單向；雙方互相分享時各有一筆
+CREATE TABLE IF NOT EXISTS partner_shares (
+    owner_id UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    partner_id UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    start_date TIMESTAMPTZ, -- NULL 代表分享全部
+    in_timeline BOOLEAN NOT NULL DEFAULT FALSE, -- 由 partner 決定是否合併至自己的時間軸
+    created_at TIMESTAMPTZ DEFAULT CURRENT_TIMESTAMP,
+    PRIMARY KEY (owner_id, partner_id),
+    CHECK (owner_id <> partner_id)
+);
+
+CREATE INDEX IF NOT EXISTS idx_partner_shares_partner ON partner_shares (partner_id);
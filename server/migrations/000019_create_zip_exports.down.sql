@@ -0,0 +1,2 @@
+DROP TABLE IF EXISTS zip_exports;
+DROP TABLE IF EXISTS file_checksums;
@@ -0,0 +1,17 @@
+-- ZIP 匯出時計算的 CRC32 (以內容 Hash 為鍵，同內容共用)
+-- 續傳時需要先前檔案的 CRC 才能產生中央目錄，另存一表避免更新 media 資料列產生同步紀錄
+CREATE TABLE IF NOT EXISTS file_checksums (
+    file_hash VARCHAR(64) PRIMARY KEY,
+    crc32 BIGINT NOT NULL
+);
+
+-- 多選匯出：先記錄選取的項目，之後以 GET 下載 (可續傳)
+CREATE TABLE IF NOT EXISTS zip_exports (
+    id UUID PRIMARY KEY DEFAULT uuid_generate_v4(),
+    user_id UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    media_ids UUID[] NOT NULL, -- 依此順序放入壓縮檔
+    include_manifest BOOLEAN NOT NULL DEFAULT FALSE,
+    created_at TIMESTAMPTZ DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE INDEX IF NOT EXISTS idx_zip_exports_user ON zip_exports (user_id, created_at DESC);
@@ -0,0 +1,2 @@
+DROP TABLE IF EXISTS inbox_log;
+DROP TABLE IF EXISTS inbox_folders;
@@ -0,0 +1,19 @@
+-- 監看的收件資料夾：放入其中的檔案會自動匯入對應使用者 (每位使用者一個資料夾)
+CREATE TABLE IF NOT EXISTS inbox_folders (
+    user_id UUID PRIMARY KEY REFERENCES users(id) ON DELETE CASCADE,
+    path TEXT NOT NULL UNIQUE, -- 伺服器上的絕對路徑
+    created_at TIMESTAMPTZ DEFAULT CURRENT_TIMESTAMP
+);
+
+-- 收件資料夾的處理紀錄 (使用者可檢視)
+CREATE TABLE IF NOT EXISTS inbox_log (
+    id UUID PRIMARY KEY DEFAULT uuid_generate_v4(),
+    user_id UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    filename TEXT NOT NULL,
+    status VARCHAR(20) NOT NULL CHECK (status IN ('imported', 'duplicate', 'failed')),
+    media_id UUID REFERENCES media(id) ON DELETE SET NULL,
+    error TEXT NOT NULL DEFAULT '',
+    created_at TIMESTAMPTZ DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE INDEX IF NOT EXISTS idx_inbox_log_user ON inbox_log (user_id, created_at DESC);
@@ -0,0 +1,21 @@
+-- 帳號完整匯出 (背景產生的 ZIP：所有原始檔與完整的 Metadata)
+-- 檔案存於 UPLOAD_DIR/exports/{id}.zip，到期後刪除檔案並標記為 expired
+CREATE TABLE IF NOT EXISTS account_exports (
+    id UUID PRIMARY KEY DEFAULT uuid_generate_v4(),
+    user_id UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    status VARCHAR(20) NOT NULL DEFAULT 'pending' CHECK (status IN ('pending', 'processing', 'ready', 'failed', 'expired')),
+    total_bytes BIGINT NOT NULL DEFAULT 0, -- 需寫入的原始檔總大小 (進度計算用)
+    done_bytes BIGINT NOT NULL DEFAULT 0,
+    size_bytes BIGINT NOT NULL DEFAULT 0, -- 完成後的 ZIP 大小
+    media_count INT NOT NULL DEFAULT 0,
+    error TEXT NOT NULL DEFAULT '',
+    created_at TIMESTAMPTZ DEFAULT CURRENT_TIMESTAMP,
+    completed_at TIMESTAMPTZ,
+    expires_at TIMESTAMPTZ
+);
+
+CREATE INDEX IF NOT EXISTS idx_account_exports_user ON account_exports (user_id, created_at DESC);
+
+-- 每位使用者同時只能有一個進行中的匯出
+CREATE UNIQUE INDEX IF NOT EXISTS idx_account_exports_active ON account_exports (user_id)
+WHERE status IN ('pending', 'processing');
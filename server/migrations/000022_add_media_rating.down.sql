@@ -0,0 +1,2 @@
+ALTER TABLE zip_exports DROP COLUMN IF EXISTS include_xmp;
+ALTER TABLE media DROP COLUMN IF EXISTS rating;
@@ -0,0 +1,5 @@
+-- 評等 (與 XMP xmp:Rating 相同：1~5 星，-1 代表排除；NULL 代表未評等)
+ALTER TABLE media ADD COLUMN IF NOT EXISTS rating SMALLINT CHECK (rating BETWEEN -1 AND 5 AND rating <> 0);
+
+-- 多選匯出是否附上 XMP sidecar
+ALTER TABLE zip_exports ADD COLUMN IF NOT EXISTS include_xmp BOOLEAN NOT NULL DEFAULT FALSE;